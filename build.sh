@@ -1,5 +1,6 @@
 #!/bin/bash
 export GOPATH=`pwd`
+export GO111MODULE=off
 mkdir -p bin
 
 CGO_ENABLED=0 go build -a -tags netgo -ldflags '-w' -o bin/coscale-cli coscale
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -69,8 +70,13 @@ func (a AlertTrigger) GetId() int64 {
 
 //GetAlertsBy will use a custom query to get a alert by unresolved/unacknowledged
 func (api *Api) GetAlertsBy(query string) (string, error) {
+	return api.GetAlertsByContext(context.Background(), query)
+}
+
+// GetAlertsByContext is like GetAlertsBy, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetAlertsByContext(ctx context.Context, query string) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/alerts/?%s=false", api.AppID, query), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/alerts/?%s=false", api.AppID, query), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -78,11 +84,16 @@ func (api *Api) GetAlertsBy(query string) (string, error) {
 
 //AlertSolution will be used to acknowledge/ resolve a alert
 func (api *Api) AlertSolution(alert *Alert, solutionType string) (string, error) {
+	return api.AlertSolutionContext(context.Background(), alert, solutionType)
+}
+
+// AlertSolutionContext is like AlertSolution, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) AlertSolutionContext(ctx context.Context, alert *Alert, solutionType string) (string, error) {
 	data := map[string][]string{
 		"version": {fmt.Sprintf("%d", alert.Version)},
 	}
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/alerts/%d/%s/", api.AppID, alert.ID, solutionType), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/alerts/%d/%s/", api.AppID, alert.ID, solutionType), data, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -90,6 +101,11 @@ func (api *Api) AlertSolution(alert *Alert, solutionType string) (string, error)
 
 // CreateType is used to add a new Alert type.
 func (api *Api) CreateType(name, description, handle, backupHandle, escalationHandle string, backupSeconds, escalationSeconds int64) (string, error) {
+	return api.CreateTypeContext(context.Background(), name, description, handle, backupHandle, escalationHandle, backupSeconds, escalationSeconds)
+}
+
+// CreateTypeContext is like CreateType, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateTypeContext(ctx context.Context, name, description, handle, backupHandle, escalationHandle string, backupSeconds, escalationSeconds int64) (string, error) {
 
 	data := map[string][]string{
 		"name":        {name},
@@ -113,9 +129,9 @@ func (api *Api) CreateType(name, description, handle, backupHandle, escalationHa
 	}
 
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/alerttypes/", api.AppID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/alerttypes/", api.AppID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetObject("alerttype", id)
+			return api.GetObjectContext(ctx, "alerttype", id)
 		}
 		return "", err
 	}
@@ -124,6 +140,11 @@ func (api *Api) CreateType(name, description, handle, backupHandle, escalationHa
 
 // UpdateType is used to update an existing Alert type.
 func (api *Api) UpdateType(alertType *AlertType) (string, error) {
+	return api.UpdateTypeContext(context.Background(), alertType)
+}
+
+// UpdateTypeContext is like UpdateType, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateTypeContext(ctx context.Context, alertType *AlertType) (string, error) {
 
 	data := map[string][]string{
 		"name":        {alertType.Name},
@@ -148,7 +169,7 @@ func (api *Api) UpdateType(alertType *AlertType) (string, error) {
 	}
 
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/", api.AppID, alertType.GetId()), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/", api.AppID, alertType.GetId()), data, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -156,8 +177,13 @@ func (api *Api) UpdateType(alertType *AlertType) (string, error) {
 
 // GetTriggers will return all triggers for an alert type
 func (api *Api) GetTriggers(alertTypeID int64) (string, error) {
+	return api.GetTriggersContext(context.Background(), alertTypeID)
+}
+
+// GetTriggersContext is like GetTriggers, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetTriggersContext(ctx context.Context, alertTypeID int64) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/triggers/", api.AppID, alertTypeID), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/triggers/", api.AppID, alertTypeID), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -165,6 +191,11 @@ func (api *Api) GetTriggers(alertTypeID int64) (string, error) {
 
 // CreateTrigger is used to add a new Trigger for alerts.
 func (api *Api) CreateTrigger(name, description, config, dimensionSpecs string, alertTypeID, autoResolve, metricID, serverID, serverGroupID int64, onApp bool) (string, error) {
+	return api.CreateTriggerContext(context.Background(), name, description, config, dimensionSpecs, alertTypeID, autoResolve, metricID, serverID, serverGroupID, onApp)
+}
+
+// CreateTriggerContext is like CreateTrigger, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateTriggerContext(ctx context.Context, name, description, config, dimensionSpecs string, alertTypeID, autoResolve, metricID, serverID, serverGroupID int64, onApp bool) (string, error) {
 
 	data := map[string][]string{
 		"name":        {name},
@@ -193,9 +224,9 @@ func (api *Api) CreateTrigger(name, description, config, dimensionSpecs string,
 	}
 
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/triggers/", api.AppID, alertTypeID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/triggers/", api.AppID, alertTypeID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetObjectFromGroup("alerttype", "trigger", alertTypeID, id)
+			return api.GetObjectFromGroupContext(ctx, "alerttype", "trigger", alertTypeID, id)
 		}
 		return "", err
 	}
@@ -204,6 +235,11 @@ func (api *Api) CreateTrigger(name, description, config, dimensionSpecs string,
 
 // UpdateTrigger is used to update a existing Trigger for alerts.
 func (api *Api) UpdateTrigger(typeID int64, trigger *AlertTrigger) (string, error) {
+	return api.UpdateTriggerContext(context.Background(), typeID, trigger)
+}
+
+// UpdateTriggerContext is like UpdateTrigger, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateTriggerContext(ctx context.Context, typeID int64, trigger *AlertTrigger) (string, error) {
 
 	data := map[string][]string{
 		"name":           {trigger.Name},
@@ -229,10 +265,10 @@ func (api *Api) UpdateTrigger(typeID int64, trigger *AlertTrigger) (string, erro
 	}
 
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/triggers/%d/", api.AppID, typeID, trigger.ID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/alerttypes/%d/triggers/%d/", api.AppID, typeID, trigger.ID), data, true, &result); err != nil {
 		return "", err
 	}
-	return api.GetObjectFromGroup("alerttype", "trigger", typeID, trigger.ID)
+	return api.GetObjectFromGroupContext(ctx, "alerttype", "trigger", typeID, trigger.ID)
 }
 
 // ParseHandle is used to parse the handle provided by user and serialize into json format.
@@ -279,6 +315,36 @@ func ParseHandle(handle string) (string, error) {
 	return string(jsonHandle), err
 }
 
+// FormatHandle is the inverse of ParseHandle: it formats a json handle as the contacts
+// used by the CLI, e.g. "EMAIL:support@coscale.com SLACK:https://hooks.slack.com...".
+func FormatHandle(handle string) (string, error) {
+	if handle == "" {
+		return "", nil
+	}
+	var contacts []map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(handle))
+	decoder.UseNumber()
+	if err := decoder.Decode(&contacts); err != nil {
+		return "", fmt.Errorf("Could not parse the alert handle: %s", err)
+	}
+
+	fields := map[string]string{
+		"EMAILUSER": "id",
+		"EMAIL":     "address",
+		"SLACK":     "webhook",
+	}
+	result := make([]string, 0, len(contacts))
+	for _, contact := range contacts {
+		contactType := fmt.Sprint(contact["type"])
+		field, ok := fields[contactType]
+		if !ok {
+			return "", fmt.Errorf("Unsupported alert handle type %s", contactType)
+		}
+		result = append(result, fmt.Sprintf("%s:%v", contactType, contact[field]))
+	}
+	return strings.Join(result, " "), nil
+}
+
 // ParseDimensionSpecs is used to parse the dimensions specs for a metric.
 func ParseDimensionSpecs(format string) (string, error) {
 	var js interface{}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"testing"
+)
+
+// Test that FormatHandle formats the handles created by ParseHandle.
+func TestFormatHandle(t *testing.T) {
+	for _, handle := range []string{
+		"EMAIL:support@coscale.com",
+		"EMAILUSER:12 EMAIL:support@coscale.com SLACK:https://hooks.slack.com/services/T0/B0/x",
+	} {
+		parsed, err := ParseHandle(handle)
+		if err != nil {
+			t.Fatalf("Error occured while parsing %s: %s", handle, err)
+		}
+		formatted, err := FormatHandle(parsed)
+		if err != nil {
+			t.Fatalf("Error occured while formatting %s: %s", parsed, err)
+		}
+		if formatted != handle {
+			t.Fatalf("expected: %s, found: %s", handle, formatted)
+		}
+	}
+
+	if formatted, err := FormatHandle(`[{"type":"EMAILUSER","id":123456789}]`); err != nil || formatted != "EMAILUSER:123456789" {
+		t.Fatalf("expected: EMAILUSER:123456789, found: %s %v", formatted, err)
+	}
+	if _, err := FormatHandle(`[{"type":"PAGERDUTY","key":"x"}]`); err == nil {
+		t.Fatalf("expected an error for an unsupported handle type")
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,7 +10,9 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -55,6 +58,19 @@ type Disabled string
 // InvalidConfig is returned when the API connection configuration is not valid.
 type InvalidConfig string
 
+// BadStatusError is returned by the API for unexpected status codes, e.g. server errors.
+type BadStatusError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+// loginError wraps a failed login request so it can be retried before it is reported as an AuthenticationError.
+type loginError struct {
+	err error
+}
+
 func (ue AuthenticationError) Error() string {
 	return string(ue)
 }
@@ -83,6 +99,14 @@ func (i InvalidConfig) Error() string {
 	return string(i)
 }
 
+func (bse BadStatusError) Error() string {
+	return fmt.Sprintf("Received bad status code: %d -- %s", bse.StatusCode, bse.Body)
+}
+
+func (le loginError) Error() string {
+	return le.err.Error()
+}
+
 // IsAuthenticationError checks if an error is a AuthenticationError.
 func IsAuthenticationError(err error) bool {
 	_, ok := err.(AuthenticationError)
@@ -137,46 +161,85 @@ type Api struct {
 	validConfig bool
 	// Print aditional util information.
 	verbose bool
+	retry   RetryPolicy
+	// Maximum duration of a call including its retries, no limit if 0.
+	timeout time.Duration
+	// client is shared by all calls so connections are reused.
+	client *http.Client
+	// tokenMu guards token, so the Api can be used by concurrent calls.
+	tokenMu *sync.Mutex
 }
 
 // NewApi creates a new Api connector using an email and a password.
 func NewApi(baseUrl string, accessToken string, appID string, rawOutput, verbose bool) *Api {
-	api := &Api{baseUrl, accessToken, appID, rawOutput, "", "", true, verbose}
+	api := &Api{baseUrl, accessToken, appID, rawOutput, "", "", true, verbose, DefaultRetryPolicy(), 0, newClient(NewTransport()), &sync.Mutex{}}
 	return api
 }
 
 // NewFakeApi creates a new Api connector using an email and a password.
 func NewFakeApi() *Api {
-	api := &Api{"", "", "", true, "", "", false, false}
+	api := &Api{"", "", "", true, "", "", false, false, DefaultRetryPolicy(), 0, newClient(NewTransport()), &sync.Mutex{}}
 	return api
 }
 
+// ForApp returns a copy of the Api connector for another application, the retry policy, timeout,
+// transport and output options are kept.
+func (api *Api) ForApp(baseUrl, accessToken, appID string) *Api {
+	other := *api
+	other.BaseUrl, other.AccessToken, other.AppID = baseUrl, accessToken, appID
+	other.token = ""
+	other.tokenMu = &sync.Mutex{}
+	other.validConfig = true
+	return &other
+}
+
+// SetTransport replaces the transport used for the API calls, e.g. to use a custom proxy or TLS configuration.
+func (api *Api) SetTransport(transport http.RoundTripper) {
+	api.client = newClient(transport)
+}
+
+// Transport returns the transport used for the API calls.
+func (api *Api) Transport() http.RoundTripper {
+	return api.client.Transport
+}
+
+// SetRetryPolicy sets the policy used to retry failed calls.
+func (api *Api) SetRetryPolicy(policy RetryPolicy) {
+	api.retry = policy
+}
+
+// SetTimeout sets the maximum duration of an API call including its retries, 0 means no limit.
+func (api *Api) SetTimeout(timeout time.Duration) {
+	api.timeout = timeout
+}
+
 // GetSource gets the source name for the CoScale cli.
 func GetSource() string {
 	return "CLI"
 }
 
-// newTimeoutDialer creates a new Dailer with the given timeouts.
-func newTimeoutDialer(connectionTimeout time.Duration, readWriteTimeout time.Duration) func(net, addr string) (c net.Conn, err error) {
-	return func(netw, addr string) (net.Conn, error) {
-		conn, err := net.DialTimeout(netw, addr, connectionTimeout)
-		if err != nil {
-			return nil, err
-		}
-		if err = conn.SetDeadline(time.Now().Add(readWriteTimeout)); err != nil {
-			return nil, err
-		}
-		return conn, nil
+// NewTransport creates the default transport for the API calls: it keeps connections
+// alive for reuse and limits the time to connect. The deadline for the whole request
+// is set per call.
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   connectionTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          10,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   connectionTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
 	}
 }
 
-// newTimeoutClient creates a http client with timeouts on the connection an reads/writes.
-func newTimeoutClient(connectTimeout time.Duration, readWriteTimeout time.Duration) *http.Client {
+// newClient creates a http client using transport.
+func newClient(transport http.RoundTripper) *http.Client {
 	return &http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			Dial:  newTimeoutDialer(connectTimeout, readWriteTimeout),
-		},
+		Transport: transport,
 	}
 }
 
@@ -188,7 +251,7 @@ type RequestErrorResponse struct {
 }
 
 // Do an http request.
-func (api *Api) doHttpRequest(method string, uri string, token string, data map[string][]string, timeout time.Duration) ([]byte, error) {
+func (api *Api) doHttpRequest(ctx context.Context, method string, uri string, token string, data map[string][]string, timeout time.Duration) ([]byte, error) {
 	if method == "GET" && len(api.query) > 0 {
 		if strings.ContainsAny(uri, "?") {
 			uri = fmt.Sprintf("%s&%s", uri, api.query)
@@ -208,6 +271,11 @@ func (api *Api) doHttpRequest(method string, uri string, token string, data map[
 		return nil, err
 	}
 
+	// The timeout covers the whole request, including reading the response body.
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
 	req.Header.Add("User-Agent", "CoScale CLI")
 
 	if method == "POST" || method == "PUT" {
@@ -218,8 +286,7 @@ func (api *Api) doHttpRequest(method string, uri string, token string, data map[
 		req.Header.Add("HTTPAuthorization", token)
 	}
 
-	client := newTimeoutClient(connectionTimeout, timeout)
-	resp, err := client.Do(req)
+	resp, err := api.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -242,7 +309,7 @@ func (api *Api) doHttpRequest(method string, uri string, token string, data map[
 		}
 		return nil, RequestError(fmt.Sprintf("Request error: %s", body))
 	} else if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Received bad status code: %d -- %s", resp.StatusCode, body)
+		return nil, BadStatusError{resp.StatusCode, string(body), parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
 
 	if err != nil {
@@ -266,45 +333,101 @@ type LoginData struct {
 
 // Login to the Api, returns the token and an error.
 func (api *Api) Login() error {
+	return api.LoginContext(context.Background())
+}
+
+// LoginContext is like Login, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) LoginContext(ctx context.Context) error {
+	return toAuthenticationError(api.login(ctx))
+}
+
+// login performs the login request, a failed request is returned as loginError.
+func (api *Api) login(ctx context.Context) error {
 
 	data := map[string][]string{
 		"accessToken": {api.AccessToken},
 	}
 
 	var loginData LoginData
-	bytes, err := api.doHttpRequest("POST", fmt.Sprintf("%s/api/v1/app/%s/login/", api.BaseUrl, api.AppID), "", data, readWriteTimeout)
+	bytes, err := api.doHttpRequest(ctx, "POST", fmt.Sprintf("%s/api/v1/app/%s/login/", api.BaseUrl, api.AppID), "", data, readWriteTimeout)
 	if err != nil {
-		return AuthenticationError(fmt.Sprintf("Authentication error: %s", err.Error()))
+		return loginError{err}
 	}
 
 	if err := json.Unmarshal(bytes, &loginData); err != nil {
 		return err
 	}
 
-	api.token = loginData.Token
+	api.setToken(loginData.Token)
 	return nil
 }
 
-// Make a call to the api, returns the bytes returned.
-func (api *Api) makeRawCall(method string, uri string, data map[string][]string, timeout time.Duration) ([]byte, error) {
+// getToken returns the token of the current session, empty if there is no session.
+func (api *Api) getToken() string {
+	api.tokenMu.Lock()
+	defer api.tokenMu.Unlock()
+	return api.token
+}
+
+// setToken replaces the token of the current session.
+func (api *Api) setToken(token string) {
+	api.tokenMu.Lock()
+	defer api.tokenMu.Unlock()
+	api.token = token
+}
+
+// toAuthenticationError converts a loginError into an AuthenticationError. A login that failed
+// because the API could not be reached is not an authentication error, its cause is returned.
+func toAuthenticationError(err error) error {
+	if le, ok := err.(loginError); ok {
+		if IsTemporaryError(le.err) {
+			return le.err
+		}
+		return AuthenticationError(fmt.Sprintf("Authentication error: %s", le.err.Error()))
+	}
+	return err
+}
+
+// Make a call to the api, returns the bytes returned. Failed calls are retried according
+// to the RetryPolicy, safe indicates that the request can be repeated even if it is not idempotent.
+func (api *Api) makeRawCall(ctx context.Context, method string, uri string, data map[string][]string, timeout time.Duration, safe bool) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		bytes, err := api.makeAuthenticatedCall(ctx, method, uri, data, timeout)
+		retry, delay := api.retry.shouldRetry(attempt, method, safe, err)
+		if !retry {
+			return bytes, toAuthenticationError(err)
+		}
+		if api.verbose {
+			fmt.Fprintf(os.Stderr, "Retrying %s %s in %s: %s\n", method, uri, delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// makeAuthenticatedCall does a single request, login is done first if there is no valid token.
+func (api *Api) makeAuthenticatedCall(ctx context.Context, method string, uri string, data map[string][]string, timeout time.Duration) ([]byte, error) {
 	// Not authenticated yet, try login.
-	if api.token == "" {
-		if err := api.Login(); err != nil {
+	if api.getToken() == "" {
+		if err := api.login(ctx); err != nil {
 			return nil, err
 		}
 	}
 
 	// Do the actual request.
-	bytes, err := api.doHttpRequest(method, api.BaseUrl+uri, api.token, data, timeout)
+	bytes, err := api.doHttpRequest(ctx, method, api.BaseUrl+uri, api.getToken(), data, timeout)
 	if err != nil {
 		if _, ok := err.(UnauthorizedError); ok {
 			// unauthorizedError: the token might have experied. Performing login again
 			// and retrying the request.
-			if err := api.Login(); err != nil {
-				api.token = ""
+			if err := api.login(ctx); err != nil {
+				api.setToken("")
 				return nil, err
 			}
-			return api.doHttpRequest(method, api.BaseUrl+uri, api.token, data, timeout)
+			return api.doHttpRequest(ctx, method, api.BaseUrl+uri, api.getToken(), data, timeout)
 		}
 		return bytes, err
 	}
@@ -313,12 +436,29 @@ func (api *Api) makeRawCall(method string, uri string, data map[string][]string,
 }
 
 // makeCall Make a call to the api and parse the json response into target.
-func (api *Api) makeCall(method string, uri string, data map[string][]string, jsonOut bool, target interface{}) error {
+func (api *Api) makeCall(ctx context.Context, method string, uri string, data map[string][]string, jsonOut bool, target interface{}) error {
+	return api.makeCallWithRetry(ctx, method, uri, data, jsonOut, target, false)
+}
+
+// makeSafeCall is like makeCall, but the call is retried on failure even if the method is not idempotent.
+// Use it only for calls that can be repeated without side effects, e.g. a POST that only reads data.
+func (api *Api) makeSafeCall(ctx context.Context, method string, uri string, data map[string][]string, jsonOut bool, target interface{}) error {
+	return api.makeCallWithRetry(ctx, method, uri, data, jsonOut, target, true)
+}
+
+// makeCallWithRetry makes the call and parses the json response into target.
+func (api *Api) makeCallWithRetry(ctx context.Context, method string, uri string, data map[string][]string, jsonOut bool, target interface{}, safe bool) error {
 	if !api.validConfig {
 		return InvalidConfig("Could not find valid authentication configuration.")
 	}
 
-	b, err := api.makeRawCall(method, uri, data, readWriteTimeout)
+	if api.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, api.timeout)
+		defer cancel()
+	}
+
+	b, err := api.makeRawCall(ctx, method, uri, data, readWriteTimeout, safe)
 	if err != nil {
 		return err
 	}
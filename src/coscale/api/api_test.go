@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test that the deadline of the context and the Api timeout abort a call.
+func TestContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login/") {
+			fmt.Fprint(w, `{"token":"token"}`)
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	api := NewApi(server.URL, "token", "app", true, false)
+	api.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	// Cancelled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := api.GetObjectsContext(ctx, "server"); err == nil {
+		t.Fatalf("Expected error.")
+	}
+
+	// Context deadline.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := api.GetObjectsContext(ctx, "server"); err == nil {
+		t.Fatalf("Expected error.")
+	}
+
+	// Api timeout.
+	api.SetTimeout(50 * time.Millisecond)
+	if _, err := api.GetObjects("server"); err == nil {
+		t.Fatalf("Expected error.")
+	}
+
+	// No timeout.
+	api.SetTimeout(0)
+	if _, err := api.GetObjects("server"); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+}
+
+// Test that the connection is reused for multiple calls.
+func TestConnectionReuse(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login/") {
+			fmt.Fprint(w, `{"token":"token"}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	api := NewApi(server.URL, "token", "app", true, false)
+	for i := 0; i < 3; i++ {
+		if _, err := api.GetObjects("server"); err != nil {
+			t.Fatalf("Error occured: %s", err)
+		}
+	}
+	if obtained := atomic.LoadInt32(&connections); obtained != 1 {
+		t.Fatalf("expected: %d connections, found: %d", 1, obtained)
+	}
+}
+
+// roundTripFunc is a http.RoundTripper for testing.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Test that a custom transport is used for the calls.
+func TestSetTransport(t *testing.T) {
+	var requests []string
+	api := NewApi("http://coscale.test", "token", "app", true, false)
+	api.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.URL.Path)
+		body := `[]`
+		if strings.HasSuffix(req.URL.Path, "/login/") {
+			body = `{"token":"token"}`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+	if _, err := api.GetObjects("server"); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	expected := []string{"/api/v1/app/app/login/", "/api/v1/app/app/servers/"}
+	if !reflect.DeepEqual(expected, requests) {
+		t.Fatalf("expected: %v, found: %v", expected, requests)
+	}
+}
+
+// Test that a connector for another application logs in to that application with the same transport.
+func TestForApp(t *testing.T) {
+	var requests []string
+	api := NewApi("http://coscale.test", "token", "app", true, false)
+	api.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.URL.Path)
+		body := `[]`
+		if strings.HasSuffix(req.URL.Path, "/login/") {
+			body = `{"token":"token"}`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+	if _, err := api.GetObjects("server"); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	other := api.ForApp("http://coscale.test", "token2", "other")
+	if _, err := other.GetObjects("server"); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	expected := []string{"/api/v1/app/app/login/", "/api/v1/app/app/servers/", "/api/v1/app/other/login/", "/api/v1/app/other/servers/"}
+	if !reflect.DeepEqual(expected, requests) {
+		t.Fatalf("expected: %v, found: %v", expected, requests)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 )
@@ -10,8 +11,13 @@ import (
 
 // GetObjects will get all the objects (json) specified by objectName. eg: all the metrics or all the servers
 func (api *Api) GetObjects(objectName string) (string, error) {
+	return api.GetObjectsContext(context.Background(), objectName)
+}
+
+// GetObjectsContext is like GetObjects, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectsContext(ctx context.Context, objectName string) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/", api.AppID, objectName), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/", api.AppID, objectName), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -19,8 +25,13 @@ func (api *Api) GetObjects(objectName string) (string, error) {
 
 // GetObject will return the object (json) specified by objectName that have a certain id
 func (api *Api) GetObject(objectName string, id int64) (string, error) {
+	return api.GetObjectContext(context.Background(), objectName, id)
+}
+
+// GetObjectContext is like GetObject, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectContext(ctx context.Context, objectName string, id int64) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/", api.AppID, objectName, id), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/", api.AppID, objectName, id), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -28,8 +39,13 @@ func (api *Api) GetObject(objectName string, id int64) (string, error) {
 
 // GetObjectFromGroup will return the object (json) specified by objectName from objectGroup that have a certain id
 func (api *Api) GetObjectFromGroup(objectGroup, objectName string, groupID, objectID int64) (string, error) {
+	return api.GetObjectFromGroupContext(context.Background(), objectGroup, objectName, groupID, objectID)
+}
+
+// GetObjectFromGroupContext is like GetObjectFromGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectFromGroupContext(ctx context.Context, objectGroup, objectName string, groupID, objectID int64) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroup, groupID, objectName, objectID), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroup, groupID, objectName, objectID), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -37,7 +53,12 @@ func (api *Api) GetObjectFromGroup(objectGroup, objectName string, groupID, obje
 
 // GetObjectRef will put in result a reference to a object specified by objectName and that have a certain id
 func (api *Api) GetObjectRef(objectName string, id int64, result Object) error {
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/", api.AppID, objectName, id), nil, false, &result); err != nil {
+	return api.GetObjectRefContext(context.Background(), objectName, id, result)
+}
+
+// GetObjectRefContext is like GetObjectRef, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectRefContext(ctx context.Context, objectName string, id int64, result Object) error {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/", api.AppID, objectName, id), nil, false, &result); err != nil {
 		return err
 	}
 	return nil
@@ -45,7 +66,12 @@ func (api *Api) GetObjectRef(objectName string, id int64, result Object) error {
 
 // GetObjectRefFromGroup will return the object specified by objectName from objectGroup that have a certain id
 func (api *Api) GetObjectRefFromGroup(objectGroup, objectName string, groupID, objectID int64, result Object) error {
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroup, groupID, objectName, objectID), nil, false, &result); err != nil {
+	return api.GetObjectRefFromGroupContext(context.Background(), objectGroup, objectName, groupID, objectID, result)
+}
+
+// GetObjectRefFromGroupContext is like GetObjectRefFromGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectRefFromGroupContext(ctx context.Context, objectGroup, objectName string, groupID, objectID int64, result Object) error {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroup, groupID, objectName, objectID), nil, false, &result); err != nil {
 		return err
 	}
 	return nil
@@ -53,11 +79,16 @@ func (api *Api) GetObjectRefFromGroup(objectGroup, objectName string, groupID, o
 
 // GetObjectByName will return the object (json) specified by objectName and name
 func (api *Api) GetObjectByName(objectName string, name string) (string, error) {
+	return api.GetObjectByNameContext(context.Background(), objectName, name)
+}
+
+// GetObjectByNameContext is like GetObjectByName, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectByNameContext(ctx context.Context, objectName string, name string) (string, error) {
 	// URL Encoded.
 	name = url.QueryEscape(name)
 
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/?selectByName=%s", api.AppID, objectName, name), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/?selectByName=%s", api.AppID, objectName, name), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -65,15 +96,20 @@ func (api *Api) GetObjectByName(objectName string, name string) (string, error)
 
 // GetObjectRefByName will put in result a reference to the oject specified by objectName and name
 func (api *Api) GetObjectRefByName(objectName string, name string, result Object) error {
+	return api.GetObjectRefByNameContext(context.Background(), objectName, name, result)
+}
+
+// GetObjectRefByNameContext is like GetObjectRefByName, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectRefByNameContext(ctx context.Context, objectName string, name string, result Object) error {
 	// URL Encoded.
 	name = url.QueryEscape(name)
 
 	objects := []*Object{&result}
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/?selectByName=%s", api.AppID, objectName, name), nil, false, &objects); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/?selectByName=%s", api.AppID, objectName, name), nil, false, &objects); err != nil {
 		return err
 	}
 	if len(objects) == 0 {
-		return fmt.Errorf("Not Found")
+		return NotFoundError("Not Found")
 	}
 	result = *objects[0]
 	return nil
@@ -81,15 +117,20 @@ func (api *Api) GetObjectRefByName(objectName string, name string, result Object
 
 // GetObjectRefByNameFromGroup will return the object specified by objectName from objectGroup that have a certain name
 func (api *Api) GetObjectRefByNameFromGroup(objectGroup, objectName string, groupID int64, name string, result Object) error {
+	return api.GetObjectRefByNameFromGroupContext(context.Background(), objectGroup, objectName, groupID, name, result)
+}
+
+// GetObjectRefByNameFromGroupContext is like GetObjectRefByNameFromGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetObjectRefByNameFromGroupContext(ctx context.Context, objectGroup, objectName string, groupID int64, name string, result Object) error {
 	// URL Encoded.
 	name = url.QueryEscape(name)
 
 	objects := []*Object{&result}
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/?selectByName=%s", api.AppID, objectGroup, groupID, objectName, name), nil, false, &objects); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/?selectByName=%s", api.AppID, objectGroup, groupID, objectName, name), nil, false, &objects); err != nil {
 		return err
 	}
 	if len(objects) == 0 {
-		return fmt.Errorf("Not Found")
+		return NotFoundError("Not Found")
 	}
 	result = *objects[0]
 	return nil
@@ -97,9 +138,14 @@ func (api *Api) GetObjectRefByNameFromGroup(objectGroup, objectName string, grou
 
 // DeleteObject will delete a object
 func (api *Api) DeleteObject(objectName string, object *Object) (string, error) {
+	return api.DeleteObjectContext(context.Background(), objectName, object)
+}
+
+// DeleteObjectContext is like DeleteObject, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) DeleteObjectContext(ctx context.Context, objectName string, object *Object) (string, error) {
 	var result string
 
-	if err := api.makeCall("DELETE", fmt.Sprintf("/api/v1/app/%s/%ss/%d/", api.AppID, objectName, (*object).GetId()), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "DELETE", fmt.Sprintf("/api/v1/app/%s/%ss/%d/", api.AppID, objectName, (*object).GetId()), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -107,10 +153,15 @@ func (api *Api) DeleteObject(objectName string, object *Object) (string, error)
 
 // AddObjectToGroup adds a object (metric, event, etc) to a group of objects.
 func (api *Api) AddObjectToGroup(objectName string, object Object, group Object) (string, error) {
+	return api.AddObjectToGroupContext(context.Background(), objectName, object, group)
+}
+
+// AddObjectToGroupContext is like AddObjectToGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) AddObjectToGroupContext(ctx context.Context, objectName string, object Object, group Object) (string, error) {
 	var objectGroupName = GetObjectGroupName(objectName)
 
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroupName, group.GetId(), objectName, object.GetId()), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroupName, group.GetId(), objectName, object.GetId()), nil, true, &result); err != nil {
 		if IsRequestError(err) {
 			// The object is already in the group. Ignore this error.
 		} else {
@@ -122,10 +173,15 @@ func (api *Api) AddObjectToGroup(objectName string, object Object, group Object)
 
 // DeleteObjectFromGroup remove a object (metric, event, etc) from a group of objects.
 func (api *Api) DeleteObjectFromGroup(objectName string, object Object, group Object) (string, error) {
+	return api.DeleteObjectFromGroupContext(context.Background(), objectName, object, group)
+}
+
+// DeleteObjectFromGroupContext is like DeleteObjectFromGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) DeleteObjectFromGroupContext(ctx context.Context, objectName string, object Object, group Object) (string, error) {
 	var objectGroupName = GetObjectGroupName(objectName)
 
 	var result string
-	if err := api.makeCall("DELETE", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroupName, group.GetId(), objectName, object.GetId()), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "DELETE", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, objectGroupName, group.GetId(), objectName, object.GetId()), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -133,8 +189,13 @@ func (api *Api) DeleteObjectFromGroup(objectName string, object Object, group Ob
 
 // DeleteObjectFromGroupByID remove a object (metric, event, etc) from a group of objects.
 func (api *Api) DeleteObjectFromGroupByID(groupName, objectName string, groupID, id int64) (string, error) {
+	return api.DeleteObjectFromGroupByIDContext(context.Background(), groupName, objectName, groupID, id)
+}
+
+// DeleteObjectFromGroupByIDContext is like DeleteObjectFromGroupByID, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) DeleteObjectFromGroupByIDContext(ctx context.Context, groupName, objectName string, groupID, id int64) (string, error) {
 	var result string
-	if err := api.makeCall("DELETE", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, groupName, groupID, objectName, id), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "DELETE", fmt.Sprintf("/api/v1/app/%s/%ss/%d/%ss/%d/", api.AppID, groupName, groupID, objectName, id), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
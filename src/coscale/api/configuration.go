@@ -3,9 +3,14 @@ package api
 import (
 	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	"os"
+	"sort"
 )
 
+// DefaultProfile is the name of the profile for configuration files without profiles.
+const DefaultProfile = "default"
+
 // ApiConfiguration contains all information to connect with the api.
 type ApiConfiguration struct {
 	BaseUrl     string `json:"baseurl"`
@@ -13,13 +18,114 @@ type ApiConfiguration struct {
 	AppId       string `json:"appid"`
 }
 
-// ReadApiConfiguration reads the api configuration from a file.
+// ApiConfigurations contains the named api configurations (profiles) of a configuration file.
+type ApiConfigurations struct {
+	// Current is the name of the profile that is used when no profile is selected.
+	Current  string                       `json:"current"`
+	Profiles map[string]*ApiConfiguration `json:"profiles"`
+}
+
+// configurationFile is the content of a configuration file. The embedded ApiConfiguration
+// is the format used before profiles were added, it always contains the current profile
+// so the file can still be used by older versions.
+type configurationFile struct {
+	ApiConfiguration
+	ApiConfigurations
+}
+
+// NewApiConfigurations creates an empty set of profiles.
+func NewApiConfigurations() *ApiConfigurations {
+	return &ApiConfigurations{Profiles: make(map[string]*ApiConfiguration)}
+}
+
+// Get returns the configuration for profile, the current profile is used if profile is empty.
+func (c *ApiConfigurations) Get(profile string) (*ApiConfiguration, error) {
+	if profile == "" {
+		profile = c.Current
+	}
+	config, ok := c.Profiles[profile]
+	if !ok {
+		return nil, InvalidConfig(fmt.Sprintf("Profile %s not found.", profile))
+	}
+	return config, nil
+}
+
+// Set adds or replaces the configuration for profile, the first profile becomes the current profile.
+func (c *ApiConfigurations) Set(profile string, config *ApiConfiguration) {
+	c.Profiles[profile] = config
+	if _, ok := c.Profiles[c.Current]; !ok {
+		c.Current = profile
+	}
+}
+
+// Use makes profile the current profile.
+func (c *ApiConfigurations) Use(profile string) error {
+	if _, ok := c.Profiles[profile]; !ok {
+		return InvalidConfig(fmt.Sprintf("Profile %s not found.", profile))
+	}
+	c.Current = profile
+	return nil
+}
+
+// Delete removes profile. If it was the current profile, the default profile or else
+// the first remaining profile becomes the current profile.
+func (c *ApiConfigurations) Delete(profile string) error {
+	if _, ok := c.Profiles[profile]; !ok {
+		return InvalidConfig(fmt.Sprintf("Profile %s not found.", profile))
+	}
+	delete(c.Profiles, profile)
+	if c.Current == profile {
+		c.Current = ""
+		if _, ok := c.Profiles[DefaultProfile]; ok {
+			c.Current = DefaultProfile
+		} else if names := c.Names(); len(names) > 0 {
+			c.Current = names[0]
+		}
+	}
+	return nil
+}
+
+// Names returns the sorted names of the profiles.
+func (c *ApiConfigurations) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadApiConfiguration reads the api configuration of the current profile from a file.
 func ReadApiConfiguration(filename string) (*ApiConfiguration, error) {
-	var configuration ApiConfiguration
-	if err := readConfig(filename, &configuration); err != nil {
+	return ReadApiConfigurationProfile(filename, "")
+}
+
+// ReadApiConfigurationProfile reads the api configuration of a profile from a file,
+// the current profile is used if profile is empty.
+func ReadApiConfigurationProfile(filename, profile string) (*ApiConfiguration, error) {
+	configurations, err := ReadApiConfigurations(filename)
+	if err != nil {
+		return nil, err
+	}
+	return configurations.Get(profile)
+}
+
+// ReadApiConfigurations reads all profiles from a file. A file without profiles is
+// loaded as the default profile.
+func ReadApiConfigurations(filename string) (*ApiConfigurations, error) {
+	var file configurationFile
+	if err := readConfig(filename, &file); err != nil {
 		return nil, err
 	}
-	return &configuration, nil
+
+	configurations := &file.ApiConfigurations
+	if configurations.Profiles == nil {
+		configurations.Profiles = make(map[string]*ApiConfiguration)
+	}
+	if len(configurations.Profiles) == 0 && file.ApiConfiguration != (ApiConfiguration{}) {
+		configurations.Set(DefaultProfile, &file.ApiConfiguration)
+	}
+	return configurations, nil
 }
 
 // readConfig reads a configuration file: gzipped json file.
@@ -43,18 +149,31 @@ func readConfig(filename string, target interface{}) error {
 	return nil
 }
 
-// WriteApiConfiguration writes a configuration file: gzipped json file.
+// WriteApiConfiguration writes a configuration file containing only config as the default profile.
 func WriteApiConfiguration(filename string, config *ApiConfiguration) error {
+	configurations := NewApiConfigurations()
+	configurations.Set(DefaultProfile, config)
+	return WriteApiConfigurations(filename, configurations)
+}
+
+// WriteApiConfigurations writes a configuration file: gzipped json file.
+func WriteApiConfigurations(filename string, configurations *ApiConfigurations) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+
+	content := configurationFile{ApiConfigurations: *configurations}
+	if current, err := configurations.Get(""); err == nil {
+		content.ApiConfiguration = *current
+	}
 
 	writer := gzip.NewWriter(file)
 	defer writer.Close()
 
 	encoder := json.NewEncoder(writer)
-	if err := encoder.Encode(config); err != nil {
+	if err := encoder.Encode(content); err != nil {
 		return err
 	}
 
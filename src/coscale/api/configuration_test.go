@@ -0,0 +1,95 @@
+package api
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Test that a configuration file without profiles is loaded as the default profile.
+func TestReadLegacyConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coscale-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "api.conf")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := gzip.NewWriter(file)
+	writer.Write([]byte(`{"baseurl":"https://api.coscale.com", "appid":"app", "accesstoken":"token"}`))
+	writer.Close()
+	file.Close()
+
+	expected := &ApiConfiguration{"https://api.coscale.com", "token", "app"}
+	obtained, err := ReadApiConfiguration(filename)
+	if err != nil {
+		t.Fatalf("Error occured while reading the configuration: %s", err)
+	}
+	if !reflect.DeepEqual(expected, obtained) {
+		t.Fatalf("expected: \n%v\n, found: \n%v\n", expected, obtained)
+	}
+	if _, err := ReadApiConfigurationProfile(filename, "prod"); !IsInvalidConfig(err) {
+		t.Fatalf("expected InvalidConfig, found: %v", err)
+	}
+}
+
+// Test writing and reading profiles.
+func TestConfigurationProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coscale-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "api.conf")
+
+	staging := &ApiConfiguration{"https://api.coscale.com", "token1", "staging"}
+	prod := &ApiConfiguration{"https://api.coscale.com", "token2", "prod"}
+
+	configurations := NewApiConfigurations()
+	configurations.Set("staging", staging)
+	configurations.Set("prod", prod)
+	if configurations.Current != "staging" {
+		t.Fatalf("expected: %s, found: %s", "staging", configurations.Current)
+	}
+	if err := configurations.Use("prod"); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if err := WriteApiConfigurations(filename, configurations); err != nil {
+		t.Fatalf("Error occured while writing the configuration: %s", err)
+	}
+
+	obtained, err := ReadApiConfigurations(filename)
+	if err != nil {
+		t.Fatalf("Error occured while reading the configuration: %s", err)
+	}
+	if !reflect.DeepEqual(configurations, obtained) {
+		t.Fatalf("expected: \n%v\n, found: \n%v\n", configurations, obtained)
+	}
+
+	// The current profile is also written in the format without profiles.
+	var legacy ApiConfiguration
+	if err := readConfig(filename, &legacy); err != nil {
+		t.Fatalf("Error occured while reading the configuration: %s", err)
+	}
+	if !reflect.DeepEqual(*prod, legacy) {
+		t.Fatalf("expected: \n%v\n, found: \n%v\n", *prod, legacy)
+	}
+
+	// Deleting the current profile selects another one.
+	if err := obtained.Delete("prod"); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if obtained.Current != "staging" {
+		t.Fatalf("expected: %s, found: %s", "staging", obtained.Current)
+	}
+	if err := obtained.Delete("prod"); !IsInvalidConfig(err) {
+		t.Fatalf("expected InvalidConfig, found: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -178,30 +179,129 @@ func splitPoints(dataPoints string, timeInSecAgo bool) (map[string][]*ApiData, e
 			newAPIData.Data = append(newAPIData.Data, DataPoint{time, value[2]})
 		}
 
-		// find the right place for this ApiData in the result
-		// if a callData for this subjectId exists, then the new data belongs to it.
-		if subjectData, found := data[subjectID]; found {
-			// search for an existing ApiData for this metricId
-			var foundMetricID bool
-			for _, apiData := range subjectData {
-				if apiData.MetricID == metricID && apiData.HasDimensions(dimValues) {
-					// found the apiData, append to it just the new dataPoint
-					apiData.Data = append(apiData.Data, newAPIData.Data...)
-					foundMetricID = true
+		addAPIData(data, newAPIData)
+	}
+
+	return data, nil
+}
+
+// addAPIData adds the data points of newAPIData to the ApiData with the same metric, subject and
+// dimension values in data, a new ApiData is added if there is none.
+func addAPIData(data map[string][]*ApiData, newAPIData *ApiData) {
+	subjectID := newAPIData.SubjectID
+	// find the right place for this ApiData in the result
+	// if a callData for this subjectId exists, then the new data belongs to it.
+	if subjectData, found := data[subjectID]; found {
+		// search for an existing ApiData for this metricId
+		var foundMetricID bool
+		for _, apiData := range subjectData {
+			if apiData.MetricID == newAPIData.MetricID && apiData.HasDimensions(newAPIData.DimensionValues) {
+				// found the apiData, append to it just the new dataPoint
+				apiData.Data = append(apiData.Data, newAPIData.Data...)
+				foundMetricID = true
+				break
+			}
+		}
+		// a apiData with the same metric Id doesn't exists, create a new one.
+		if !foundMetricID {
+			data[subjectID] = append(data[subjectID], newAPIData)
+		}
+	} else {
+		// this is a new subjectId, create a new callData.
+		data[subjectID] = []*ApiData{newAPIData}
+	}
+}
+
+// dataNameRefs are the name based references that can be used instead of M<id> and S<id>.
+var dataNameRefs = []struct{ key, objectName, idPrefix string }{
+	{"metric=", "metric", "M"},
+	{"server=", "server", "S"},
+}
+
+// ResolveDataPointNames replaces the metric and subject references by name in dataPoints by
+// their ids, e.g. metric="Response time":server="web-1":-60:1.2 becomes M1:S2:-60:1.2. The
+// names are quoted using json quoting or unquoted up to the next colon. The ids are found
+// using resolve, e.g. the ID function of a NameCache.
+func ResolveDataPointNames(dataPoints string, resolve func(objectName, name string) (int64, error)) (string, error) {
+	var buffer bytes.Buffer
+	i := 0
+	for i < len(dataPoints) {
+		// The metric and the subject are at the start of an entry.
+		for _, ref := range dataNameRefs {
+			if !strings.HasPrefix(dataPoints[i:], ref.key) {
+				end := strings.IndexAny(dataPoints[i:], ":;")
+				if end == -1 || dataPoints[i+end] == ';' {
 					break
 				}
+				buffer.WriteString(dataPoints[i : i+end+1])
+				i += end + 1
+				continue
 			}
-			// a apiData with the same metric Id doesn't exists, create a new one.
-			if !foundMetricID {
-				data[subjectID] = append(data[subjectID], newAPIData)
+			name, next, err := parseDataName(dataPoints, i+len(ref.key))
+			if err != nil {
+				return "", err
+			}
+			id, err := resolve(ref.objectName, name)
+			if err != nil {
+				return "", err
+			}
+			buffer.WriteString(fmt.Sprintf("%s%d", ref.idPrefix, id))
+			i = next
+			if i < len(dataPoints) && dataPoints[i] == ':' {
+				buffer.WriteByte(':')
+				i++
+			}
+		}
+
+		// Copy the rest of the entry, a semicolon in the dimension values does not end the entry.
+		inString, depth := false, 0
+		for ; i < len(dataPoints); i++ {
+			c := dataPoints[i]
+			buffer.WriteByte(c)
+			switch {
+			case inString && c == '\\' && i+1 < len(dataPoints):
+				i++
+				buffer.WriteByte(dataPoints[i])
+			case c == '"':
+				inString = !inString
+			case !inString && c == '{':
+				depth++
+			case !inString && c == '}':
+				depth--
+			}
+			if c == ';' && !inString && depth == 0 {
+				i++
+				break
 			}
-		} else {
-			// this is a new subjectId, create a new callData.
-			data[subjectID] = []*ApiData{newAPIData}
 		}
 	}
+	return buffer.String(), nil
+}
 
-	return data, nil
+// parseDataName parses a quoted or unquoted name starting at i, the position after the name is returned.
+func parseDataName(dataPoints string, i int) (string, int, error) {
+	if i < len(dataPoints) && dataPoints[i] == '"' {
+		for end := i + 1; end < len(dataPoints); end++ {
+			if dataPoints[end] == '\\' {
+				end++
+			} else if dataPoints[end] == '"' {
+				var name string
+				if err := json.Unmarshal([]byte(dataPoints[i:end+1]), &name); err != nil {
+					return "", 0, fmt.Errorf("Bad datapoint format, invalid name %s", dataPoints[i:end+1])
+				}
+				return name, end + 1, nil
+			}
+		}
+		return "", 0, fmt.Errorf("Bad datapoint format, unterminated name %s", dataPoints[i:])
+	}
+	end := strings.IndexAny(dataPoints[i:], ":;")
+	if end == -1 {
+		end = len(dataPoints) - i
+	}
+	if end == 0 {
+		return "", 0, fmt.Errorf("Bad datapoint format, empty name at %s", dataPoints[i:])
+	}
+	return dataPoints[i : i+end], i + end, nil
 }
 
 // ParseDataPoint will parse a dataPoints which is provided by user on the command line
@@ -217,7 +317,25 @@ func ParseDataPoint(dataPoints string, timeInSecAgo bool) ([]map[string][]*ApiDa
 	if err != nil {
 		return nil, err
 	}
+	return batchAPIData(data)
+}
+
+// SplitDataPoints parses dataPoints in the format of ParseDataPoint into ApiData without
+// splitting the data into batches.
+func SplitDataPoints(dataPoints string, timeInSecAgo bool) ([]*ApiData, error) {
+	data, err := splitPoints(dataPoints, timeInSecAgo)
+	if err != nil {
+		return nil, err
+	}
+	var result []*ApiData
+	for _, subjectData := range data {
+		result = append(result, subjectData...)
+	}
+	return result, nil
+}
 
+// batchAPIData splits the data into batches that don't exceed MaxUploadSize.
+func batchAPIData(data map[string][]*ApiData) ([]map[string][]*ApiData, error) {
 	_, uncompressedSize, err := serializeAPIData(data)
 	if err != nil {
 		return nil, err
@@ -349,17 +467,26 @@ func serializeAPIData(data map[string][]*ApiData) (string, int, error) {
 
 // InsertData inserts a batch of data. Returns the number of pending actions.
 func (api *Api) InsertData(data map[string][]*ApiData) (string, error) {
+	return api.InsertDataContext(context.Background(), data)
+}
+
+// InsertDataContext is like InsertData, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) InsertDataContext(ctx context.Context, data map[string][]*ApiData) (string, error) {
 
 	serializedData, _, err := serializeAPIData(data)
 	if err != nil {
 		return "", err
 	}
+	return api.insertSerializedData(ctx, serializedData)
+}
 
+// insertSerializedData inserts a batch of data serialized by serializeAPIData.
+func (api *Api) insertSerializedData(ctx context.Context, serializedData string) (string, error) {
 	postData := map[string][]string{
 		"cdata": {serializedData},
 	}
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/data/", api.AppID), postData, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/data/", api.AppID), postData, true, &result); err != nil {
 		return "", err
 	}
 
@@ -368,11 +495,17 @@ func (api *Api) InsertData(data map[string][]*ApiData) (string, error) {
 
 // GetData performs an API call to retrieve data from the API.
 func (api *Api) GetData(start, stop int, metricId int64, subjectIds, aggregator, viewType, dimensionsSpecs string, aggregateSubjects bool) (string, error) {
+	return api.GetDataContext(context.Background(), start, stop, metricId, subjectIds, aggregator, viewType, dimensionsSpecs, aggregateSubjects)
+}
+
+// GetDataContext is like GetData, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetDataContext(ctx context.Context, start, stop int, metricId int64, subjectIds, aggregator, viewType, dimensionsSpecs string, aggregateSubjects bool) (string, error) {
 	postData := map[string][]string{
 		"data": {getBatchData(start, stop, metricId, subjectIds, aggregator, viewType, dimensionsSpecs, aggregateSubjects)},
 	}
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/data/dimension/getCalculated/", api.AppID), postData, true, &result); err != nil {
+	// getCalculated only reads data.
+	if err := api.makeSafeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/data/dimension/getCalculated/", api.AppID), postData, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -381,14 +514,9 @@ func (api *Api) GetData(start, stop int, metricId int64, subjectIds, aggregator,
 // getBatchData make the json object(with the informations provided on command line) required for GetData-getBatch request
 func getBatchData(start, stop int, metricId int64, subjectIds, aggregator, viewType, dimensionsSpecs string, aggregateSubjects bool) string {
 	var buffer bytes.Buffer
-	var now = int(time.Now().Unix())
 	// negative and null values are seconds ago
-	if start <= 0 {
-		start = now + start
-	}
-	if stop <= 0 {
-		stop = now + stop
-	}
+	now := time.Now()
+	start, stop = UnixTime(start, now), UnixTime(stop, now)
 	buffer.WriteString(fmt.Sprintf(`{"start":%d, "stop":%d, "ids":[{"metricId":%d, "subjects":"`, start, stop, metricId))
 	for i, id := range strings.Split(subjectIds, ",") {
 		if i > 0 {
@@ -399,3 +527,51 @@ func getBatchData(start, stop int, metricId int64, subjectIds, aggregator, viewT
 	buffer.WriteString(fmt.Sprintf(`", "aggregator":"%s", "viewtype":"%s", "dimensionsSpecs":%s, "aggregateSubjects":%t}]}`, aggregator, viewType, dimensionsSpecs, aggregateSubjects))
 	return buffer.String()
 }
+
+// DataBuffer collects data until it is sent with InsertData, e.g. for data that is read as a stream.
+type DataBuffer struct {
+	data map[string][]*ApiData
+	// size is the approximate size of the serialized data.
+	size int
+}
+
+// NewDataBuffer creates an empty DataBuffer.
+func NewDataBuffer() *DataBuffer {
+	return &DataBuffer{data: make(map[string][]*ApiData)}
+}
+
+// AddDataPoints parses dataPoints in the format of ParseDataPoint and adds them to the buffer.
+// Nothing is added if dataPoints contains an error.
+func (b *DataBuffer) AddDataPoints(dataPoints string, timeInSecAgo bool) error {
+	data, err := SplitDataPoints(dataPoints, timeInSecAgo)
+	if err != nil {
+		return err
+	}
+	for _, apiData := range data {
+		b.Add(apiData)
+	}
+	return nil
+}
+
+// Add adds the data points of apiData to the buffer.
+func (b *DataBuffer) Add(apiData *ApiData) {
+	b.size += len(apiData.String())
+	addAPIData(b.data, apiData)
+}
+
+// Size returns the approximate size in bytes of the buffered data.
+func (b *DataBuffer) Size() int {
+	return b.size
+}
+
+// Flush empties the buffer and returns the buffered data split into batches that don't exceed
+// MaxUploadSize, nil if the buffer is empty.
+func (b *DataBuffer) Flush() ([]map[string][]*ApiData, error) {
+	if len(b.data) == 0 {
+		return nil, nil
+	}
+	data := b.data
+	b.data = make(map[string][]*ApiData)
+	b.size = 0
+	return batchAPIData(data)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVMapping defines how the rows of a CSV file are converted into data points for one metric
+// and subject. The first row of the file contains the column names, columns are referenced by
+// name or by their 1-based index.
+type CSVMapping struct {
+	MetricID  int64
+	SubjectID string
+	// TimeColumn contains unix timestamps, seconds ago as negative numbers or RFC 3339 times.
+	TimeColumn  string
+	ValueColumn string
+	// DimensionColumns contain dimension values, the column names are the dimension names.
+	DimensionColumns []string
+	// Comma is the field delimiter, ',' if 0.
+	Comma rune
+}
+
+// csvTimeLayouts are the layouts accepted for times that are not a number, in the local time zone
+// unless the time contains a time zone.
+var csvTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// ParseCSVData converts the rows of a CSV file into data for InsertData, split into batches that
+// don't exceed MaxUploadSize. Rows with an empty value are skipped, errors contain the line number.
+func ParseCSVData(r io.Reader, mapping *CSVMapping) ([]map[string][]*ApiData, error) {
+	reader := csv.NewReader(r)
+	if mapping.Comma != 0 {
+		reader.Comma = mapping.Comma
+	}
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("The CSV file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	timeIndex, err := csvColumn(header, mapping.TimeColumn)
+	if err != nil {
+		return nil, err
+	}
+	valueIndex, err := csvColumn(header, mapping.ValueColumn)
+	if err != nil {
+		return nil, err
+	}
+	dimensionIndexes := make([]int, len(mapping.DimensionColumns))
+	for i, column := range mapping.DimensionColumns {
+		if dimensionIndexes[i], err = csvColumn(header, column); err != nil {
+			return nil, err
+		}
+	}
+
+	data := make(map[string][]*ApiData)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		value := strings.TrimSpace(record[valueIndex])
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", line, value)
+		}
+		timestamp, err := parseCSVTime(strings.TrimSpace(record[timeIndex]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		var dimensionValues map[string]string
+		if len(dimensionIndexes) > 0 {
+			dimensionValues = make(map[string]string)
+			for _, index := range dimensionIndexes {
+				dimensionValues[header[index]] = record[index]
+			}
+		}
+		addAPIData(data, &ApiData{
+			MetricID:        mapping.MetricID,
+			SubjectID:       mapping.SubjectID,
+			Data:            []DataPoint{{timestamp, strconv.FormatFloat(number, 'f', -1, 64)}},
+			DimensionValues: dimensionValues,
+		})
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("The CSV file contains no values")
+	}
+	return batchAPIData(data)
+}
+
+// csvColumn returns the index of a column given by name or 1-based index.
+func csvColumn(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.TrimSpace(name) == column {
+			return i, nil
+		}
+	}
+	if index, err := strconv.Atoi(column); err == nil && index >= 1 && index <= len(header) {
+		return index - 1, nil
+	}
+	return 0, fmt.Errorf("The CSV file has no column %q", column)
+}
+
+// parseCSVTime parses a time in the format used by the API: a unix timestamp, 0 for now or a
+// negative number of seconds ago. Times in csvTimeLayouts are converted into unix timestamps.
+func parseCSVTime(value string) (int, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return seconds, nil
+	}
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return int(t.Unix()), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q", value)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test ParseCSVData with a column mapping.
+func TestParseCSVData(t *testing.T) {
+	csvData := `time,value,queue,host
+1495108650,1.5,q1,a
+-60,"2",q1,b
+1495108710, 3.25 ,q1,a
+1495108770,,q1,a
+2017-05-18T12:00:00Z,4,q2,a
+`
+	mapping := &CSVMapping{MetricID: 1, SubjectID: "S2", TimeColumn: "time", ValueColumn: "2", DimensionColumns: []string{"queue"}}
+	batches, err := ParseCSVData(strings.NewReader(csvData), mapping)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	expected := []map[string][]*ApiData{{
+		"S2": {
+			{1, "S2", []DataPoint{{1495108650, "1.5"}, {-60, "2"}, {1495108710, "3.25"}}, map[string]string{"queue": "q1"}},
+			{1, "S2", []DataPoint{{int(time.Date(2017, 5, 18, 12, 0, 0, 0, time.UTC).Unix()), "4"}}, map[string]string{"queue": "q2"}},
+		},
+	}}
+	if !reflect.DeepEqual(expected, batches) {
+		t.Fatalf("expected: %v, found: %v", expected[0]["S2"], batches[0]["S2"])
+	}
+
+	errors := []struct {
+		csv      string
+		expected string
+	}{
+		{"time,value\n0,1\n60,x\n", `line 3: invalid value "x"`},
+		{"time,value\n0,1\nyesterday,2\n", `line 3: invalid time "yesterday"`},
+		{"time,value\n0,1\n0,1,2\n", "record on line 3: wrong number of fields"},
+		{"t,value\n0,1\n", `The CSV file has no column "time"`},
+		{"time,value\n", "The CSV file contains no values"},
+	}
+	for _, test := range errors {
+		_, err := ParseCSVData(strings.NewReader(test.csv), &CSVMapping{MetricID: 1, SubjectID: "A", TimeColumn: "time", ValueColumn: "value"})
+		if err == nil || err.Error() != test.expected {
+			t.Fatalf("expected: %s, found: %v", test.expected, err)
+		}
+	}
+}
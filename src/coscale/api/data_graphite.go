@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GraphitePoint is a line of the Carbon plaintext protocol of Graphite.
+type GraphitePoint struct {
+	// Path is the dotted metric path, e.g. servers.web-1.cpu.load.
+	Path string
+	// Tags are the tags of a tagged path, e.g. cpu.load;host=web-1.
+	Tags  map[string]string
+	Value string
+	// Time is a unix timestamp in seconds, 0 (the current time) for a line without timestamp or
+	// the timestamp -1.
+	Time int
+}
+
+// ParseGraphiteLine parses a line of the Carbon plaintext protocol: <path> <value> [<timestamp>].
+// eg: servers.web-1.cpu.load 0.82 1465839830
+func ParseGraphiteLine(line string) (*GraphitePoint, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("Bad plaintext format, expected a path, a value and a timestamp")
+	}
+
+	parts := strings.Split(fields[0], ";")
+	point := &GraphitePoint{Path: parts[0]}
+	if point.Path == "" || strings.HasPrefix(point.Path, ".") || strings.HasSuffix(point.Path, ".") || strings.Contains(point.Path, "..") {
+		return nil, fmt.Errorf("Bad plaintext path %q", fields[0])
+	}
+	for _, tag := range parts[1:] {
+		pair := strings.SplitN(tag, "=", 2)
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, fmt.Errorf("Bad plaintext tag %q, expected name=value", tag)
+		}
+		if point.Tags == nil {
+			point.Tags = make(map[string]string)
+		}
+		point.Tags[pair[0]] = pair[1]
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("Bad plaintext value %q", fields[1])
+	}
+	point.Value = strconv.FormatFloat(value, 'f', -1, 64)
+
+	if len(fields) == 3 {
+		timestamp, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || timestamp < -1 {
+			return nil, fmt.Errorf("Bad plaintext timestamp %q", fields[2])
+		}
+		if timestamp > 0 {
+			point.Time = int(timestamp)
+		}
+	}
+	return point, nil
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test ParseGraphiteLine for plain and tagged paths.
+func TestParseGraphiteLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected *GraphitePoint
+	}{
+		{"servers.web-1.cpu.load 0.82 1465839830", &GraphitePoint{"servers.web-1.cpu.load", nil, "0.82", 1465839830}},
+		{"  requests  12  ", &GraphitePoint{"requests", nil, "12", 0}},
+		{"requests 1e3 -1", &GraphitePoint{"requests", nil, "1000", 0}},
+		{"cpu.load;host=web-1;core=0 2.5 1465839830.7", &GraphitePoint{"cpu.load", map[string]string{"host": "web-1", "core": "0"}, "2.5", 1465839830}},
+	}
+	for _, test := range tests {
+		point, err := ParseGraphiteLine(test.line)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", test.line, err)
+		}
+		if !reflect.DeepEqual(test.expected, point) {
+			t.Fatalf("expected: %v, found: %v", test.expected, point)
+		}
+	}
+
+	for _, invalid := range []string{"requests", "requests x 0", "requests nan 0", "requests 1 now", "a..b 1", ".a 1", "a;host 1", "a 1 2 3"} {
+		if _, err := ParseGraphiteLine(invalid); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
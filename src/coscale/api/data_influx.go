@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfluxPoint is a value of a line in the InfluxDB line protocol.
+type InfluxPoint struct {
+	// Metric is the measurement and the field key separated by a dot, or the measurement for
+	// the field "value".
+	Metric string
+	Tags   map[string]string
+	Value  string
+	// Time is a unix timestamp in seconds, 0 (the current time) if the line has no timestamp.
+	Time int
+}
+
+// ParseInfluxLine parses a line in the InfluxDB line protocol, every numeric field of the line
+// is returned as a point. The timestamp is in units of precision, at most a second, e.g.
+// time.Nanosecond. String and boolean fields are skipped.
+// eg: cpu,host=web-1,cpu=cpu0 usage_idle=92.5,processes=210i 1465839830100400200
+func ParseInfluxLine(line string, precision time.Duration) ([]*InfluxPoint, error) {
+	sections := splitInflux(line, ' ')
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, fmt.Errorf("Bad line protocol format, expected a measurement, fields and a timestamp")
+	}
+
+	key := splitInflux(sections[0], ',')
+	measurement := unescapeInflux(key[0])
+	if measurement == "" {
+		return nil, fmt.Errorf("Bad line protocol format, the measurement is empty")
+	}
+	var tags map[string]string
+	for _, tag := range key[1:] {
+		name, value, err := splitInfluxPair(tag)
+		if err != nil {
+			return nil, err
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[name] = value
+	}
+
+	timestamp := 0
+	if len(sections) == 3 {
+		t, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Bad line protocol timestamp %q", sections[2])
+		}
+		timestamp = int(t / int64(time.Second/precision))
+	}
+
+	var points []*InfluxPoint
+	for _, field := range splitInflux(sections[1], ',') {
+		name, value, err := splitInfluxPair(field)
+		if err != nil {
+			return nil, err
+		}
+		number, ok, err := parseInfluxValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("Bad line protocol value %q for field %s", value, name)
+		}
+		if !ok {
+			continue
+		}
+		metric := measurement
+		if name != "value" {
+			metric += "." + name
+		}
+		points = append(points, &InfluxPoint{Metric: metric, Tags: tags, Value: number, Time: timestamp})
+	}
+	return points, nil
+}
+
+// parseInfluxValue returns a field value as a number for the API, false for string and boolean
+// values. Integers have the suffix i or u.
+func parseInfluxValue(value string) (string, bool, error) {
+	if strings.HasPrefix(value, `"`) {
+		return "", false, nil
+	}
+	switch value {
+	case "t", "T", "true", "True", "TRUE", "f", "F", "false", "False", "FALSE":
+		return "", false, nil
+	}
+	if strings.HasSuffix(value, "i") || strings.HasSuffix(value, "u") {
+		number, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+		if err != nil {
+			return "", false, err
+		}
+		return strconv.FormatInt(number, 10), true, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", false, err
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64), true, nil
+}
+
+// splitInfluxPair splits a tag or field into its unescaped key and value.
+func splitInfluxPair(pair string) (string, string, error) {
+	parts := splitInflux(pair, '=')
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Bad line protocol format at %q, expected key=value", pair)
+	}
+	return unescapeInflux(parts[0]), unescapeInflux(parts[1]), nil
+}
+
+// splitInflux splits s at the occurrences of sep that are not escaped by a backslash and are not
+// in a double quoted string.
+func splitInflux(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeInflux removes the backslashes that escape commas, spaces and equal signs.
+func unescapeInflux(s string) string {
+	return strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=").Replace(s)
+}
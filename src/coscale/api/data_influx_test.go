@@ -0,0 +1,42 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test ParseInfluxLine for tags, escapes, field types and timestamps.
+func TestParseInfluxLine(t *testing.T) {
+	points, err := ParseInfluxLine(`cpu\ load,host=web\ 1,region=eu\,west usage=92.5,procs=210i,state="a b,c",up=t 1465839830100400200`, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	tags := map[string]string{"host": "web 1", "region": "eu,west"}
+	expected := []*InfluxPoint{
+		{"cpu load.usage", tags, "92.5", 1465839830},
+		{"cpu load.procs", tags, "210", 1465839830},
+	}
+	if !reflect.DeepEqual(expected, points) {
+		t.Fatalf("expected: %v, found: %v", expected, points)
+	}
+
+	points, err = ParseInfluxLine("requests value=3", time.Second)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if len(points) != 1 || points[0].Metric != "requests" || points[0].Tags != nil || points[0].Time != 0 {
+		t.Fatalf("expected: the measurement without timestamp, found: %v", points[0])
+	}
+
+	points, err = ParseInfluxLine("requests value=3 1465839830100", time.Millisecond)
+	if err != nil || points[0].Time != 1465839830 {
+		t.Fatalf("expected: the timestamp in seconds, found: %v, %v", points, err)
+	}
+
+	for _, invalid := range []string{"cpu", "cpu usage=x", "cpu,host usage=1", "cpu usage=1 now", ",host=a usage=1", "cpu usage=1 1 2"} {
+		if _, err := ParseInfluxLine(invalid, time.Nanosecond); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PrometheusSample is a sample of the Prometheus text or OpenMetrics exposition format.
+type PrometheusSample struct {
+	Name string
+	// Family is the name of the metric family, e.g. http_requests for the sample
+	// http_requests_total, and Type is the type of the family: counter, gauge, histogram,
+	// summary, untyped, ...
+	Family string
+	Type   string
+	Labels map[string]string
+	Value  string
+	// Time is a unix timestamp in seconds, 0 if the sample has no timestamp.
+	Time int
+}
+
+// prometheusSuffixes are the suffixes of the samples of a metric family.
+var prometheusSuffixes = []string{"_total", "_count", "_sum", "_bucket", "_created", "_info", "_gcount", "_gsum"}
+
+// ParsePrometheusText parses the Prometheus text exposition format or, if openMetrics is true,
+// the OpenMetrics text format. Samples with the value NaN or +/-Inf are skipped, errors contain
+// the line number.
+func ParsePrometheusText(r io.Reader, openMetrics bool) ([]*PrometheusSample, error) {
+	types := make(map[string]string)
+	var samples []*PrometheusSample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxUploadSize)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			if len(fields) == 2 && fields[1] == "EOF" {
+				break
+			}
+			continue
+		}
+		sample, err := parsePrometheusSample(line, openMetrics)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", number, err)
+		}
+		if sample == nil {
+			continue
+		}
+		sample.Family, sample.Type = sample.Name, "untyped"
+		if kind, ok := types[sample.Name]; ok {
+			sample.Type = kind
+		} else {
+			for _, suffix := range prometheusSuffixes {
+				if kind, ok := types[strings.TrimSuffix(sample.Name, suffix)]; ok && strings.HasSuffix(sample.Name, suffix) {
+					sample.Family, sample.Type = strings.TrimSuffix(sample.Name, suffix), kind
+					break
+				}
+			}
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// parsePrometheusSample parses a sample line: <name>[{<label>="<value>",...}] <value> [<timestamp>].
+// nil is returned for the values NaN and +/-Inf.
+func parsePrometheusSample(line string, openMetrics bool) (*PrometheusSample, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end < 1 {
+		return nil, fmt.Errorf("Bad sample format, expected a name and a value")
+	}
+	sample := &PrometheusSample{Name: line[:end]}
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parsePrometheusLabels(rest)
+		if err != nil {
+			return nil, err
+		}
+		sample.Labels = labels
+		rest = rest[n:]
+	}
+	// OpenMetrics exemplars follow the sample after a #.
+	if i := strings.Index(rest, "#"); openMetrics && i >= 0 {
+		rest = rest[:i]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) < 1 || len(fields) > 2 {
+		return nil, fmt.Errorf("Bad sample format, expected a value and a timestamp")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("Bad sample value %q", fields[0])
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, nil
+	}
+	sample.Value = strconv.FormatFloat(value, 'f', -1, 64)
+
+	if len(fields) == 2 {
+		timestamp, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Bad sample timestamp %q", fields[1])
+		}
+		// The Prometheus format uses milliseconds, OpenMetrics uses seconds.
+		if !openMetrics {
+			timestamp /= 1000
+		}
+		sample.Time = int(timestamp)
+	}
+	return sample, nil
+}
+
+// parsePrometheusLabels parses the labels at the start of s: {<label>="<value>",...}. The labels
+// and the length of the labels in s are returned.
+func parsePrometheusLabels(s string) (map[string]string, int, error) {
+	labels := make(map[string]string)
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i < len(s) && s[i] == '}' {
+			if len(labels) == 0 {
+				return nil, i + 1, nil
+			}
+			return labels, i + 1, nil
+		}
+		equals := strings.Index(s[i:], "=")
+		if equals < 1 || i+equals+1 >= len(s) || s[i+equals+1] != '"' {
+			return nil, 0, fmt.Errorf("Bad labels %s, expected <label>=\"<value>\"", s)
+		}
+		name := strings.TrimSpace(s[i : i+equals])
+		i += equals + 2
+
+		var value strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("Bad labels %s, the value of %s is not terminated", s, name)
+		}
+		labels[name] = value.String()
+		i++
+	}
+}
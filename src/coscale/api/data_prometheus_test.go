@@ -0,0 +1,62 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test ParsePrometheusText for the families, labels and timestamps.
+func TestParsePrometheusText(t *testing.T) {
+	text := `# HELP http_requests_total The number of requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",path="/a \"b\"\\c"} 1027 1395066363000
+http_requests_total{method="get",} 3
+
+# TYPE latency histogram
+latency_bucket{le="0.1"} 5
+latency_bucket{le="+Inf"} 7
+latency_sum 1.5
+latency_count 7
+# TYPE temperature gauge
+temperature NaN
+temperature{} -2.5e1
+up 1
+`
+	samples, err := ParsePrometheusText(strings.NewReader(text), false)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	expected := []*PrometheusSample{
+		{"http_requests_total", "http_requests_total", "counter", map[string]string{"method": "post", "path": `/a "b"\c`}, "1027", 1395066363},
+		{"http_requests_total", "http_requests_total", "counter", map[string]string{"method": "get"}, "3", 0},
+		{"latency_bucket", "latency", "histogram", map[string]string{"le": "0.1"}, "5", 0},
+		{"latency_bucket", "latency", "histogram", map[string]string{"le": "+Inf"}, "7", 0},
+		{"latency_sum", "latency", "histogram", nil, "1.5", 0},
+		{"latency_count", "latency", "histogram", nil, "7", 0},
+		{"temperature", "temperature", "gauge", nil, "-25", 0},
+		{"up", "up", "untyped", nil, "1", 0},
+	}
+	if !reflect.DeepEqual(expected, samples) {
+		for i := range samples {
+			t.Logf("%v", samples[i])
+		}
+		t.Fatalf("expected: %v, found: %v", expected, samples)
+	}
+
+	// OpenMetrics uses timestamps in seconds, has exemplars and ends with # EOF.
+	openMetrics := "# TYPE jobs counter\njobs_total 4 1395066363.5 # {trace_id=\"a\"} 1\n# EOF\njobs_total 5\n"
+	samples, err = ParsePrometheusText(strings.NewReader(openMetrics), true)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if len(samples) != 1 || samples[0].Family != "jobs" || samples[0].Type != "counter" || samples[0].Time != 1395066363 {
+		t.Fatalf("expected: the counter jobs, found: %v", samples)
+	}
+
+	for _, invalid := range []string{"up", "up x", `up{a="1} 1`, `up{a=1} 1`, "up 1 2 3"} {
+		if _, err := ParsePrometheusText(strings.NewReader(invalid), false); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
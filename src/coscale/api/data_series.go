@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DataSeries is a time series of a metric for a subject and a dimension specification.
+type DataSeries struct {
+	MetricID  int64
+	SubjectID string
+	// Dimensions is the dimension specification of the series as json, e.g. [[1,"AVG(*)"]].
+	Dimensions string
+	Values     []DataValue
+}
+
+// DataValue is a value of a DataSeries, Value is a number or a histogram in the json format of
+// the API.
+type DataValue struct {
+	Time  int64
+	Value string
+}
+
+// calculatedData is an item of the result of GetData: the series by subject for a metric.
+//
+//	[{"metricId":1,"data":{"s1":[{"dimensionSpecs":[[1,"AVG(*)"]],"values":[[1495108590,1.2],...]}]}}]
+type calculatedData struct {
+	MetricID int64 `json:"metricId"`
+	Data     map[string][]struct {
+		DimensionSpecs json.RawMessage     `json:"dimensionSpecs"`
+		Values         [][]json.RawMessage `json:"values"`
+	} `json:"data"`
+}
+
+// ParseDataSeries decodes the result of GetData into series, sorted by metric, subject and
+// dimensions.
+func ParseDataSeries(result string) ([]*DataSeries, error) {
+	var items []*calculatedData
+	if err := json.Unmarshal([]byte(result), &items); err != nil {
+		return nil, fmt.Errorf("Could not parse the data: %s", err)
+	}
+	var series []*DataSeries
+	for _, item := range items {
+		for subjectID, subjectSeries := range item.Data {
+			for _, s := range subjectSeries {
+				dimensions := "[]"
+				if len(s.DimensionSpecs) > 0 && string(s.DimensionSpecs) != "null" {
+					dimensions = compactJSON(s.DimensionSpecs)
+				}
+				newSeries := &DataSeries{MetricID: item.MetricID, SubjectID: subjectID, Dimensions: dimensions}
+				for _, value := range s.Values {
+					if len(value) != 2 {
+						return nil, fmt.Errorf("Could not parse the data: invalid value %s", value)
+					}
+					t, err := strconv.ParseInt(string(value[0]), 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("Could not parse the data: invalid time %s", value[0])
+					}
+					if string(value[1]) == "null" {
+						// No data at this time.
+						continue
+					}
+					newSeries.Values = append(newSeries.Values, DataValue{t, compactJSON(value[1])})
+				}
+				series = append(series, newSeries)
+			}
+		}
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		if series[i].MetricID != series[j].MetricID {
+			return series[i].MetricID < series[j].MetricID
+		}
+		if series[i].SubjectID != series[j].SubjectID {
+			return series[i].SubjectID < series[j].SubjectID
+		}
+		return series[i].Dimensions < series[j].Dimensions
+	})
+	return series, nil
+}
+
+// GetDataSeriesContext is like GetDataContext, but the result is decoded into series. The time
+// range is split into windows of at most window, every window is a separate GetData call.
+func (api *Api) GetDataSeriesContext(ctx context.Context, start, stop int, window time.Duration, metricId int64, subjectIds, aggregator, viewType, dimensionsSpecs string, aggregateSubjects bool) ([]*DataSeries, error) {
+	now := time.Now()
+	start, stop = UnixTime(start, now), UnixTime(stop, now)
+	step := int(window / time.Second)
+	if step <= 0 {
+		step = stop - start
+	}
+
+	var series []*DataSeries
+	for windowStart := start; ; windowStart += step {
+		windowStop := windowStart + step
+		if windowStop > stop {
+			windowStop = stop
+		}
+		result, err := api.GetDataContext(ctx, windowStart, windowStop, metricId, subjectIds, aggregator, viewType, dimensionsSpecs, aggregateSubjects)
+		if err != nil {
+			return nil, err
+		}
+		windowSeries, err := ParseDataSeries(result)
+		if err != nil {
+			return nil, err
+		}
+		series = mergeDataSeries(series, windowSeries)
+		if windowStop >= stop {
+			break
+		}
+	}
+	return series, nil
+}
+
+// mergeDataSeries appends the values of the series of the next window to the series of the
+// previous windows. Values at the border of two windows are only added once.
+func mergeDataSeries(series, next []*DataSeries) []*DataSeries {
+	for _, n := range next {
+		var found *DataSeries
+		for _, s := range series {
+			if s.MetricID == n.MetricID && s.SubjectID == n.SubjectID && s.Dimensions == n.Dimensions {
+				found = s
+				break
+			}
+		}
+		if found == nil {
+			series = append(series, n)
+			continue
+		}
+		for _, value := range n.Values {
+			if last := len(found.Values) - 1; last >= 0 && value.Time <= found.Values[last].Time {
+				continue
+			}
+			found.Values = append(found.Values, value)
+		}
+	}
+	return series
+}
+
+// compactJSON removes the white space from json.
+func compactJSON(raw json.RawMessage) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
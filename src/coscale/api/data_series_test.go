@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that ParseDataSeries decodes the series of every subject and dimension specification.
+func TestParseDataSeries(t *testing.T) {
+	result := `[{"metricId":1,"data":{
+		"s2":[{"dimensionSpecs":[[1, "AVG(*)"]],"values":[[60,1.5],[120,null]]}],
+		"s1":[{"dimensionSpecs":null,"values":[[60,[2,50,[1,2,3]]]]}]}}]`
+	series, err := ParseDataSeries(result)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	expected := []*DataSeries{
+		{MetricID: 1, SubjectID: "s1", Dimensions: "[]", Values: []DataValue{{60, "[2,50,[1,2,3]]"}}},
+		{MetricID: 1, SubjectID: "s2", Dimensions: `[[1,"AVG(*)"]]`, Values: []DataValue{{60, "1.5"}}},
+	}
+	if !reflect.DeepEqual(expected, series) {
+		t.Fatalf("expected: %v, found: %v", expected, series)
+	}
+
+	if _, err := ParseDataSeries(`{"msg":"failed"}`); err == nil {
+		t.Fatalf("expected an error for an invalid result")
+	}
+}
+
+// Test that GetDataSeriesContext splits the time range into windows and merges the series.
+func TestGetDataSeriesWindows(t *testing.T) {
+	var windows []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login/") {
+			fmt.Fprint(w, `{"token":"token"}`)
+			return
+		}
+		var request struct{ Start, Stop int }
+		if err := json.Unmarshal([]byte(r.FormValue("data")), &request); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		windows = append(windows, fmt.Sprintf("%d-%d", request.Start, request.Stop))
+		// A value at the start and at the stop of every window.
+		fmt.Fprintf(w, `[{"metricId":1,"data":{"s1":[{"dimensionSpecs":[],"values":[[%d,1],[%d,2]]}]}}]`, request.Start, request.Stop)
+	}))
+	defer server.Close()
+
+	api := NewApi(server.URL, "token", "app", true, false)
+	series, err := api.GetDataSeriesContext(context.Background(), 1000, 1250, 100*time.Second, 1, "s1", "AVG", "DEFAULT", "[]", false)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if expected := []string{"1000-1100", "1100-1200", "1200-1250"}; !reflect.DeepEqual(expected, windows) {
+		t.Fatalf("expected: %v, found: %v", expected, windows)
+	}
+	expected := []DataValue{{1000, "1"}, {1100, "2"}, {1200, "2"}, {1250, "2"}}
+	if len(series) != 1 || !reflect.DeepEqual(expected, series[0].Values) {
+		t.Fatalf("expected: %v, found: %v", expected, series)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatsdSample is a line of the StatsD protocol.
+type StatsdSample struct {
+	Name string
+	// Type is c for counters, g for gauges, ms for timers and histograms and s for sets.
+	Type string
+	// Value is a number, a gauge value with a sign changes the gauge. For sets the value is a
+	// member of the set.
+	Value      string
+	SampleRate float64
+	// Tags are the DogStatsD tags of the line.
+	Tags map[string]string
+}
+
+// ParseStatsdLine parses a line of the StatsD protocol with optional DogStatsD tags:
+// <name>:<value>|<type>[|@<sample rate>][|#<tag>:<value>,...]
+// eg: api.requests:1|c|@0.1|#status:200
+func ParseStatsdLine(line string) (*StatsdSample, error) {
+	colon := strings.LastIndex(strings.SplitN(line, "|", 2)[0], ":")
+	if colon < 1 {
+		return nil, fmt.Errorf("Bad StatsD format, expected <name>:<value>|<type>")
+	}
+	parts := strings.Split(line[colon+1:], "|")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("Bad StatsD format, expected <name>:<value>|<type>")
+	}
+	sample := &StatsdSample{Name: line[:colon], Type: parts[1], Value: parts[0], SampleRate: 1}
+
+	switch sample.Type {
+	case "h":
+		sample.Type = "ms"
+	case "c", "g", "ms", "s":
+	default:
+		return nil, fmt.Errorf("Bad StatsD type %q, expected c, g, ms, h or s", sample.Type)
+	}
+	if sample.Value == "" {
+		return nil, fmt.Errorf("Bad StatsD format, the value is empty")
+	}
+	if sample.Type != "s" {
+		if _, err := strconv.ParseFloat(sample.Value, 64); err != nil {
+			return nil, fmt.Errorf("Bad StatsD value %q", sample.Value)
+		}
+	}
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, err := strconv.ParseFloat(part[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("Bad StatsD sample rate %q", part)
+			}
+			sample.SampleRate = rate
+		case strings.HasPrefix(part, "#"):
+			for _, tag := range strings.Split(part[1:], ",") {
+				pair := strings.SplitN(tag, ":", 2)
+				if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+					return nil, fmt.Errorf("Bad StatsD tag %q, expected name:value", tag)
+				}
+				if sample.Tags == nil {
+					sample.Tags = make(map[string]string)
+				}
+				sample.Tags[pair[0]] = pair[1]
+			}
+		default:
+			return nil, fmt.Errorf("Bad StatsD format at %q", part)
+		}
+	}
+	return sample, nil
+}
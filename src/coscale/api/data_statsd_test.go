@@ -0,0 +1,35 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test ParseStatsdLine for the types, sample rates and tags.
+func TestParseStatsdLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected *StatsdSample
+	}{
+		{"api.requests:1|c", &StatsdSample{"api.requests", "c", "1", 1, nil}},
+		{"api.requests:2|c|@0.1|#status:200,host:web-1", &StatsdSample{"api.requests", "c", "2", 0.1, map[string]string{"status": "200", "host": "web-1"}}},
+		{"queue.size:-3|g", &StatsdSample{"queue.size", "g", "-3", 1, nil}},
+		{"api.latency:12.5|h", &StatsdSample{"api.latency", "ms", "12.5", 1, nil}},
+		{"api.users:user-1|s", &StatsdSample{"api.users", "s", "user-1", 1, nil}},
+	}
+	for _, test := range tests {
+		sample, err := ParseStatsdLine(test.line)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", test.line, err)
+		}
+		if !reflect.DeepEqual(test.expected, sample) {
+			t.Fatalf("expected: %v, found: %v", test.expected, sample)
+		}
+	}
+
+	for _, invalid := range []string{"requests", ":1|c", "requests:1", "requests:x|c", "requests:|c", "requests:1|x", "requests:1|c|@2", "requests:1|c|#status", "requests:1|c|x"} {
+		if _, err := ParseStatsdLine(invalid); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
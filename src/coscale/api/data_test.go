@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -88,3 +89,68 @@ func TestParseDataPoint(t *testing.T) {
 		t.Fatalf("expected: \n%v\n, found: \n%v\n", "[]", obtained3)
 	}
 }
+
+// Test ResolveDataPointNames for quoted and unquoted names.
+func TestResolveDataPointNames(t *testing.T) {
+	ids := map[string]int64{"metric/Response time": 1, "server/web-1": 2, "metric/a:b": 3}
+	resolve := func(objectName, name string) (int64, error) {
+		if id, ok := ids[objectName+"/"+name]; ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("The %s %q was not found", objectName, name)
+	}
+
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{`metric="Response time":server="web-1":-60:1.2`, `M1:S2:-60:1.2`},
+		{`metric=Response time:server=web-1:[-60:1.2,0:1.1]`, `M1:S2:[-60:1.2,0:1.1]`},
+		{`M5:server=web-1:-60:1.2;metric="a:b":A:0:2`, `M5:S2:-60:1.2;M3:A:0:2`},
+		{`metric="Response time":S7:-60:1.3:{"Queue":"q;1"};metric="a:b":S7:0:1`, `M1:S7:-60:1.3:{"Queue":"q;1"};M3:S7:0:1`},
+		{`M1:S1:-60:1.2`, `M1:S1:-60:1.2`},
+	}
+	for _, test := range tests {
+		found, err := ResolveDataPointNames(test.data, resolve)
+		if err != nil {
+			t.Fatalf("Error occured for %s: %s", test.data, err)
+		}
+		if found != test.expected {
+			t.Fatalf("expected: %s, found: %s", test.expected, found)
+		}
+	}
+
+	for _, data := range []string{`metric="unknown":S1:0:1`, `metric="Response time:S1:0:1`, `metric=:S1:0:1`} {
+		if _, err := ResolveDataPointNames(data, resolve); err == nil {
+			t.Fatalf("expected an error for %s", data)
+		}
+	}
+}
+
+// Test that a DataBuffer groups the data points and is empty after a flush.
+func TestDataBuffer(t *testing.T) {
+	buffer := NewDataBuffer()
+	for _, line := range []string{"M2:S2:-60:1.1,0:1.2", "M2:S2:[-120:9.1]", `M1:S1:-60:[100,50,[1,2,3,4,5,6]]:{"Queue":"q1","Data Center":"data center 1"}`,
+		`M1:S1:[-120:[100,50,[1,2,3,4,5,7]],-180:[100,50,[1,2,3,4,5,8]]]:{"Queue":"q1","Data Center":"data center 1"}`} {
+		if err := buffer.AddDataPoints(line, false); err != nil {
+			t.Fatalf("Error occured: %s", err)
+		}
+	}
+	if err := buffer.AddDataPoints("M1:X1:0:1", false); err == nil {
+		t.Fatalf("expected an error for a bad datapoint")
+	}
+	if buffer.Size() == 0 {
+		t.Fatalf("expected: the size of the data, found: 0")
+	}
+
+	batches, err := buffer.Flush()
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if !reflect.DeepEqual(expected, batches) {
+		t.Fatalf("expected: %v, found: %v", expected, batches)
+	}
+	if batches, _ := buffer.Flush(); batches != nil || buffer.Size() != 0 {
+		t.Fatalf("expected: an empty buffer, found: %v", batches)
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricDefinition is a metric with the names of its dimensions, it is used to validate data.
+type MetricDefinition struct {
+	Metric     *Metric
+	Dimensions map[string]bool
+}
+
+// DataIssue describes data that does not match the definition of its metric.
+type DataIssue struct {
+	MetricID  int64  `json:"metricId"`
+	SubjectID string `json:"subject"`
+	// Time is the time of the data point, if the issue is about a single data point.
+	Time  *int   `json:"time,omitempty"`
+	Issue string `json:"issue"`
+}
+
+// DataIssuesError is returned when data does not match the definitions of its metrics.
+type DataIssuesError []*DataIssue
+
+func (e DataIssuesError) Error() string {
+	issues, _ := json.Marshal([]*DataIssue(e))
+	return fmt.Sprintf(`{"msg":"The data does not match the definition of the metrics","issues":%s}`, issues)
+}
+
+// histogramValuePattern matches a histogram value: [<samples>,<percentile width>,[<percentiles>]].
+var histogramValuePattern = regexp.MustCompile(`^\[([0-9]+),([0-9]+),\[([-0-9.eE+]+(?:,[-0-9.eE+]+)*)\]\]$`)
+
+// GetMetricDefinition gets a metric and the names of its dimensions.
+func (api *Api) GetMetricDefinition(metricID int64) (*MetricDefinition, error) {
+	return api.GetMetricDefinitionContext(context.Background(), metricID)
+}
+
+// GetMetricDefinitionContext is like GetMetricDefinition, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetMetricDefinitionContext(ctx context.Context, metricID int64) (*MetricDefinition, error) {
+	var metric Metric
+	if err := api.GetObjectRefContext(ctx, "metric", metricID, &metric); err != nil {
+		return nil, err
+	}
+	result, err := api.GetDimensionsContext(ctx, metricID)
+	if err != nil {
+		return nil, err
+	}
+	var dimensions []*Dimension
+	if err := json.Unmarshal([]byte(result), &dimensions); err != nil {
+		return nil, fmt.Errorf("Could not parse the dimensions of metric %d: %s", metricID, err)
+	}
+	definition := &MetricDefinition{Metric: &metric, Dimensions: make(map[string]bool)}
+	for _, dimension := range dimensions {
+		definition.Dimensions[dimension.Name] = true
+	}
+	return definition, nil
+}
+
+// ValidateData checks the data of an insert call against the definition of its metric, which is
+// returned by definition. The following is checked:
+//
+//	the value is a number for DOUBLE metrics and a histogram for HISTOGRAM metrics,
+//	unix timestamps are a multiple of the period of the metric,
+//	the dimensions are dimensions of the metric,
+//	the subject is S<server id> for SERVER metrics and A for APPLICATION metrics.
+//
+// The relative times, zero or seconds ago, are aligned by the API and not checked.
+func ValidateData(callData map[string][]*ApiData, definition func(metricID int64) (*MetricDefinition, error)) ([]*DataIssue, error) {
+	var issues []*DataIssue
+	for _, subjectID := range sortedSubjects(callData) {
+		for _, apiData := range callData[subjectID] {
+			def, err := definition(apiData.MetricID)
+			if IsNotFoundError(err) {
+				issues = append(issues, &DataIssue{MetricID: apiData.MetricID, SubjectID: subjectID, Issue: "The metric does not exist"})
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			issues = append(issues, validateAPIData(apiData, def)...)
+		}
+	}
+	return issues, nil
+}
+
+// validateAPIData checks the data points of one metric and subject.
+func validateAPIData(apiData *ApiData, def *MetricDefinition) []*DataIssue {
+	metric := def.Metric
+	var issues []*DataIssue
+	addIssue := func(time *int, format string, args ...interface{}) {
+		issues = append(issues, &DataIssue{MetricID: apiData.MetricID, SubjectID: apiData.SubjectID, Time: time, Issue: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case metric.Subject == "SERVER" && !strings.HasPrefix(apiData.SubjectID, "S"):
+		addIssue(nil, "The metric %q is a SERVER metric, the subject should be S<server id>", metric.Name)
+	case metric.Subject == "APPLICATION" && apiData.SubjectID != "A":
+		addIssue(nil, "The metric %q is an APPLICATION metric, the subject should be A", metric.Name)
+	}
+
+	var dimensions []string
+	for dimension := range apiData.DimensionValues {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Strings(dimensions)
+	for _, dimension := range dimensions {
+		if !def.Dimensions[dimension] {
+			addIssue(nil, "The metric %q has no dimension %q", metric.Name, dimension)
+		}
+	}
+
+	for i := range apiData.Data {
+		point := &apiData.Data[i]
+		if issue := validateValue(point.Data, metric.DataType); issue != "" {
+			addIssue(&point.SecondsAgo, "%s for the %s metric %q", issue, metric.DataType, metric.Name)
+		}
+		if point.SecondsAgo > 0 && metric.Period > 0 && point.SecondsAgo%metric.Period != 0 {
+			addIssue(&point.SecondsAgo, "The time is not a multiple of the period of %d seconds of the metric %q", metric.Period, metric.Name)
+		}
+	}
+	return issues
+}
+
+// validateValue checks the value of a data point for the data type, a description of the issue is
+// returned if the value is invalid.
+func validateValue(value, dataType string) string {
+	if dataType != "HISTOGRAM" {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Sprintf("The value %s is not a number", value)
+		}
+		return ""
+	}
+	parts := histogramValuePattern.FindStringSubmatch(value)
+	if parts == nil {
+		return fmt.Sprintf("The value %s is not a histogram", value)
+	}
+	width, _ := strconv.Atoi(parts[2])
+	if !ValidPercentileWidth(width) {
+		return fmt.Sprintf("The histogram %s has an invalid percentile width", value)
+	}
+	if percentiles := strings.Count(parts[3], ",") + 1; percentiles != 100/width+1 {
+		return fmt.Sprintf("The histogram %s has %d percentiles instead of %d", value, percentiles, 100/width+1)
+	}
+	return ""
+}
+
+// sortedSubjects returns the subjects of the data of an insert call in order.
+func sortedSubjects(callData map[string][]*ApiData) []string {
+	subjects := make([]string, 0, len(callData))
+	for subjectID := range callData {
+		subjects = append(subjects, subjectID)
+	}
+	sort.Strings(subjects)
+	return subjects
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test that ValidateData reports the data that does not match the definition of the metrics.
+func TestValidateData(t *testing.T) {
+	definitions := map[int64]*MetricDefinition{
+		1: {&Metric{ID: 1, Name: "cpu", DataType: "DOUBLE", Period: 60, Subject: "SERVER"}, map[string]bool{"core": true}},
+		2: {&Metric{ID: 2, Name: "latency", DataType: "HISTOGRAM", Period: 60, Subject: "APPLICATION"}, map[string]bool{}},
+	}
+	definition := func(metricID int64) (*MetricDefinition, error) {
+		if def, ok := definitions[metricID]; ok {
+			return def, nil
+		}
+		return nil, NotFoundError("Instance not found")
+	}
+
+	tests := []struct {
+		data   string
+		issues []string
+	}{
+		{`M1:S1:[1495108620:1.2,-30:1.3]:{"core":"0"};M2:A:-60:[2,50,[1,2,3]]`, nil},
+		{`M1:S1:1495108650:1.2`, []string{"multiple of the period"}},
+		{`M1:A:0:1.2`, []string{"subject should be S<server id>"}},
+		{`M2:S1:0:[2,50,[1,2,3]]`, []string{"subject should be A"}},
+		{`M1:S1:0:1.2:{"queue":"q1"}`, []string{`no dimension "queue"`}},
+		{`M2:A:0:1.2`, []string{"not a histogram"}},
+		{`M2:A:0:[2,50,[1,2]]`, []string{"2 percentiles instead of 3"}},
+		{`M1:S1:0:[2,50,[1,2,3]]`, []string{"not a number"}},
+		{`M3:A:0:1`, []string{"does not exist"}},
+	}
+	for _, test := range tests {
+		callsData, err := ParseDataPoint(test.data, false)
+		if err != nil {
+			t.Fatalf("Error occured for %s: %s", test.data, err)
+		}
+		var issues []*DataIssue
+		for _, callData := range callsData {
+			found, err := ValidateData(callData, definition)
+			if err != nil {
+				t.Fatalf("Error occured for %s: %s", test.data, err)
+			}
+			issues = append(issues, found...)
+		}
+		if len(issues) != len(test.issues) {
+			t.Fatalf("expected: %d issues for %s, found: %s", len(test.issues), test.data, DataIssuesError(issues))
+		}
+		for i, issue := range issues {
+			if !strings.Contains(issue.Issue, test.issues[i]) {
+				t.Fatalf("expected: %q, found: %q", test.issues[i], issue.Issue)
+			}
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package api
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // Dimension is a metric dimension.
 type Dimension struct {
@@ -15,8 +18,13 @@ func (d *Dimension) String() string {
 
 // GetDimension gets one dimension by its id.
 func (api *Api) GetDimension(id int64) (string, error) {
+	return api.GetDimensionContext(context.Background(), id)
+}
+
+// GetDimensionContext is like GetDimension, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetDimensionContext(ctx context.Context, id int64) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/dimensions/%d/", api.AppID, id), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/dimensions/%d/", api.AppID, id), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -24,8 +32,13 @@ func (api *Api) GetDimension(id int64) (string, error) {
 
 // GetDimensions gets the dimensions for a metric
 func (api *Api) GetDimensions(metricID int64) (string, error) {
+	return api.GetDimensionsContext(context.Background(), metricID)
+}
+
+// GetDimensionsContext is like GetDimensions, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetDimensionsContext(ctx context.Context, metricID int64) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/metrics/%d/dimensions/", api.AppID, metricID), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/metrics/%d/dimensions/", api.AppID, metricID), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -33,14 +46,19 @@ func (api *Api) GetDimensions(metricID int64) (string, error) {
 
 // CreateDimension creates a new dimension.
 func (api *Api) CreateDimension(name string) (string, error) {
+	return api.CreateDimensionContext(context.Background(), name)
+}
+
+// CreateDimensionContext is like CreateDimension, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateDimensionContext(ctx context.Context, name string) (string, error) {
 	data := map[string][]string{
 		"name": {name},
 	}
 
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/dimensions/", api.AppID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/dimensions/", api.AppID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetDimension(id)
+			return api.GetDimensionContext(ctx, id)
 		}
 		return "", err
 	}
@@ -1,6 +1,10 @@
 package api
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"time"
+)
 
 // Event describes the event object on the API
 type Event struct {
@@ -38,6 +42,11 @@ func (e EventData) GetId() int64 {
 
 // CreateEvent creates an Event using the API.
 func (api *Api) CreateEvent(name, description, attributeDescriptions, typeString string) (string, error) {
+	return api.CreateEventContext(context.Background(), name, description, attributeDescriptions, typeString)
+}
+
+// CreateEventContext is like CreateEvent, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateEventContext(ctx context.Context, name, description, attributeDescriptions, typeString string) (string, error) {
 	data := map[string][]string{
 		"name":                  {name},
 		"description":           {description},
@@ -46,9 +55,9 @@ func (api *Api) CreateEvent(name, description, attributeDescriptions, typeString
 		"source":                {GetSource()},
 	}
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/events/", api.AppID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/events/", api.AppID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetObject("event", id)
+			return api.GetObjectContext(ctx, "event", id)
 		}
 		return "", err
 	}
@@ -57,6 +66,11 @@ func (api *Api) CreateEvent(name, description, attributeDescriptions, typeString
 
 // UpdateEvent updates an Event using the API.
 func (api *Api) UpdateEvent(event *Event) (string, error) {
+	return api.UpdateEventContext(context.Background(), event)
+}
+
+// UpdateEventContext is like UpdateEvent, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateEventContext(ctx context.Context, event *Event) (string, error) {
 	data := map[string][]string{
 		"name":                  {event.Name},
 		"description":           {event.Description},
@@ -66,15 +80,20 @@ func (api *Api) UpdateEvent(event *Event) (string, error) {
 		"version":               {fmt.Sprintf("%d", event.Version)},
 	}
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/events/%d/", api.AppID, event.ID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/events/%d/", api.AppID, event.ID), data, true, &result); err != nil {
 		return "", err
 	}
-	return api.GetObject("event", event.ID)
+	return api.GetObjectContext(ctx, "event", event.ID)
 }
 
 // DeleteEvent deletes an Event using the API.
 func (api *Api) DeleteEvent(event *Event) error {
-	if err := api.makeCall("DELETE", fmt.Sprintf("/api/v1/app/%s/events/%d/", api.AppID, event.ID), nil, false, nil); err != nil {
+	return api.DeleteEventContext(context.Background(), event)
+}
+
+// DeleteEventContext is like DeleteEvent, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) DeleteEventContext(ctx context.Context, event *Event) error {
+	if err := api.makeCall(ctx, "DELETE", fmt.Sprintf("/api/v1/app/%s/events/%d/", api.AppID, event.ID), nil, false, nil); err != nil {
 		return err
 	}
 	return nil
@@ -82,16 +101,46 @@ func (api *Api) DeleteEvent(event *Event) error {
 
 // ListEventData will return a list of eventdata for the event Id
 func (api *Api) ListEventData(eventId, since, before int64) (string, error) {
+	return api.ListEventDataContext(context.Background(), eventId, since, before)
+}
+
+// ListEventDataContext is like ListEventData, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) ListEventDataContext(ctx context.Context, eventId, since, before int64) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/events/%d/data/?start=%d&stop=%d", api.AppID, eventId, since, before), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/events/%d/data/?start=%d&stop=%d", api.AppID, eventId, since, before), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
 }
 
+// ListEventDataRange is like ListEventData, since and before are times in the format of ParseTime:
+// unix timestamps, 0 for now or negative seconds ago. nil leaves the start or stop open.
+func (api *Api) ListEventDataRange(eventId int64, since, before *int) (string, error) {
+	return api.ListEventDataRangeContext(context.Background(), eventId, since, before)
+}
+
+// ListEventDataRangeContext is like ListEventDataRange, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) ListEventDataRangeContext(ctx context.Context, eventId int64, since, before *int) (string, error) {
+	now := time.Now()
+	// The API leaves the start or stop open for -1.
+	start, stop := int64(-1), int64(-1)
+	if since != nil {
+		start = int64(UnixTime(*since, now))
+	}
+	if before != nil {
+		stop = int64(UnixTime(*before, now))
+	}
+	return api.ListEventDataContext(ctx, eventId, start, stop)
+}
+
 // GetEventData will return the eventdata by the event Id and eventdata Id.
 func (api *Api) GetEventData(eventId, eventdataId int64, eventData *EventData) error {
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/events/%d/data/get/%d/", api.AppID, eventId, eventdataId), nil, false, &eventData); err != nil {
+	return api.GetEventDataContext(context.Background(), eventId, eventdataId, eventData)
+}
+
+// GetEventDataContext is like GetEventData, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetEventDataContext(ctx context.Context, eventId, eventdataId int64, eventData *EventData) error {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/events/%d/data/get/%d/", api.AppID, eventId, eventdataId), nil, false, &eventData); err != nil {
 		return err
 	}
 	return nil
@@ -99,6 +148,11 @@ func (api *Api) GetEventData(eventId, eventdataId int64, eventData *EventData) e
 
 // InsertEventData inserts EventData for a given Event using the API.
 func (api *Api) InsertEventData(id int64, message, subject, attribute string, timestamp, stopTime int64) (string, error) {
+	return api.InsertEventDataContext(context.Background(), id, message, subject, attribute, timestamp, stopTime)
+}
+
+// InsertEventDataContext is like InsertEventData, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) InsertEventDataContext(ctx context.Context, id int64, message, subject, attribute string, timestamp, stopTime int64) (string, error) {
 	data := map[string][]string{
 		"message":   {message},
 		"timestamp": {fmt.Sprintf("%d", timestamp)},
@@ -111,7 +165,7 @@ func (api *Api) InsertEventData(id int64, message, subject, attribute string, ti
 	}
 
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/events/%d/data/", api.AppID, id), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/events/%d/data/", api.AppID, id), data, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -119,6 +173,11 @@ func (api *Api) InsertEventData(id int64, message, subject, attribute string, ti
 
 // UpdateEventData updates EventData using the API.
 func (api *Api) UpdateEventData(eventId, eventdataId int64, eventData *EventData) (string, error) {
+	return api.UpdateEventDataContext(context.Background(), eventId, eventdataId, eventData)
+}
+
+// UpdateEventDataContext is like UpdateEventData, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateEventDataContext(ctx context.Context, eventId, eventdataId int64, eventData *EventData) (string, error) {
 	data := map[string][]string{
 		"message":   {eventData.Message},
 		"timestamp": {fmt.Sprintf("%d", eventData.Timestamp)},
@@ -131,7 +190,7 @@ func (api *Api) UpdateEventData(eventId, eventdataId int64, eventData *EventData
 		data["stopTime"] = []string{fmt.Sprintf("%d", eventData.Stoptime)}
 	}
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/events/%d/data/%d/", api.AppID, eventId, eventData.ID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/events/%d/data/%d/", api.AppID, eventId, eventData.ID), data, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -139,7 +198,12 @@ func (api *Api) UpdateEventData(eventId, eventdataId int64, eventData *EventData
 
 // DeleteEventData deletes EventData using the API.
 func (api *Api) DeleteEventData(eventId, eventdataId int64) error {
-	if err := api.makeCall("DELETE", fmt.Sprintf("/api/v1/app/%s/events/%d/data/%d/", api.AppID, eventId, eventdataId), nil, false, nil); err != nil {
+	return api.DeleteEventDataContext(context.Background(), eventId, eventdataId)
+}
+
+// DeleteEventDataContext is like DeleteEventData, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) DeleteEventDataContext(ctx context.Context, eventId, eventdataId int64) error {
+	if err := api.makeCall(ctx, "DELETE", fmt.Sprintf("/api/v1/app/%s/events/%d/data/%d/", api.AppID, eventId, eventdataId), nil, false, nil); err != nil {
 		return err
 	}
 	return nil
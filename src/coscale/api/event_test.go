@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that ListEventData passes the times as they are and that ListEventDataRange converts the
+// relative times and leaves the start or stop open for nil.
+func TestListEventData(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login/") {
+			fmt.Fprint(w, `{"token":"token"}`)
+			return
+		}
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	api := NewApi(server.URL, "token", "app", true, false)
+
+	if _, err := api.ListEventData(1, -3600, 1495108650); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if query != "start=-3600&stop=1495108650" {
+		t.Fatalf("expected: start=-3600&stop=1495108650, found: %s", query)
+	}
+
+	if _, err := api.ListEventDataRange(1, nil, nil); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if query != "start=-1&stop=-1" {
+		t.Fatalf("expected: start=-1&stop=-1, found: %s", query)
+	}
+
+	// -1 is one second ago, e.g. for the time expression -1s.
+	since, before := -1, 1495108650
+	start := time.Now().Unix()
+	if _, err := api.ListEventDataRange(1, &since, &before); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	end := time.Now().Unix()
+	var found int64
+	if _, err := fmt.Sscanf(query, "start=%d&stop=1495108650", &found); err != nil || found < start-1 || found > end-1 {
+		t.Fatalf("expected: start=%d&stop=1495108650, found: %s", start-1, query)
+	}
+}
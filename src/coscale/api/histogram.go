@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Histogram is the value of a data point of a HISTOGRAM metric.
+type Histogram struct {
+	// Samples is the number of samples.
+	Samples int
+	// PercentileWidth is the distance between the percentiles, 100 is a multiple of the width.
+	PercentileWidth int
+	// Percentiles are the 0th, PercentileWidth, 2*PercentileWidth, ... and 100th percentile.
+	Percentiles []float64
+}
+
+// ValidPercentileWidth checks if width can be used as the distance between the percentiles of a
+// histogram: 100 should be a multiple of the width.
+func ValidPercentileWidth(width int) bool {
+	return width >= 1 && width <= 100 && 100%width == 0
+}
+
+// NewHistogram computes the histogram of raw samples, e.g. latencies, with percentiles at every
+// width percent. The percentiles are the nearest rank: the smallest sample that is larger than or
+// equal to the percentage of the samples. The data format has no negative percentiles, so the
+// samples can not be negative.
+func NewHistogram(samples []float64, width int) (*Histogram, error) {
+	if !ValidPercentileWidth(width) {
+		return nil, fmt.Errorf("Invalid percentile width %d, 100 should be a multiple of the width", width)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("A histogram needs at least one sample")
+	}
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+	for _, sample := range sorted {
+		if math.IsNaN(sample) || math.IsInf(sample, 0) {
+			return nil, fmt.Errorf("Invalid sample %v", sample)
+		}
+		if sample < 0 {
+			return nil, fmt.Errorf("Invalid sample %v, the samples of a histogram can not be negative", sample)
+		}
+	}
+
+	histogram := &Histogram{Samples: len(sorted), PercentileWidth: width}
+	for p := 0; p <= 100; p += width {
+		rank := int(math.Ceil(float64(p)*float64(len(sorted))/100)) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		histogram.Percentiles = append(histogram.Percentiles, sorted[rank])
+	}
+	return histogram, nil
+}
+
+// String formats the histogram in the format of the API: [<samples>,<percentile width>,[<percentiles>]].
+func (h *Histogram) String() string {
+	percentiles := make([]string, len(h.Percentiles))
+	for i, percentile := range h.Percentiles {
+		percentiles[i] = strconv.FormatFloat(percentile, 'f', -1, 64)
+	}
+	return fmt.Sprintf("[%d,%d,[%s]]", h.Samples, h.PercentileWidth, strings.Join(percentiles, ","))
+}
+
+// NewHistogramDataPoint creates a data point with the histogram of raw samples, see NewHistogram.
+func NewHistogramDataPoint(time int, samples []float64, width int) (DataPoint, error) {
+	histogram, err := NewHistogram(samples, width)
+	if err != nil {
+		return DataPoint{}, err
+	}
+	return DataPoint{time, histogram.String()}, nil
+}
+
+// rawSamplesPattern matches a time with a list of raw samples: <time>:[<sample>,<sample>,...]
+// Negative samples are matched so NewHistogram reports them instead of a bad data point format.
+var rawSamplesPattern = regexp.MustCompile(`(-?[0-9]+):\[([-0-9.,eE+ ]*)\]`)
+
+// ExpandRawSamples replaces the lists of raw samples in dataPoints by their histogram, e.g.
+// M1:S1:0:[12,15,30] becomes M1:S1:0:[3,50,[12,15,30]] for the width 50. Values that are already
+// a histogram are not changed.
+func ExpandRawSamples(dataPoints string, width int) (string, error) {
+	var expandErr error
+	expanded := rawSamplesPattern.ReplaceAllStringFunc(dataPoints, func(match string) string {
+		parts := rawSamplesPattern.FindStringSubmatch(match)
+		var samples []float64
+		for _, value := range strings.Split(parts[2], ",") {
+			sample, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				expandErr = fmt.Errorf("Invalid sample %q", value)
+				return match
+			}
+			samples = append(samples, sample)
+		}
+		histogram, err := NewHistogram(samples, width)
+		if err != nil {
+			expandErr = err
+			return match
+		}
+		return parts[1] + ":" + histogram.String()
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
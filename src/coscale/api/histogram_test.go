@@ -0,0 +1,79 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test the percentiles of NewHistogram.
+func TestNewHistogram(t *testing.T) {
+	histogram, err := NewHistogram([]float64{50, 15, 40, 20, 35}, 25)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	expected := &Histogram{5, 25, []float64{15, 20, 35, 40, 50}}
+	if !reflect.DeepEqual(expected, histogram) {
+		t.Fatalf("expected: %v, found: %v", expected, histogram)
+	}
+	if found := histogram.String(); found != "[5,25,[15,20,35,40,50]]" {
+		t.Fatalf("expected: [5,25,[15,20,35,40,50]], found: %s", found)
+	}
+
+	point, err := NewHistogramDataPoint(-60, []float64{1.5}, 100)
+	if err != nil || point.String() != "[-60,[1,100,[1.5,1.5]]]" {
+		t.Fatalf("expected: [-60,[1,100,[1.5,1.5]]], found: %s %v", point.String(), err)
+	}
+
+	if _, err := NewHistogram(nil, 10); err == nil {
+		t.Fatalf("expected an error without samples")
+	}
+	if _, err := NewHistogram([]float64{1}, 30); err == nil {
+		t.Fatalf("expected an error for the width 30")
+	}
+	expectedError := "Invalid sample -2, the samples of a histogram can not be negative"
+	if _, err := NewHistogram([]float64{3, -2}, 50); err == nil || err.Error() != expectedError {
+		t.Fatalf("expected: %s, found: %v", expectedError, err)
+	}
+	for width, valid := range map[int]bool{1: true, 10: true, 100: true, 0: false, 30: false, 200: false} {
+		if ValidPercentileWidth(width) != valid {
+			t.Fatalf("expected: %v for the width %d, found: %v", valid, width, !valid)
+		}
+	}
+}
+
+// Test that ExpandRawSamples replaces the raw samples by a histogram that ParseDataPoint accepts.
+func TestExpandRawSamples(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"M1:S1:0:[30,10,20]", "M1:S1:0:[3,50,[10,20,30]]"},
+		{"M1:S1:[-60:[1,2],0:[3]];M2:A:0:1.5", "M1:S1:[-60:[2,50,[1,1,2]],0:[1,50,[3,3,3]]];M2:A:0:1.5"},
+		{"M1:S1:0:[2,50,[1,1,2]]", "M1:S1:0:[2,50,[1,1,2]]"},
+		{"M1:S1:[-60:1.2,0:1.1]", "M1:S1:[-60:1.2,0:1.1]"},
+		{"M1:S1:0:[1e3,2.5E-1]", "M1:S1:0:[2,50,[0.25,0.25,1000]]"},
+	}
+	for _, test := range tests {
+		found, err := ExpandRawSamples(test.data, 50)
+		if err != nil {
+			t.Fatalf("Error occured for %s: %s", test.data, err)
+		}
+		if found != test.expected {
+			t.Fatalf("expected: %s, found: %s", test.expected, found)
+		}
+		if _, err := ParseDataPoint(found, false); err != nil {
+			t.Fatalf("Error occured for %s: %s", found, err)
+		}
+	}
+
+	for _, invalid := range []string{"M1:S1:0:[]", "M1:S1:0:[1,,2]", "M1:S1:0:[1,-5]", "M1:S1:[0:[2],-60:[-0.5]]"} {
+		if _, err := ExpandRawSamples(invalid, 50); err == nil {
+			t.Fatalf("expected an error for %s", invalid)
+		}
+	}
+	_, err := ExpandRawSamples("M1:S1:0:[1,-5]", 50)
+	if err == nil || !strings.Contains(err.Error(), "can not be negative") {
+		t.Fatalf("expected an error for the negative sample, found: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -44,6 +45,11 @@ func (e MetricGroup) GetId() int64 {
 
 // CreateMetric creates a new Metric using the API.
 func (api *Api) CreateMetric(name, description, datatype, unit, subject string, period int) (string, error) {
+	return api.CreateMetricContext(context.Background(), name, description, datatype, unit, subject, period)
+}
+
+// CreateMetricContext is like CreateMetric, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateMetricContext(ctx context.Context, name, description, datatype, unit, subject string, period int) (string, error) {
 	data := map[string][]string{
 		"name":        {name},
 		"description": {description},
@@ -54,9 +60,9 @@ func (api *Api) CreateMetric(name, description, datatype, unit, subject string,
 		"source":      {GetSource()},
 	}
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/metrics/", api.AppID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/metrics/", api.AppID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetObject("metric", id)
+			return api.GetObjectContext(ctx, "metric", id)
 		}
 		return "", err
 	}
@@ -65,6 +71,11 @@ func (api *Api) CreateMetric(name, description, datatype, unit, subject string,
 
 // UpdateMetric updates an existing Metric using the API.
 func (api *Api) UpdateMetric(metric *Metric) (string, error) {
+	return api.UpdateMetricContext(context.Background(), metric)
+}
+
+// UpdateMetricContext is like UpdateMetric, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateMetricContext(ctx context.Context, metric *Metric) (string, error) {
 	data := map[string][]string{
 		"name":        {metric.Name},
 		"description": {metric.Description},
@@ -76,14 +87,19 @@ func (api *Api) UpdateMetric(metric *Metric) (string, error) {
 		"version":     {fmt.Sprintf("%d", metric.Version)},
 	}
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/metrics/%d/", api.AppID, metric.ID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/metrics/%d/", api.AppID, metric.ID), data, true, &result); err != nil {
 		return "", err
 	}
-	return api.GetObject("metric", metric.ID)
+	return api.GetObjectContext(ctx, "metric", metric.ID)
 }
 
 // CreateMetricGroup creates a new metric group using the API.
 func (api *Api) CreateMetricGroup(name, description, Type, state, subject string) (string, error) {
+	return api.CreateMetricGroupContext(context.Background(), name, description, Type, state, subject)
+}
+
+// CreateMetricGroupContext is like CreateMetricGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateMetricGroupContext(ctx context.Context, name, description, Type, state, subject string) (string, error) {
 	data := map[string][]string{
 		"name":        {name},
 		"description": {description},
@@ -93,9 +109,9 @@ func (api *Api) CreateMetricGroup(name, description, Type, state, subject string
 		"source":      {GetSource()},
 	}
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/metricgroups/", api.AppID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/metricgroups/", api.AppID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetObject("metricgroup", id)
+			return api.GetObjectContext(ctx, "metricgroup", id)
 		}
 		return "", err
 	}
@@ -104,6 +120,11 @@ func (api *Api) CreateMetricGroup(name, description, Type, state, subject string
 
 // UpdateMetricGroup updates the MetricGroup using the API.
 func (api *Api) UpdateMetricGroup(metricGroup *MetricGroup) (string, error) {
+	return api.UpdateMetricGroupContext(context.Background(), metricGroup)
+}
+
+// UpdateMetricGroupContext is like UpdateMetricGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateMetricGroupContext(ctx context.Context, metricGroup *MetricGroup) (string, error) {
 	data := map[string][]string{
 		"name":        {metricGroup.Name},
 		"description": {metricGroup.Description},
@@ -114,16 +135,21 @@ func (api *Api) UpdateMetricGroup(metricGroup *MetricGroup) (string, error) {
 		"version":     {fmt.Sprintf("%d", metricGroup.Version)},
 	}
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/metricgroups/%d/", api.AppID, metricGroup.ID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/metricgroups/%d/", api.AppID, metricGroup.ID), data, true, &result); err != nil {
 		return "", err
 	}
-	return api.GetObject("metricgroup", metricGroup.ID)
+	return api.GetObjectContext(ctx, "metricgroup", metricGroup.ID)
 }
 
 // GetMetricsByGroup will return all the metrics from a metricgroup.
 func (api *Api) GetMetricsByGroup(metricGroup *MetricGroup) (string, error) {
+	return api.GetMetricsByGroupContext(context.Background(), metricGroup)
+}
+
+// GetMetricsByGroupContext is like GetMetricsByGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetMetricsByGroupContext(ctx context.Context, metricGroup *MetricGroup) (string, error) {
 	var result string
-	if err := api.makeCall("GET", fmt.Sprintf("/api/v1/app/%s/metricgroups/%d/metrics/", api.AppID, metricGroup.GetId()), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "GET", fmt.Sprintf("/api/v1/app/%s/metricgroups/%d/metrics/", api.AppID, metricGroup.GetId()), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -131,8 +157,13 @@ func (api *Api) GetMetricsByGroup(metricGroup *MetricGroup) (string, error) {
 
 // AddMetricDimension adds a dimension to a metric.
 func (api *Api) AddMetricDimension(metricID, dimensionID int64) (string, error) {
+	return api.AddMetricDimensionContext(context.Background(), metricID, dimensionID)
+}
+
+// AddMetricDimensionContext is like AddMetricDimension, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) AddMetricDimensionContext(ctx context.Context, metricID, dimensionID int64) (string, error) {
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/metrics/%d/dimensions/%d/", api.AppID, metricID, dimensionID), nil, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/metrics/%d/dimensions/%d/", api.AppID, metricID, dimensionID), nil, true, &result); err != nil {
 		return "", err
 	}
 	return result, nil
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// NameCache resolves the ids of objects by name using GetObjectRefByName. The ids are cached,
+// so every name is looked up once.
+type NameCache struct {
+	api *Api
+	ctx context.Context
+	mu  sync.Mutex
+	ids map[string]int64
+}
+
+// NewNameCache creates a NameCache, ctx is used for the API calls.
+func (api *Api) NewNameCache(ctx context.Context) *NameCache {
+	return &NameCache{api: api, ctx: ctx, ids: make(map[string]int64)}
+}
+
+// ID returns the id of the object with objectName (metric, server, ...) and name.
+func (c *NameCache) ID(objectName, name string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := objectName + "/" + name
+	if id, ok := c.ids[key]; ok {
+		return id, nil
+	}
+	var object Object
+	switch objectName {
+	case "metric":
+		object = &Metric{}
+	case "server":
+		object = &Server{}
+	case "servergroup":
+		object = &ServerGroup{}
+	case "event":
+		object = &Event{}
+	default:
+		return 0, fmt.Errorf("Objects of type %s can not be found by name", objectName)
+	}
+	if err := c.api.GetObjectRefByNameContext(c.ctx, objectName, name, object); err != nil {
+		if IsNotFoundError(err) {
+			return 0, NotFoundError(fmt.Sprintf("The %s %q was not found", objectName, name))
+		}
+		return 0, err
+	}
+	c.ids[key] = object.GetId()
+	return c.ids[key], nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test that NameCache looks up every name once.
+func TestNameCache(t *testing.T) {
+	var requests []string
+	api := NewApi("http://coscale.test", "token", "app", true, false)
+	api.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"token":"token"}`
+		if !strings.HasSuffix(req.URL.Path, "/login/") {
+			requests = append(requests, req.URL.Path+"?"+req.URL.RawQuery)
+			body = `[]`
+			if req.URL.Query().Get("selectByName") == "web-1" {
+				body = `[{"id":12,"name":"web-1"}]`
+			}
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+
+	cache := api.NewNameCache(context.Background())
+	for i := 0; i < 2; i++ {
+		id, err := cache.ID("server", "web-1")
+		if err != nil || id != 12 {
+			t.Fatalf("expected: 12, found: %d %v", id, err)
+		}
+	}
+	if _, err := cache.ID("server", "web-2"); !IsNotFoundError(err) || err.Error() != `The server "web-2" was not found` {
+		t.Fatalf("expected: not found error, found: %v", err)
+	}
+	expected := []string{"/api/v1/app/app/servers/?selectByName=web-1", "/api/v1/app/app/servers/?selectByName=web-2"}
+	if !reflect.DeepEqual(expected, requests) {
+		t.Fatalf("expected: %v, found: %v", expected, requests)
+	}
+
+	if err := api.GetObjectRefByName("server", "web-2", &Server{}); !IsNotFoundError(err) {
+		t.Fatalf("expected: NotFoundError, found: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy defines how failed API calls are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts for a call, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles for every next retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts. A Retry-After longer than MaxDelay stops the retries.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the RetryPolicy used by a new Api.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// jitter is the random source for the backoff, seeded per process so that
+// hosts started by the same cron schedule do not retry in lockstep.
+var jitter = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// backoff returns the delay before the given retry (1 for the first retry): exponential with equal jitter.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	jitter.Lock()
+	defer jitter.Unlock()
+	return delay/2 + time.Duration(jitter.Int63n(int64(delay/2)+1))
+}
+
+// shouldRetry checks if the call can be retried after the given attempt failed with err,
+// if true, this also returns the delay to wait before the next attempt.
+// safe indicates that the request may be repeated even if the API might have processed it.
+func (p RetryPolicy) shouldRetry(attempt int, method string, safe bool, err error) (bool, time.Duration) {
+	if err == nil || attempt >= p.MaxAttempts {
+		return false, 0
+	}
+	safe = safe || isIdempotent(method)
+
+	if le, ok := err.(loginError); ok {
+		// The login itself is always safe to repeat.
+		err = le.err
+		safe = true
+	}
+
+	if se, ok := err.(BadStatusError); ok {
+		switch {
+		case se.StatusCode == http.StatusTooManyRequests:
+			// The request was rejected before being processed.
+		case se.StatusCode >= 500 && safe:
+		default:
+			return false, 0
+		}
+		if se.RetryAfter > 0 {
+			if se.RetryAfter > p.MaxDelay {
+				return false, 0
+			}
+			return true, se.RetryAfter
+		}
+		return true, p.backoff(attempt)
+	}
+
+	if isDialError(err) || (safe && isNetworkError(err)) {
+		return true, p.backoff(attempt)
+	}
+	return false, 0
+}
+
+// IsTemporaryError checks if a call failed because the API could not be reached or was unavailable,
+// e.g. a network error, a server error or throttling, so the same call can succeed later.
+func IsTemporaryError(err error) bool {
+	if le, ok := err.(loginError); ok {
+		err = le.err
+	}
+	if se, ok := err.(BadStatusError); ok {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	return err == context.DeadlineExceeded || isNetworkError(err)
+}
+
+// isIdempotent checks if repeating a request with this method has the same effect as sending it once.
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// netError returns the net.Error wrapped in err, if any.
+func netError(err error) (net.Error, bool) {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	ne, ok := err.(net.Error)
+	return ne, ok
+}
+
+// isDialError checks if err occurred while connecting, so the request never reached the API.
+func isDialError(err error) bool {
+	ne, ok := netError(err)
+	if !ok {
+		return false
+	}
+	oe, ok := ne.(*net.OpError)
+	return ok && oe.Op == "dial"
+}
+
+// isNetworkError checks if err is a transport error such as a timeout or a connection reset.
+func isNetworkError(err error) bool {
+	if ue, ok := err.(*url.Error); ok && (ue.Err == io.EOF || ue.Err == io.ErrUnexpectedEOF) {
+		// The connection was closed before the response was received.
+		return true
+	}
+	_, ok := netError(err)
+	return ok
+}
+
+// parseRetryAfter parses the Retry-After header which contains either seconds or a http date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
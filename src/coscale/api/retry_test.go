@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestApi creates an Api for a test server which fails the first failures calls with status.
+func newTestApi(failures int, status int, retryAfter string) (*Api, *int, func()) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login/") {
+			fmt.Fprint(w, `{"token":"token"}`)
+			return
+		}
+		calls++
+		if calls <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"msg":"failed"}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	api := NewApi(server.URL, "token", "app", true, false)
+	api.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	return api, &calls, server.Close
+}
+
+// Test the retries of failed calls.
+func TestRetry(t *testing.T) {
+	// Idempotent call is retried on server errors.
+	api, calls, stop := newTestApi(2, 503, "")
+	if _, err := api.GetObjects("server"); err != nil {
+		t.Fatalf("Error occured while retrying: %s", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected: %d calls, found: %d", 3, *calls)
+	}
+	stop()
+
+	// Give up after MaxAttempts.
+	api, calls, stop = newTestApi(5, 500, "")
+	_, err := api.GetObjects("server")
+	if bse, ok := err.(BadStatusError); !ok || bse.StatusCode != 500 {
+		t.Fatalf("expected BadStatusError, found: %v", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected: %d calls, found: %d", 3, *calls)
+	}
+	stop()
+
+	// A POST is not retried on server errors.
+	api, calls, stop = newTestApi(1, 500, "")
+	if _, err := api.CreateServer("server", "", ""); err == nil {
+		t.Fatalf("Expected error.")
+	}
+	if *calls != 1 {
+		t.Fatalf("expected: %d calls, found: %d", 1, *calls)
+	}
+	stop()
+
+	// But it is retried when it was throttled.
+	api, calls, stop = newTestApi(1, 429, "0")
+	if _, err := api.CreateServer("server", "", ""); err != nil {
+		t.Fatalf("Error occured while retrying: %s", err)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected: %d calls, found: %d", 2, *calls)
+	}
+	stop()
+
+	// A Retry-After beyond MaxDelay stops the retries.
+	api, calls, stop = newTestApi(1, 503, "120")
+	if _, err := api.GetObjects("server"); err == nil {
+		t.Fatalf("Expected error.")
+	}
+	if *calls != 1 {
+		t.Fatalf("expected: %d calls, found: %d", 1, *calls)
+	}
+	stop()
+}
+
+// Test parseRetryAfter.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-5":                            0,
+		"Sun, 01 Jan 2017 12:00:30 GMT": 30 * time.Second,
+		"Sun, 01 Jan 2017 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for header, expected := range tests {
+		if obtained := parseRetryAfter(header, now); obtained != expected {
+			t.Fatalf("%q expected: %s, found: %s", header, expected, obtained)
+		}
+	}
+}
+
+// Test the backoff stays between half of the exponential delay and MaxDelay.
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for retry, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		delay := policy.backoff(retry + 1)
+		if delay < max/2 || delay > max {
+			t.Fatalf("retry %d expected between %s and %s, found: %s", retry+1, max/2, max, delay)
+		}
+	}
+}
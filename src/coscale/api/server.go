@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -51,6 +52,11 @@ func (e ServerGroup) GetId() int64 {
 
 // CreateServer creates a new Server using the API.
 func (api *Api) CreateServer(name string, description string, serverType string) (string, error) {
+	return api.CreateServerContext(context.Background(), name, description, serverType)
+}
+
+// CreateServerContext is like CreateServer, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateServerContext(ctx context.Context, name string, description string, serverType string) (string, error) {
 	data := map[string][]string{
 		"name":        {name},
 		"description": {description},
@@ -58,9 +64,9 @@ func (api *Api) CreateServer(name string, description string, serverType string)
 		"source":      {GetSource()},
 	}
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/servers/", api.AppID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/servers/", api.AppID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetObject("server", id)
+			return api.GetObjectContext(ctx, "server", id)
 		}
 		return "", err
 	}
@@ -69,6 +75,11 @@ func (api *Api) CreateServer(name string, description string, serverType string)
 
 // UpdateServer updates all fields on an existing Server using the API.
 func (api *Api) UpdateServer(server *Server) (string, error) {
+	return api.UpdateServerContext(context.Background(), server)
+}
+
+// UpdateServerContext is like UpdateServer, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateServerContext(ctx context.Context, server *Server) (string, error) {
 	data := map[string][]string{
 		"name":        {server.Name},
 		"description": {server.Description},
@@ -78,14 +89,19 @@ func (api *Api) UpdateServer(server *Server) (string, error) {
 		"version":     {strconv.FormatInt(server.Version, 10)},
 	}
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/servers/%d/", api.AppID, server.ID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/servers/%d/", api.AppID, server.ID), data, true, &result); err != nil {
 		return "", err
 	}
-	return api.GetObject("server", server.ID)
+	return api.GetObjectContext(ctx, "server", server.ID)
 }
 
 // GetServerGroupByPath returns a server group by the hierarchy.
 func (api *Api) GetServerGroupByPath(path string) (string, error) {
+	return api.GetServerGroupByPathContext(context.Background(), path)
+}
+
+// GetServerGroupByPathContext is like GetServerGroupByPath, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) GetServerGroupByPathContext(ctx context.Context, path string) (string, error) {
 
 	var serverGroup *ServerGroup
 
@@ -103,7 +119,7 @@ func (api *Api) GetServerGroupByPath(path string) (string, error) {
 		}
 
 		api.SetQueryString(query)
-		result, err := api.GetObjectByName("servergroup", groupName)
+		result, err := api.GetObjectByNameContext(ctx, "servergroup", groupName)
 		if err != nil {
 			return "", err
 		}
@@ -128,6 +144,11 @@ func (api *Api) GetServerGroupByPath(path string) (string, error) {
 
 // CreateServerGroup creates a new ServerGroup using the API.
 func (api *Api) CreateServerGroup(name, description, Type, state string, parentID int64) (string, error) {
+	return api.CreateServerGroupContext(context.Background(), name, description, Type, state, parentID)
+}
+
+// CreateServerGroupContext is like CreateServerGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) CreateServerGroupContext(ctx context.Context, name, description, Type, state string, parentID int64) (string, error) {
 	data := map[string][]string{
 		"name":        {name},
 		"description": {description},
@@ -142,9 +163,9 @@ func (api *Api) CreateServerGroup(name, description, Type, state string, parentI
 	}
 
 	var result string
-	if err := api.makeCall("POST", fmt.Sprintf("/api/v1/app/%s/servergroups/", api.AppID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "POST", fmt.Sprintf("/api/v1/app/%s/servergroups/", api.AppID), data, true, &result); err != nil {
 		if duplicate, id := IsDuplicate(err); duplicate {
-			return api.GetObject("servergroup", id)
+			return api.GetObjectContext(ctx, "servergroup", id)
 		}
 		return "", err
 	}
@@ -153,6 +174,11 @@ func (api *Api) CreateServerGroup(name, description, Type, state string, parentI
 
 // UpdateServerGroup updates all fields of an existing ServerGroup using the API.
 func (api *Api) UpdateServerGroup(serverGroup *ServerGroup) (string, error) {
+	return api.UpdateServerGroupContext(context.Background(), serverGroup)
+}
+
+// UpdateServerGroupContext is like UpdateServerGroup, ctx is used for cancellation and deadlines of the API calls.
+func (api *Api) UpdateServerGroupContext(ctx context.Context, serverGroup *ServerGroup) (string, error) {
 	data := map[string][]string{
 		"name":        {serverGroup.Name},
 		"description": {serverGroup.Description},
@@ -163,8 +189,8 @@ func (api *Api) UpdateServerGroup(serverGroup *ServerGroup) (string, error) {
 		"version":     {strconv.FormatInt(serverGroup.Version, 10)},
 	}
 	var result string
-	if err := api.makeCall("PUT", fmt.Sprintf("/api/v1/app/%s/servergroups/%d/", api.AppID, serverGroup.ID), data, true, &result); err != nil {
+	if err := api.makeCall(ctx, "PUT", fmt.Sprintf("/api/v1/app/%s/servergroups/%d/", api.AppID, serverGroup.ID), data, true, &result); err != nil {
 		return "", err
 	}
-	return api.GetObject("servergroup", serverGroup.ID)
+	return api.GetObjectContext(ctx, "servergroup", serverGroup.ID)
 }
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// spoolExtension is the extension of the files in a Spool, rejected files get rejectedExtension.
+const (
+	spoolExtension    = ".cdata"
+	rejectedExtension = ".rejected"
+)
+
+// spoolSequence makes the names of the files unique when batches are spooled at the same time.
+var spoolSequence uint32
+
+// Spool stores batches of data that could not be inserted in a directory, so they can be sent
+// later. Every batch is a file with the serialized data of the insert call, the name of the file
+// starts with the time it was spooled.
+type Spool struct {
+	// Dir is the directory with the spooled batches.
+	Dir string
+	// MaxSize is the maximum size in bytes of the spooled batches, the oldest batches are dropped
+	// when it is exceeded. Zero means no limit.
+	MaxSize int64
+	// MaxAge is the time after which a spooled batch is dropped. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// SpoolResult is the result of replaying a Spool.
+type SpoolResult struct {
+	// Sent is the number of batches that were inserted.
+	Sent int `json:"sent"`
+	// Rejected is the number of batches the API refused, they are kept with the extension .rejected.
+	Rejected int `json:"rejected"`
+	// Dropped is the number of batches that exceeded MaxAge or MaxSize.
+	Dropped int `json:"dropped"`
+	// Remaining is the number of batches that are still spooled.
+	Remaining int `json:"remaining"`
+}
+
+// SpooledError is returned when data could not be inserted and was added to a Spool.
+type SpooledError struct {
+	Err  error
+	File string
+}
+
+func (se SpooledError) Error() string {
+	return fmt.Sprintf("%s, the data was spooled in %s", se.Err.Error(), se.File)
+}
+
+// NewSpool creates a Spool for the data of an application in dir.
+func NewSpool(dir, appID string, maxSize int64, maxAge time.Duration) *Spool {
+	return &Spool{Dir: filepath.Join(dir, appID), MaxSize: maxSize, MaxAge: maxAge}
+}
+
+// Add writes a batch of serialized data to the spool and returns the name of the file. The
+// oldest batches are dropped if the spool exceeds MaxSize.
+func (s *Spool) Add(serializedData string) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return "", err
+	}
+	// Write a temporary file first so a partial batch is never replayed.
+	tmp, err := ioutil.TempFile(s.Dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.WriteString(serializedData)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	seq := atomic.AddUint32(&spoolSequence, 1) % 1000000
+	file := filepath.Join(s.Dir, fmt.Sprintf("%019d-%d-%06d%s", time.Now().UnixNano(), os.Getpid(), seq, spoolExtension))
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if _, err := s.prune(time.Now()); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// Files returns the spooled batches, oldest first, after dropping the batches that exceed MaxAge
+// or MaxSize. The number of dropped batches is also returned.
+func (s *Spool) Files() ([]string, int, error) {
+	dropped, err := s.prune(time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+	files, err := s.list()
+	return files, dropped, err
+}
+
+// list returns the spooled batches, oldest first.
+func (s *Spool) list() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), spoolExtension) {
+			files = append(files, filepath.Join(s.Dir, entry.Name()))
+		}
+	}
+	// The names start with the zero padded time, so they sort by age.
+	sort.Strings(files)
+	return files, nil
+}
+
+// prune drops the batches older than MaxAge and the oldest batches beyond MaxSize.
+func (s *Spool) prune(now time.Time) (int, error) {
+	files, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+	dropped := 0
+	var kept []string
+	var sizes []int64
+	var total int64
+	for _, file := range files {
+		if s.MaxAge > 0 && now.Sub(spoolTime(file)) > s.MaxAge {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return dropped, err
+			}
+			dropped++
+			continue
+		}
+		info, err := os.Stat(file)
+		if os.IsNotExist(err) {
+			// Replayed by another process.
+			continue
+		} else if err != nil {
+			return dropped, err
+		}
+		kept = append(kept, file)
+		sizes = append(sizes, info.Size())
+		total += info.Size()
+	}
+	for i := 0; s.MaxSize > 0 && total > s.MaxSize && i < len(kept); i++ {
+		if err := os.Remove(kept[i]); err != nil && !os.IsNotExist(err) {
+			return dropped, err
+		}
+		total -= sizes[i]
+		dropped++
+	}
+	return dropped, nil
+}
+
+// spoolTime returns the time a batch was spooled from the name of its file.
+func spoolTime(file string) time.Time {
+	name := filepath.Base(file)
+	if i := strings.Index(name, "-"); i > 0 {
+		name = name[:i]
+	}
+	nanos, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// InsertDataSpooled is like InsertDataContext, but the data is added to spool when the API could
+// not be reached, a SpooledError is returned in that case.
+func (api *Api) InsertDataSpooled(ctx context.Context, data map[string][]*ApiData, spool *Spool) (string, error) {
+	// The relative times would be the time of the replay otherwise.
+	serializedData, _, err := serializeAPIData(absoluteData(data, time.Now()))
+	if err != nil {
+		return "", err
+	}
+	result, err := api.insertSerializedData(ctx, serializedData)
+	if err != nil && IsTemporaryError(err) {
+		file, spoolErr := spool.Add(serializedData)
+		if spoolErr != nil {
+			return "", fmt.Errorf("%s, the data could not be spooled: %s", err.Error(), spoolErr.Error())
+		}
+		return "", SpooledError{err, file}
+	}
+	return result, err
+}
+
+// absoluteData returns a copy of data with the relative times, 0 for now or negative seconds ago,
+// converted to unix timestamps.
+func absoluteData(data map[string][]*ApiData, now time.Time) map[string][]*ApiData {
+	result := make(map[string][]*ApiData, len(data))
+	for subjectID, subjectData := range data {
+		for _, apiData := range subjectData {
+			absolute := *apiData
+			absolute.Data = make([]DataPoint, len(apiData.Data))
+			for i, point := range apiData.Data {
+				absolute.Data[i] = DataPoint{SecondsAgo: UnixTime(point.SecondsAgo, now), Data: point.Data}
+			}
+			result[subjectID] = append(result[subjectID], &absolute)
+		}
+	}
+	return result
+}
+
+// ReplaySpool inserts the spooled batches oldest first and removes them once they are inserted.
+// It stops at the first batch that fails because the API could not be reached, batches that are
+// refused by the API are kept with the extension .rejected so they don't block the spool.
+func (api *Api) ReplaySpool(ctx context.Context, spool *Spool) (*SpoolResult, error) {
+	files, dropped, err := spool.Files()
+	if err != nil {
+		return nil, err
+	}
+	result := &SpoolResult{Dropped: dropped, Remaining: len(files)}
+	for _, file := range files {
+		serializedData, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			// Replayed by another process.
+			result.Remaining--
+			continue
+		} else if err != nil {
+			return result, err
+		}
+		if _, err := api.insertSerializedData(ctx, string(serializedData)); err != nil {
+			if IsTemporaryError(err) {
+				return result, err
+			}
+			if err := os.Rename(file, file+rejectedExtension); err != nil {
+				return result, err
+			}
+			result.Rejected++
+		} else {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return result, err
+			}
+			result.Sent++
+		}
+		result.Remaining--
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that data is spooled when the API can't be reached and is replayed later.
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := ParseDataPoint("M1:S1:-60:1.2", false)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+
+	// The server is stopped, the login fails with a network error.
+	api, _, stop := newTestApi(0, 0, "")
+	stop()
+	spool := NewSpool(dir, "app", 0, 0)
+	_, err = api.InsertDataSpooled(context.Background(), data[0], spool)
+	if _, ok := err.(SpooledError); !ok {
+		t.Fatalf("expected SpooledError, found: %v", err)
+	}
+	api.InsertDataSpooled(context.Background(), data[0], spool)
+	files, _, err := spool.Files()
+	if err != nil || len(files) != 2 || filepath.Dir(files[0]) != filepath.Join(dir, "app") {
+		t.Fatalf("expected: 2 spooled files, found: %v %v", files, err)
+	}
+
+	// Server errors are spooled, the replay stops at the first failure.
+	api, calls, stop := newTestApi(5, 503, "")
+	result, err := api.ReplaySpool(context.Background(), spool)
+	if err == nil || result.Sent != 0 || result.Remaining != 2 {
+		t.Fatalf("expected: an error and 2 remaining batches, found: %+v %v", result, err)
+	}
+	stop()
+
+	// Refused batches are kept aside and don't block the next batches.
+	api, calls, stop = newTestApi(1, 400, "")
+	defer stop()
+	result, err = api.ReplaySpool(context.Background(), spool)
+	if err != nil || *result != (SpoolResult{Sent: 1, Rejected: 1}) || *calls != 2 {
+		t.Fatalf("expected: 1 sent and 1 rejected batch, found: %+v %v", result, err)
+	}
+	entries, _ := ioutil.ReadDir(filepath.Join(dir, "app"))
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".cdata.rejected") {
+		t.Fatalf("expected: a rejected batch, found: %v", entries)
+	}
+
+	// A successful insert is not spooled.
+	if _, err := api.InsertDataSpooled(context.Background(), data[0], spool); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if files, _, _ := spool.Files(); len(files) != 0 {
+		t.Fatalf("expected: no spooled files, found: %v", files)
+	}
+}
+
+// Test that relative times are spooled as unix timestamps, so the replayed data keeps its time.
+func TestSpoolRelativeTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := ParseDataPoint("M1:S1:-60:1.2", false)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	api, _, stop := newTestApi(0, 0, "")
+	stop()
+	spool := NewSpool(dir, "app", 0, 0)
+	start := int(time.Now().Unix())
+	if _, err := api.InsertDataSpooled(context.Background(), data[0], spool); err == nil {
+		t.Fatalf("expected a SpooledError")
+	}
+	end := int(time.Now().Unix())
+	for _, subjectData := range data[0] {
+		if subjectData[0].Data[0].SecondsAgo != -60 {
+			t.Fatalf("expected: the data is not changed, found: %v", subjectData[0].Data)
+		}
+	}
+
+	var inserted []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login/") {
+			fmt.Fprint(w, `{"token":"token"}`)
+			return
+		}
+		compressed, _ := base64.StdEncoding.DecodeString(r.FormValue("cdata"))
+		if reader, err := gzip.NewReader(bytes.NewReader(compressed)); err == nil {
+			inserted, _ = ioutil.ReadAll(reader)
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	api = NewApi(server.URL, "token", "app", true, false)
+	if result, err := api.ReplaySpool(context.Background(), spool); err != nil || result.Sent != 1 {
+		t.Fatalf("expected: 1 sent batch, found: %+v %v", result, err)
+	}
+
+	parts := regexp.MustCompile(`"d":\[\[(-?[0-9]+),1.2\]\]`).FindSubmatch(inserted)
+	if parts == nil {
+		t.Fatalf("expected: a data point, found: %s", inserted)
+	}
+	if found, _ := strconv.Atoi(string(parts[1])); found < start-60 || found > end-60 {
+		t.Fatalf("expected: %d, found: %d", start-60, found)
+	}
+}
+
+// Test that the spool drops the oldest batches beyond MaxSize and MaxAge.
+func TestSpoolLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	spool := NewSpool(dir, "app", 25, 0)
+	first, _ := spool.Add("0123456789")
+	second, _ := spool.Add("0123456789")
+	third, err := spool.Add("0123456789")
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	files, _, _ := spool.Files()
+	if len(files) != 2 || files[0] != second || files[1] != third {
+		t.Fatalf("expected: %s was dropped, found: %v", first, files)
+	}
+
+	spool.MaxAge = time.Hour
+	old := filepath.Join(spool.Dir, "0000000000000000001-1-000001.cdata")
+	if err := ioutil.WriteFile(old, []byte("x"), 0600); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	files, dropped, err := spool.Files()
+	if err != nil || dropped != 1 || len(files) != 2 {
+		t.Fatalf("expected: 1 dropped file, found: %v %d %v", files, dropped, err)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationPattern matches a duration relative to now, e.g. -2h, 7d or 1d12h.
+var durationPattern = regexp.MustCompile(`^([+-]?)((?:[0-9]+[smhdw])+)$`)
+
+// durationPartPattern matches the parts of a duration.
+var durationPartPattern = regexp.MustCompile(`([0-9]+)([smhdw])`)
+
+// durationUnits are the units of a duration in seconds.
+var durationUnits = map[string]int{"s": 1, "m": 60, "h": 3600, "d": 24 * 3600, "w": 7 * 24 * 3600}
+
+// ParseTime parses a time expression into the time format of the API: a unix timestamp, 0 for now
+// or a negative number of seconds ago. The following expressions are accepted:
+//
+//	a number: a unix timestamp, 0 for now or negative seconds ago,
+//	a duration with the units s, m, h, d or w: seconds ago, e.g. -2h, 7d or 1d12h, with a + the
+//	duration is in the future,
+//	now, today or yesterday, the days start at midnight in the local time zone,
+//	a time such as 2017-05-18T12:00:00Z or 2017-05-18 12:00 (local time).
+func ParseTime(expression string, now time.Time) (int, error) {
+	expression = strings.TrimSpace(expression)
+	if seconds, err := strconv.Atoi(expression); err == nil {
+		return seconds, nil
+	}
+
+	switch strings.ToLower(expression) {
+	case "now":
+		return 0, nil
+	case "today":
+		return int(midnight(now).Unix()), nil
+	case "yesterday":
+		return int(midnight(now).AddDate(0, 0, -1).Unix()), nil
+	}
+
+	if parts := durationPattern.FindStringSubmatch(expression); parts != nil {
+		seconds := 0
+		for _, part := range durationPartPattern.FindAllStringSubmatch(parts[2], -1) {
+			value, err := strconv.Atoi(part[1])
+			if err != nil {
+				return 0, fmt.Errorf("Invalid time %q", expression)
+			}
+			seconds += value * durationUnits[part[2]]
+		}
+		if parts[1] == "+" {
+			// The API has no format for a time in the future.
+			return int(now.Unix()) + seconds, nil
+		}
+		return -seconds, nil
+	}
+
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.ParseInLocation(layout, expression, now.Location()); err == nil {
+			return int(t.Unix()), nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid time %q, use a unix timestamp, a duration such as -2h or 7d, now, today, yesterday or a time such as 2017-05-18T12:00:00Z", expression)
+}
+
+// UnixTime converts a time in the format of the API into a unix timestamp, 0 and negative times
+// are relative to now.
+func UnixTime(t int, now time.Time) int {
+	if t <= 0 {
+		return int(now.Unix()) + t
+	}
+	return t
+}
+
+// midnight returns the start of the day of t.
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// Test the time expressions of ParseTime.
+func TestParseTime(t *testing.T) {
+	location := time.FixedZone("CEST", 2*3600)
+	now := time.Date(2017, 5, 18, 12, 30, 0, 0, location)
+	today := int(time.Date(2017, 5, 18, 0, 0, 0, 0, location).Unix())
+
+	tests := []struct {
+		expression string
+		expected   int
+	}{
+		{"1495108650", 1495108650},
+		{"-60", -60},
+		{"0", 0},
+		{"now", 0},
+		{"-2h", -7200},
+		{"7d", -7 * 24 * 3600},
+		{"1d12h", -36 * 3600},
+		{"2w", -14 * 24 * 3600},
+		{"+30m", int(now.Unix()) + 1800},
+		{"today", today},
+		{"Yesterday", today - 24*3600},
+		{"2017-05-18T10:00:00Z", 1495101600},
+		{"2017-05-18 12:00", 1495101600},
+		{"2017-05-18", today},
+	}
+	for _, test := range tests {
+		found, err := ParseTime(test.expression, now)
+		if err != nil {
+			t.Fatalf("Error occured for %s: %s", test.expression, err)
+		}
+		if found != test.expected {
+			t.Fatalf("expected: %d for %s, found: %d", test.expected, test.expression, found)
+		}
+	}
+
+	for _, invalid := range []string{"", "2 days", "-2x", "h", "2017-13-01"} {
+		if _, err := ParseTime(invalid, now); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+
+	if found := UnixTime(-60, now); found != int(now.Unix())-60 {
+		t.Fatalf("expected: %d, found: %d", now.Unix()-60, found)
+	}
+	if found := UnixTime(1495101600, now); found != 1495101600 {
+		t.Fatalf("expected: 1495101600, found: %d", found)
+	}
+}
@@ -18,6 +18,12 @@ func main() {
 		command.DataObject,
 		command.AlertObject,
 		command.ConfigObject,
+		command.ApplyObject,
+		command.PlanObject,
+		command.ExportObject,
+		command.CloneObject,
+		command.RelayObject,
+		command.ScrapeObject,
 	}
 	var usage = os.Args[0] + ` <object> <action> [--<field>='<data>']`
 	var app = command.NewCommand(os.Args[0], usage, subCommands)
@@ -0,0 +1,916 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"coscale/api"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// ApplyObject defines the apply command on the CLI.
+var ApplyObject = &Command{
+	Name:      "apply",
+	UsageLine: "apply (-f)",
+	Long: `
+Create or update the servers, server groups, dimensions, metrics, metric groups, events,
+alert types and triggers described in a YAML or json file. Objects are matched by name:
+objects that do not exist are created, existing objects are updated if a field in the file
+has another value. Fields that are not in the file are not changed, objects that are not in the
+file are not deleted. The changes are printed.
+
+The flags for apply are:
+
+Mandatory:
+	-f, --file
+		The YAML or json file, "-" reads the file from stdin. For a directory, e.g. written by
+		export, the .yaml, .yml and .json files in the directory are used.
+
+Use plan to see the changes before they are made. The file has the following format, only the names and the metric and config of
+the triggers are required:
+
+	servers:
+	  - name: web1
+	    description: Web server
+	    type: nginx
+	    state: ENABLED
+	servergroups:
+	  - name: frontend
+	    parent: production        # The name of the parent group.
+	dimensions:
+	  - name: queue
+	metrics:
+	  - name: Queued messages
+	    dataType: DOUBLE          # Required to create a metric.
+	    subject: SERVER           # Required to create a metric.
+	    period: 60
+	    unit: messages
+	    dimensions: [queue]       # Dimensions are only added.
+	metricgroups:
+	  - name: RabbitMQ
+	    subject: SERVER           # Required to create a metric group.
+	    metrics: [Queued messages]
+	events:
+	  - name: Deployments
+	    description: Application deployments
+	alerttypes:
+	  - name: Operations
+	    handle: EMAIL:ops@example.com SLACK:https://hooks.slack.com/...
+	    backupHandle: EMAILUSER:1
+	    backupSeconds: 600
+	    triggers:
+	      - name: Queue too long
+	        metric: Queued messages
+	        config: avg(300) > 1000
+	        servergroup: frontend  # Or server, without both the trigger is for the application.
+	        autoresolveSeconds: 3600
+	        dimensionSpecs:
+	          - dimension: queue
+	            values: "*"       # Dimension value ids with an optional aggregator, e.g. "AVG(*)".
+
+If an API call fails the changes that were made are kept, apply can be run again.
+`,
+	Run: func(cmd *Command, args []string) {
+		runSpec(cmd, args, false)
+	},
+}
+
+// PlanObject defines the plan command on the CLI.
+var PlanObject = &Command{
+	Name:      "plan",
+	UsageLine: "plan (-f)",
+	Long: `
+Show the changes apply would make for a YAML or json file without making them: the objects
+that would be created or updated and the old and new values of the changed fields.
+The format of the file is described in "coscale-cli apply help".
+
+The live objects that are not in the file are shown as deleted, apply does not delete them.
+Only the kinds in the file are compared: without "servers:" in the file the servers are not
+shown. The triggers are compared for the alert types with triggers in the file.
+
+The exit code is 0 if the live objects match the file and 4 if there are changes pending.
+
+The flags for plan are:
+
+Mandatory:
+	-f, --file
+		The YAML or json file, "-" reads the file from stdin.
+
+The changes are printed as a diff, --output, --query and --format print the json changes instead.
+`,
+	Run: func(cmd *Command, args []string) {
+		runSpec(cmd, args, true)
+	},
+}
+
+// runSpec runs apply, or plan in dry run mode, for the file in the args.
+func runSpec(cmd *Command, args []string, dryRun bool) {
+	var file string
+	cmd.Flag.Usage = func() { cmd.PrintUsage() }
+	cmd.Flag.StringVar(&file, "f", DEFAULT_STRING_FLAG_VALUE, "The YAML or json file.")
+	cmd.Flag.StringVar(&file, "file", DEFAULT_STRING_FLAG_VALUE, "The YAML or json file.")
+	cmd.ParseArgs(args)
+
+	if file == DEFAULT_STRING_FLAG_VALUE {
+		cmd.PrintUsage()
+		os.Exit(EXIT_FLAG_ERROR)
+	}
+	spec, err := readAppSpec(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(EXIT_FLAG_ERROR)
+	}
+
+	ctx := context.Background()
+	state, err := loadAppState(ctx, cmd.Capi)
+	if err != nil {
+		cmd.PrintResult("", err)
+	}
+	r := &reconciler{ctx: ctx, capi: cmd.Capi, state: state, dryRun: dryRun, reportMissing: dryRun}
+	if err := r.apply(spec); err != nil {
+		// Show the changes that were made before the error.
+		fmt.Fprintln(os.Stdout, formatChanges(r.changes))
+		cmd.PrintResult("", err)
+	}
+	if !dryRun {
+		cmd.PrintResult(formatChanges(r.changes), nil)
+	}
+
+	exitCode := EXIT_SUCCESS
+	if len(r.changes) > 0 {
+		exitCode = EXIT_CHANGES_PENDING
+	}
+	if cmd.isFlagSet("output", "query", "format") {
+		cmd.printResultAndExit(formatChanges(r.changes), nil, exitCode)
+	}
+	fmt.Fprintln(os.Stdout, formatPlan(r.changes))
+	os.Exit(exitCode)
+}
+
+// isFlagSet returns true if one of the flags is on the command line.
+func (c *Command) isFlagSet(names ...string) bool {
+	found := false
+	c.Flag.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			found = found || f.Name == name
+		}
+	})
+	return found
+}
+
+// formatPlan formats the changes as a diff: "+" for objects that are created, "~" for objects that
+// are updated, "-" for objects that are not in the file and "!" for objects that are skipped, followed
+// by the old and new field values or the reason.
+func formatPlan(changes []*specChange) string {
+	if len(changes) == 0 {
+		return "No changes, the live objects are up to date."
+	}
+	symbols := map[string]string{"create": "+", "update": "~", "delete": "-", "skip": "!"}
+	counts := make(map[string]int)
+	var buffer bytes.Buffer
+	for _, change := range changes {
+		counts[change.Action]++
+		fmt.Fprintf(&buffer, "%s %s %q\n", symbols[change.Action], change.Kind, change.Name)
+		if change.Reason != "" {
+			fmt.Fprintf(&buffer, "    %s\n", change.Reason)
+		}
+		for _, field := range change.Fields {
+			newValue, _ := json.Marshal(field.New)
+			if change.Action == "create" {
+				fmt.Fprintf(&buffer, "    %s: %s\n", field.Field, newValue)
+			} else {
+				oldValue, _ := json.Marshal(field.Old)
+				fmt.Fprintf(&buffer, "    %s: %s -> %s\n", field.Field, oldValue, newValue)
+			}
+		}
+	}
+	fmt.Fprintf(&buffer, "\nPlan: %d to create, %d to update, %d to delete.", counts["create"], counts["update"], counts["delete"])
+	if counts["skip"] > 0 {
+		fmt.Fprintf(&buffer, " %d skipped.", counts["skip"])
+	}
+	return buffer.String()
+}
+
+// formatChanges returns the changes as json.
+func formatChanges(changes []*specChange) string {
+	if changes == nil {
+		changes = []*specChange{}
+	}
+	result, _ := json.MarshalIndent(changes, "", " ")
+	return string(result)
+}
+
+// reconciler makes the live objects of an application match a spec. In dry run mode the
+// changes are only recorded, no API calls are made.
+type reconciler struct {
+	ctx    context.Context
+	capi   *api.Api
+	state  *appState
+	dryRun bool
+	// reportMissing records the live objects that are not in the spec as deletes, they are
+	// never deleted.
+	reportMissing bool
+	changes       []*specChange
+}
+
+// record adds a change, the fields of a new object are compared with an empty object.
+func (r *reconciler) record(action, kind, name string, fields []fieldChange) {
+	r.changes = append(r.changes, &specChange{Action: action, Kind: kind, Name: name, Fields: fields})
+}
+
+// apply creates and updates the objects of the spec, objects are handled in the order of
+// their dependencies. With reportMissing the objects that are not in the spec are recorded last.
+func (r *reconciler) apply(spec *appSpec) error {
+	steps := []func(*appSpec) error{
+		r.applyServers,
+		r.applyServerGroups,
+		r.applyDimensions,
+		r.applyMetrics,
+		r.applyMetricGroups,
+		r.applyEvents,
+		r.applyAlertTypes,
+	}
+	if r.reportMissing {
+		steps = append(steps, r.recordMissing)
+	}
+	for _, step := range steps {
+		if err := step(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode parses the result of a create or update call into target.
+func decode(result string, err error, target interface{}) error {
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(result), target)
+}
+
+func (r *reconciler) applyServers(spec *appSpec) error {
+	for _, desired := range spec.Servers {
+		live, ok := r.state.servers[desired.Name]
+		if !ok {
+			r.record("create", "server", desired.Name, diffFields(desired, &serverSpec{}))
+			if r.dryRun {
+				continue
+			}
+			server := &api.Server{}
+			result, err := r.capi.CreateServerContext(r.ctx, desired.Name, stringValue(desired.Description, ""), stringValue(desired.Type, ""))
+			if err := decode(result, err, server); err != nil {
+				return fmt.Errorf("server %s: %s", desired.Name, err)
+			}
+			r.state.servers[desired.Name] = server
+			if desired.State == nil || *desired.State == server.State {
+				continue
+			}
+			live = server
+		} else if fields := diffFields(desired, r.state.serverToSpec(live)); len(fields) > 0 {
+			r.record("update", "server", desired.Name, fields)
+		} else {
+			continue
+		}
+		if r.dryRun {
+			continue
+		}
+
+		server := *live
+		server.Description = stringValue(desired.Description, server.Description)
+		server.Type = stringValue(desired.Type, server.Type)
+		server.State = stringValue(desired.State, server.State)
+		result, err := r.capi.UpdateServerContext(r.ctx, &server)
+		if err := decode(result, err, live); err != nil {
+			return fmt.Errorf("server %s: %s", desired.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyServerGroups creates the parents before the server groups.
+func (r *reconciler) applyServerGroups(spec *appSpec) error {
+	done := make(map[string]bool)
+	remaining := append([]*serverGroupSpec{}, spec.ServerGroups...)
+	for len(remaining) > 0 {
+		var next []*serverGroupSpec
+		for _, desired := range remaining {
+			parent := stringValue(desired.Parent, "")
+			if parent != "" && !done[parent] && isServerGroupInSpec(spec, parent) {
+				next = append(next, desired)
+				continue
+			}
+			if err := r.applyServerGroup(desired); err != nil {
+				return err
+			}
+			done[desired.Name] = true
+		}
+		if len(next) == len(remaining) {
+			return fmt.Errorf("servergroup %s: the parents of the servergroups form a cycle", next[0].Name)
+		}
+		remaining = next
+	}
+	return nil
+}
+
+func isServerGroupInSpec(spec *appSpec, name string) bool {
+	for _, serverGroup := range spec.ServerGroups {
+		if serverGroup.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// serverGroupID returns the id of a server group by name, -1 for an empty name.
+func (r *reconciler) serverGroupID(name string) (int64, error) {
+	if name == "" {
+		return -1, nil
+	}
+	serverGroup, ok := r.state.serverGroups[name]
+	if !ok {
+		return 0, fmt.Errorf("servergroup %s not found", name)
+	}
+	return serverGroup.ID, nil
+}
+
+func (r *reconciler) applyServerGroup(desired *serverGroupSpec) error {
+	live, ok := r.state.serverGroups[desired.Name]
+	if !ok {
+		r.record("create", "servergroup", desired.Name, diffFields(desired, &serverGroupSpec{}))
+		if r.dryRun {
+			return nil
+		}
+		parentID, err := r.serverGroupID(stringValue(desired.Parent, ""))
+		if err != nil {
+			return fmt.Errorf("servergroup %s: %s", desired.Name, err)
+		}
+		serverGroup := &api.ServerGroup{}
+		result, err := r.capi.CreateServerGroupContext(r.ctx, desired.Name, stringValue(desired.Description, ""),
+			stringValue(desired.Type, ""), stringValue(desired.State, "ENABLED"), parentID)
+		if err := decode(result, err, serverGroup); err != nil {
+			return fmt.Errorf("servergroup %s: %s", desired.Name, err)
+		}
+		r.state.serverGroups[desired.Name] = serverGroup
+		return nil
+	}
+
+	fields := diffFields(desired, r.state.serverGroupToSpec(live))
+	if len(fields) == 0 {
+		return nil
+	}
+	r.record("update", "servergroup", desired.Name, fields)
+	if r.dryRun {
+		return nil
+	}
+	serverGroup := *live
+	serverGroup.Description = stringValue(desired.Description, serverGroup.Description)
+	serverGroup.Type = stringValue(desired.Type, serverGroup.Type)
+	serverGroup.State = stringValue(desired.State, serverGroup.State)
+	if desired.Parent != nil {
+		parentID, err := r.serverGroupID(*desired.Parent)
+		if err != nil {
+			return fmt.Errorf("servergroup %s: %s", desired.Name, err)
+		}
+		if parentID == -1 {
+			parentID = 0
+		}
+		serverGroup.ParentID = parentID
+	}
+	result, err := r.capi.UpdateServerGroupContext(r.ctx, &serverGroup)
+	if err := decode(result, err, live); err != nil {
+		return fmt.Errorf("servergroup %s: %s", desired.Name, err)
+	}
+	return nil
+}
+
+func (r *reconciler) applyDimensions(spec *appSpec) error {
+	for _, desired := range spec.Dimensions {
+		if _, err := r.dimension(desired.Name); err != nil {
+			return fmt.Errorf("dimension %s: %s", desired.Name, err)
+		}
+	}
+	return nil
+}
+
+// dimension returns the dimension with name, the dimension is created if it does not exist.
+func (r *reconciler) dimension(name string) (*api.Dimension, error) {
+	if dimension, ok := r.state.dimensions[name]; ok {
+		return dimension, nil
+	}
+	r.record("create", "dimension", name, nil)
+	dimension := &api.Dimension{Name: name}
+	if !r.dryRun {
+		result, err := r.capi.CreateDimensionContext(r.ctx, name)
+		if err := decode(result, err, dimension); err != nil {
+			return nil, err
+		}
+	}
+	r.state.dimensions[name] = dimension
+	return dimension, nil
+}
+
+func (r *reconciler) applyMetrics(spec *appSpec) error {
+	for _, desired := range spec.Metrics {
+		if err := r.applyMetric(desired); err != nil {
+			return fmt.Errorf("metric %s: %s", desired.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) applyMetric(desired *metricSpec) error {
+	// The dimensions are compared separately because they are only added.
+	withoutDimensions := *desired
+	withoutDimensions.Dimensions = nil
+
+	live, ok := r.state.metrics[desired.Name]
+	if !ok {
+		r.record("create", "metric", desired.Name, diffFields(desired, &metricSpec{}))
+		if desired.DataType == nil || desired.Subject == nil {
+			return fmt.Errorf("dataType and subject are required to create the metric")
+		}
+		if !r.dryRun {
+			metric := &api.Metric{}
+			result, err := r.capi.CreateMetricContext(r.ctx, desired.Name, stringValue(desired.Description, ""), *desired.DataType,
+				stringValue(desired.Unit, ""), *desired.Subject, intValue(desired.Period, 60))
+			if err := decode(result, err, metric); err != nil {
+				return err
+			}
+			r.state.metrics[desired.Name] = metric
+		}
+		return r.addMetricDimensions(desired.Name, desired.Dimensions)
+	}
+
+	missing := missingNames(desired.Dimensions, r.state.metricDimensions[desired.Name])
+	metricFields := diffFields(&withoutDimensions, r.state.metricToSpec(live))
+	fields := metricFields
+	if len(missing) > 0 {
+		current := r.state.metricDimensions[desired.Name]
+		fields = append(fields, fieldChange{"dimensions", current, sortedNames(append(append([]string{}, current...), missing...))})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	r.record("update", "metric", desired.Name, fields)
+	if len(metricFields) > 0 {
+		if !r.dryRun {
+			metric := *live
+			metric.Description = stringValue(desired.Description, metric.Description)
+			metric.DataType = stringValue(desired.DataType, metric.DataType)
+			metric.Period = intValue(desired.Period, metric.Period)
+			metric.Unit = stringValue(desired.Unit, metric.Unit)
+			metric.Subject = stringValue(desired.Subject, metric.Subject)
+			result, err := r.capi.UpdateMetricContext(r.ctx, &metric)
+			if err := decode(result, err, live); err != nil {
+				return err
+			}
+		}
+	}
+	return r.addMetricDimensions(desired.Name, missing)
+}
+
+// addMetricDimensions adds dimensions to a metric, missing dimensions are created.
+func (r *reconciler) addMetricDimensions(metricName string, dimensions []string) error {
+	for _, name := range dimensions {
+		dimension, err := r.dimension(name)
+		if err != nil {
+			return fmt.Errorf("dimension %s: %s", name, err)
+		}
+		if !r.dryRun {
+			if _, err := r.capi.AddMetricDimensionContext(r.ctx, r.state.metrics[metricName].ID, dimension.ID); err != nil {
+				return fmt.Errorf("dimension %s: %s", name, err)
+			}
+		}
+		r.state.metricDimensions[metricName] = sortedNames(append(r.state.metricDimensions[metricName], name))
+	}
+	return nil
+}
+
+// missingNames returns the names in desired that are not in current.
+func missingNames(desired, current []string) []string {
+	found := make(map[string]bool)
+	for _, name := range current {
+		found[name] = true
+	}
+	var missing []string
+	for _, name := range desired {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func (r *reconciler) applyMetricGroups(spec *appSpec) error {
+	for _, desired := range spec.MetricGroups {
+		if err := r.applyMetricGroup(desired); err != nil {
+			return fmt.Errorf("metricgroup %s: %s", desired.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) applyMetricGroup(desired *metricGroupSpec) error {
+	// The metrics are compared separately because they are added and removed with other calls.
+	withoutMetrics := *desired
+	withoutMetrics.Metrics = nil
+
+	live, ok := r.state.metricGroups[desired.Name]
+	if !ok {
+		r.record("create", "metricgroup", desired.Name, diffFields(desired, &metricGroupSpec{}))
+		if desired.Subject == nil {
+			return fmt.Errorf("subject is required to create the metricgroup")
+		}
+		if r.dryRun {
+			return nil
+		}
+		live = &api.MetricGroup{}
+		result, err := r.capi.CreateMetricGroupContext(r.ctx, desired.Name, stringValue(desired.Description, ""),
+			stringValue(desired.Type, ""), stringValue(desired.State, "ENABLED"), *desired.Subject)
+		if err := decode(result, err, live); err != nil {
+			return err
+		}
+		r.state.metricGroups[desired.Name] = live
+		return r.updateGroupMetrics(desired.Name, desired.Metrics)
+	}
+
+	groupFields := diffFields(&withoutMetrics, r.state.metricGroupToSpec(live))
+	fields := groupFields
+	current := r.state.groupMetrics[desired.Name]
+	if desired.Metrics != nil && !reflect.DeepEqual(desired.Metrics, current) {
+		fields = append(fields, fieldChange{"metrics", current, desired.Metrics})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	r.record("update", "metricgroup", desired.Name, fields)
+	if r.dryRun {
+		return nil
+	}
+	if len(groupFields) > 0 {
+		metricGroup := *live
+		metricGroup.Description = stringValue(desired.Description, metricGroup.Description)
+		metricGroup.Type = stringValue(desired.Type, metricGroup.Type)
+		metricGroup.State = stringValue(desired.State, metricGroup.State)
+		metricGroup.Subject = stringValue(desired.Subject, metricGroup.Subject)
+		result, err := r.capi.UpdateMetricGroupContext(r.ctx, &metricGroup)
+		if err := decode(result, err, live); err != nil {
+			return err
+		}
+	}
+	return r.updateGroupMetrics(desired.Name, desired.Metrics)
+}
+
+// updateGroupMetrics adds and removes metrics so the metric group contains the given metrics,
+// nil keeps the metrics of the group.
+func (r *reconciler) updateGroupMetrics(groupName string, metrics []string) error {
+	if metrics == nil {
+		return nil
+	}
+	group := r.state.metricGroups[groupName]
+	current := r.state.groupMetrics[groupName]
+	for _, name := range missingNames(metrics, current) {
+		metric, ok := r.state.metrics[name]
+		if !ok {
+			return fmt.Errorf("metric %s not found", name)
+		}
+		if _, err := r.capi.AddObjectToGroupContext(r.ctx, "metric", metric, group); err != nil {
+			return fmt.Errorf("metric %s: %s", name, err)
+		}
+	}
+	for _, name := range missingNames(current, metrics) {
+		if _, err := r.capi.DeleteObjectFromGroupContext(r.ctx, "metric", r.state.metrics[name], group); err != nil {
+			return fmt.Errorf("metric %s: %s", name, err)
+		}
+	}
+	r.state.groupMetrics[groupName] = metrics
+	return nil
+}
+
+func (r *reconciler) applyEvents(spec *appSpec) error {
+	for _, desired := range spec.Events {
+		live, ok := r.state.events[desired.Name]
+		if !ok {
+			r.record("create", "event", desired.Name, diffFields(desired, &eventSpec{}))
+			if r.dryRun {
+				continue
+			}
+			event := &api.Event{}
+			result, err := r.capi.CreateEventContext(r.ctx, desired.Name, stringValue(desired.Description, ""),
+				stringValue(desired.AttributeDescriptions, ""), stringValue(desired.Type, ""))
+			if err := decode(result, err, event); err != nil {
+				return fmt.Errorf("event %s: %s", desired.Name, err)
+			}
+			r.state.events[desired.Name] = event
+			continue
+		}
+
+		fields := diffFields(desired, r.state.eventToSpec(live))
+		if len(fields) == 0 {
+			continue
+		}
+		r.record("update", "event", desired.Name, fields)
+		if r.dryRun {
+			continue
+		}
+		event := *live
+		event.Description = stringValue(desired.Description, event.Description)
+		event.AttributeDescriptions = stringValue(desired.AttributeDescriptions, event.AttributeDescriptions)
+		event.Type = stringValue(desired.Type, event.Type)
+		result, err := r.capi.UpdateEventContext(r.ctx, &event)
+		if err := decode(result, err, live); err != nil {
+			return fmt.Errorf("event %s: %s", desired.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *reconciler) applyAlertTypes(spec *appSpec) error {
+	for _, desired := range spec.AlertTypes {
+		if err := r.applyAlertType(desired); err != nil {
+			return fmt.Errorf("alerttype %s: %s", desired.Name, err)
+		}
+		for _, trigger := range desired.Triggers {
+			if err := r.applyTrigger(desired.Name, trigger); err != nil {
+				return fmt.Errorf("trigger %s of alerttype %s: %s", trigger.Name, desired.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseHandle converts a handle of a spec into the json format, nil or empty is kept empty.
+func parseHandle(handle *string, defaultValue string) (string, error) {
+	if handle == nil {
+		return defaultValue, nil
+	}
+	if *handle == "" {
+		return "", nil
+	}
+	return api.ParseHandle(*handle)
+}
+
+func (r *reconciler) applyAlertType(desired *alertTypeSpec) error {
+	// The triggers are applied separately.
+	withoutTriggers := *desired
+	withoutTriggers.Triggers = nil
+
+	live, ok := r.state.alertTypes[desired.Name]
+	if !ok {
+		r.record("create", "alerttype", desired.Name, diffFields(&withoutTriggers, &alertTypeSpec{}))
+		if desired.Handle == nil || *desired.Handle == "" {
+			return fmt.Errorf("handle is required to create the alerttype")
+		}
+		if r.dryRun {
+			r.state.triggers[desired.Name] = make(map[string]*api.AlertTrigger)
+			return nil
+		}
+		handle, err := parseHandle(desired.Handle, "")
+		if err != nil {
+			return err
+		}
+		backupHandle, err := parseHandle(desired.BackupHandle, api.DEFAULT_STRING_VALUE)
+		if err != nil {
+			return err
+		}
+		escalationHandle, err := parseHandle(desired.EscalationHandle, api.DEFAULT_STRING_VALUE)
+		if err != nil {
+			return err
+		}
+		alertType := &api.AlertType{}
+		result, err := r.capi.CreateTypeContext(r.ctx, desired.Name, stringValue(desired.Description, ""), handle, backupHandle,
+			escalationHandle, int64Value(desired.BackupSeconds, -1), int64Value(desired.EscalationSeconds, -1))
+		if err := decode(result, err, alertType); err != nil {
+			return err
+		}
+		r.state.alertTypes[desired.Name] = alertType
+		r.state.triggers[desired.Name] = make(map[string]*api.AlertTrigger)
+		return nil
+	}
+
+	liveSpec, err := r.state.alertTypeToSpec(live)
+	if err != nil {
+		return err
+	}
+	fields := diffFields(&withoutTriggers, liveSpec)
+	if len(fields) == 0 {
+		return nil
+	}
+	r.record("update", "alerttype", desired.Name, fields)
+	if r.dryRun {
+		return nil
+	}
+	alertType := *live
+	alertType.Description = stringValue(desired.Description, alertType.Description)
+	alertType.BackupSeconds = int64Value(desired.BackupSeconds, alertType.BackupSeconds)
+	alertType.EscalationSeconds = int64Value(desired.EscalationSeconds, alertType.EscalationSeconds)
+	if alertType.Handle, err = parseHandle(desired.Handle, alertType.Handle); err != nil {
+		return err
+	}
+	if alertType.BackupHandle, err = parseHandle(desired.BackupHandle, alertType.BackupHandle); err != nil {
+		return err
+	}
+	if alertType.EscalationHandle, err = parseHandle(desired.EscalationHandle, alertType.EscalationHandle); err != nil {
+		return err
+	}
+	result, err := r.capi.UpdateTypeContext(r.ctx, &alertType)
+	if err := decode(result, err, live); err != nil {
+		return err
+	}
+	return nil
+}
+
+// triggerReferences contains the ids of the objects a trigger refers to.
+type triggerReferences struct {
+	metricID, serverID, serverGroupID int64
+	dimensionSpecs                    string
+}
+
+// resolveTrigger finds the ids of the objects a trigger refers to, -1 is used for no server or group.
+func (r *reconciler) resolveTrigger(desired *triggerSpec) (*triggerReferences, error) {
+	refs := &triggerReferences{serverID: -1, serverGroupID: -1}
+	metric, ok := r.state.metrics[desired.Metric]
+	if !ok {
+		return nil, fmt.Errorf("metric %s not found", desired.Metric)
+	}
+	refs.metricID = metric.ID
+	if desired.Server != nil {
+		server, ok := r.state.servers[*desired.Server]
+		if !ok {
+			return nil, fmt.Errorf("server %s not found", *desired.Server)
+		}
+		refs.serverID = server.ID
+	}
+	if desired.ServerGroup != nil {
+		serverGroup, ok := r.state.serverGroups[*desired.ServerGroup]
+		if !ok {
+			return nil, fmt.Errorf("servergroup %s not found", *desired.ServerGroup)
+		}
+		refs.serverGroupID = serverGroup.ID
+	}
+	dimensionSpecs := make([][]interface{}, 0, len(desired.DimensionSpecs))
+	for _, dimensionSpec := range desired.DimensionSpecs {
+		dimension, ok := r.state.dimensions[dimensionSpec.Dimension]
+		if !ok {
+			return nil, fmt.Errorf("dimension %s not found", dimensionSpec.Dimension)
+		}
+		dimensionSpecs = append(dimensionSpecs, []interface{}{dimension.ID, dimensionSpec.Values})
+	}
+	encoded, err := json.Marshal(dimensionSpecs)
+	if err != nil {
+		return nil, err
+	}
+	refs.dimensionSpecs = string(encoded)
+	return refs, nil
+}
+
+func (r *reconciler) applyTrigger(alertTypeName string, desired *triggerSpec) error {
+	name := alertTypeName + "/" + desired.Name
+	live, ok := r.state.triggers[alertTypeName][desired.Name]
+	if !ok {
+		r.record("create", "trigger", name, diffFields(desired, &triggerSpec{}))
+		if r.dryRun {
+			return nil
+		}
+		refs, err := r.resolveTrigger(desired)
+		if err != nil {
+			return err
+		}
+		alertTypeID := r.state.alertTypes[alertTypeName].ID
+		trigger := &api.AlertTrigger{}
+		result, err := r.capi.CreateTriggerContext(r.ctx, desired.Name, stringValue(desired.Description, ""), desired.Config,
+			refs.dimensionSpecs, alertTypeID, int64Value(desired.AutoResolveSeconds, -1), refs.metricID, refs.serverID,
+			refs.serverGroupID, refs.serverID == -1 && refs.serverGroupID == -1)
+		if err := decode(result, err, trigger); err != nil {
+			return err
+		}
+		r.state.triggers[alertTypeName][desired.Name] = trigger
+		return nil
+	}
+
+	liveSpec, err := r.state.triggerToSpec(live)
+	if err != nil {
+		return err
+	}
+	// The application, server or server group and the dimension specs are always compared.
+	fields := diffFields(desired, liveSpec, "server", "servergroup", "dimensionSpecs")
+	if len(fields) == 0 {
+		return nil
+	}
+	r.record("update", "trigger", name, fields)
+	if r.dryRun {
+		return nil
+	}
+	refs, err := r.resolveTrigger(desired)
+	if err != nil {
+		return err
+	}
+	trigger := *live
+	trigger.Description = stringValue(desired.Description, trigger.Description)
+	trigger.Config = desired.Config
+	trigger.AutoResolve = int64Value(desired.AutoResolveSeconds, trigger.AutoResolve)
+	trigger.Metric = refs.metricID
+	trigger.ServerID, trigger.GroupID = 0, 0
+	if refs.serverID != -1 {
+		trigger.ServerID = refs.serverID
+	}
+	if refs.serverGroupID != -1 {
+		trigger.GroupID = refs.serverGroupID
+	}
+	trigger.OnApp = refs.serverID == -1 && refs.serverGroupID == -1
+	trigger.DimensionSpecs = refs.dimensionSpecs
+	result, err := r.capi.UpdateTriggerContext(r.ctx, r.state.alertTypes[alertTypeName].ID, &trigger)
+	if err := decode(result, err, live); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recordMissing records the live objects that are not in the spec as deletes. Only the kinds
+// that are in the spec are compared, e.g. "servers: []" reports all servers. The triggers are
+// compared for the alert types with triggers in the spec. Dimensions are not compared because
+// they are shared by metrics.
+func (r *reconciler) recordMissing(spec *appSpec) error {
+	if spec.AlertTypes != nil {
+		for _, alertType := range spec.AlertTypes {
+			if alertType.Triggers == nil {
+				continue
+			}
+			var desired []string
+			for _, trigger := range alertType.Triggers {
+				desired = append(desired, trigger.Name)
+			}
+			r.recordDeletes("trigger", alertType.Name+"/", objectNames(r.state.triggers[alertType.Name]), desired)
+		}
+		var desired []string
+		for _, alertType := range spec.AlertTypes {
+			desired = append(desired, alertType.Name)
+		}
+		r.recordDeletes("alerttype", "", objectNames(r.state.alertTypes), desired)
+	}
+
+	if spec.Events != nil {
+		var desired []string
+		for _, event := range spec.Events {
+			desired = append(desired, event.Name)
+		}
+		r.recordDeletes("event", "", objectNames(r.state.events), desired)
+	}
+
+	if spec.MetricGroups != nil {
+		var desired []string
+		for _, metricGroup := range spec.MetricGroups {
+			desired = append(desired, metricGroup.Name)
+		}
+		r.recordDeletes("metricgroup", "", objectNames(r.state.metricGroups), desired)
+	}
+
+	if spec.Metrics != nil {
+		var desired []string
+		for _, metric := range spec.Metrics {
+			desired = append(desired, metric.Name)
+		}
+		r.recordDeletes("metric", "", objectNames(r.state.metrics), desired)
+	}
+
+	if spec.ServerGroups != nil {
+		var desired []string
+		for _, serverGroup := range spec.ServerGroups {
+			desired = append(desired, serverGroup.Name)
+		}
+		r.recordDeletes("servergroup", "", objectNames(r.state.serverGroups), desired)
+	}
+
+	if spec.Servers != nil {
+		var desired []string
+		for _, server := range spec.Servers {
+			desired = append(desired, server.Name)
+		}
+		r.recordDeletes("server", "", objectNames(r.state.servers), desired)
+	}
+	return nil
+}
+
+// recordDeletes records the live objects that are not desired, prefix is added to the names of
+// the changes.
+func (r *reconciler) recordDeletes(kind, prefix string, live, desired []string) {
+	for _, name := range missingNames(live, desired) {
+		r.changes = append(r.changes, &specChange{Action: "delete", Kind: kind, Name: prefix + name,
+			Reason: "not in the file, apply does not delete it"})
+	}
+}
+
+// objectNames returns the sorted names of a map of api objects by name.
+func objectNames(objects interface{}) []string {
+	var names []string
+	for _, key := range reflect.ValueOf(objects).MapKeys() {
+		names = append(names, key.String())
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"coscale/api"
+	"encoding/json"
+	"testing"
+)
+
+var appYAML = `
+# The configuration of the test application.
+servers:
+  - name: web 1
+    description: "Web server: nginx"
+    state: INACTIVE
+servergroups:
+  - name: frontend
+    parent: production
+  - name: production
+metrics:
+  - name: Queued messages
+    dataType: DOUBLE
+    subject: SERVER
+    period: 60
+    dimensions: [queue, host]
+alerttypes:
+  - name: Operations
+    handle: SLACK:https://hooks.slack.com/x  EMAIL:ops@example.com
+    backupSeconds: 600
+    triggers:
+      - name: Queue too long
+        metric: Queued messages
+        config: avg(300) > 1000
+        dimensionSpecs:
+          - dimension: queue
+            values: "*"
+    description: |
+      Alerts for
+      the operations team.
+`
+
+// Test unmarshalYAML for the block and flow styles.
+func TestUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		expected string
+	}{
+		{"a: 1\nb: [x, 'y z', \"q\"]\nc:\n  - d: true\n    e: ~\n", `{"a":1,"b":["x","y z","q"],"c":[{"d":true,"e":null}]}`},
+		{"list:\n- 1.5\n- {k: v}\n", `{"list":[1.5,{"k":"v"}]}`},
+		{"text: >-\n  folded\n  lines\n\nnext: 'it''s'\n", `{"next":"it's","text":"folded lines"}`},
+		{"- - a\n  - b\n", `[["a","b"]]`},
+	}
+	for _, test := range tests {
+		value, err := unmarshalYAML([]byte(test.yaml))
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", test.yaml, err)
+		}
+		found, _ := json.Marshal(value)
+		if string(found) != test.expected {
+			t.Fatalf("expected: %s, found: %s", test.expected, found)
+		}
+	}
+
+	for _, invalid := range []string{"a: 1\na: 2\n", "a:\n\tb: 1\n", "a: [1, 2\n", "a: 1\n  b: 2\n"} {
+		if _, err := unmarshalYAML([]byte(invalid)); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
+
+// Test decodeAppSpec for YAML and the validation of the spec.
+func TestDecodeAppSpec(t *testing.T) {
+	spec, err := decodeAppSpec([]byte(appYAML), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	alertType := spec.AlertTypes[0]
+	if *alertType.Handle != "EMAIL:ops@example.com SLACK:https://hooks.slack.com/x" {
+		t.Fatalf("expected: the handle with sorted contacts, found: %s", *alertType.Handle)
+	}
+	if *alertType.Description != "Alerts for\nthe operations team.\n" {
+		t.Fatalf("expected: the block scalar, found: %q", *alertType.Description)
+	}
+	if dimensions := spec.Metrics[0].Dimensions; dimensions[0] != "host" || dimensions[1] != "queue" {
+		t.Fatalf("expected: sorted dimensions, found: %v", dimensions)
+	}
+
+	invalid := []string{
+		"servers:\n  - description: no name\n",
+		"servers:\n  - name: a\n  - name: a\n",
+		"server:\n  - name: a\n",
+		"servers:\n  - name: a\n    colour: red\n",
+		"alerttypes:\n  - name: a\n    triggers:\n      - name: t\n        config: avg(60) > 1\n",
+		"alerttypes:\n  - name: a\n    handle: PHONE:123\n",
+	}
+	for _, data := range invalid {
+		if _, err := decodeAppSpec([]byte(data), false); err == nil {
+			t.Fatalf("expected an error for %q", data)
+		}
+	}
+
+	if _, err := decodeAppSpec([]byte(`{"events": [{"name": "deployment"}]}`), true); err != nil {
+		t.Fatalf("unexpected error for json: %s", err)
+	}
+}
+
+// Test the changes that are found by a dry run of the reconciler.
+func TestReconcileDryRun(t *testing.T) {
+	spec, err := decodeAppSpec([]byte(appYAML), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	state := newAppState()
+	state.servers["web 1"] = &api.Server{ID: 1, Name: "web 1", Description: "Web server: nginx", State: "ENABLED"}
+	state.serverGroups["production"] = &api.ServerGroup{ID: 2, Name: "production"}
+	state.metrics["Queued messages"] = &api.Metric{ID: 3, Name: "Queued messages", DataType: "DOUBLE", Subject: "SERVER", Period: 60}
+	state.metricDimensions["Queued messages"] = []string{"queue"}
+	state.dimensions["queue"] = &api.Dimension{ID: 4, Name: "queue"}
+	state.alertTypes["Operations"] = &api.AlertType{ID: 5, Name: "Operations", Description: "Alerts for\nthe operations team.\n",
+		Handle: `[{"type":"EMAIL","address":"ops@example.com"},{"type":"SLACK","webhook":"https://hooks.slack.com/x"}]`, BackupSeconds: 600}
+	state.triggers["Operations"] = map[string]*api.AlertTrigger{
+		"Queue too long": {ID: 6, Name: "Queue too long", Metric: 3, Config: "avg(300) > 1000", GroupID: 2, DimensionSpecs: `[[4,"*"]]`},
+	}
+
+	r := &reconciler{state: state, dryRun: true}
+	if err := r.apply(spec); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `[{"action":"update","kind":"server","name":"web 1","fields":[{"field":"state","old":"ENABLED","new":"INACTIVE"}]},` +
+		`{"action":"create","kind":"servergroup","name":"frontend","fields":[{"field":"parent","old":null,"new":"production"}]},` +
+		`{"action":"update","kind":"metric","name":"Queued messages","fields":[{"field":"dimensions","old":["queue"],"new":["host","queue"]}]},` +
+		`{"action":"create","kind":"dimension","name":"host"},` +
+		`{"action":"update","kind":"trigger","name":"Operations/Queue too long","fields":[{"field":"servergroup","old":"production","new":null}]}]`
+	found, _ := json.Marshal(r.changes)
+	if string(found) != expected {
+		t.Fatalf("expected: %s, found: %s", expected, found)
+	}
+}
+
+// Test the deletes that are reported for the objects that are not in the spec and the diff of
+// the changes.
+func TestFormatPlan(t *testing.T) {
+	spec, err := decodeAppSpec([]byte("events: []\nalerttypes:\n  - name: Operations\n    triggers: []\n"), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	state := newAppState()
+	state.servers["web 1"] = &api.Server{ID: 1, Name: "web 1"}
+	state.events["deployment"] = &api.Event{ID: 2, Name: "deployment"}
+	state.alertTypes["Operations"] = &api.AlertType{ID: 5, Name: "Operations"}
+	state.alertTypes["Other"] = &api.AlertType{ID: 7, Name: "Other"}
+	state.triggers["Operations"] = map[string]*api.AlertTrigger{"Queue too long": {ID: 6, Name: "Queue too long"}}
+	state.triggers["Other"] = map[string]*api.AlertTrigger{}
+
+	r := &reconciler{state: state, dryRun: true, reportMissing: true}
+	if err := r.apply(spec); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	r.changes = append(r.changes,
+		&specChange{Action: "create", Kind: "event", Name: "deploy", Fields: []fieldChange{{"description", nil, "Deployments"}}},
+		&specChange{Action: "update", Kind: "server", Name: "web 1", Fields: []fieldChange{{"state", "ENABLED", "INACTIVE"}}})
+
+	expected := `- trigger "Operations/Queue too long"
+    not in the file, apply does not delete it
+- alerttype "Other"
+    not in the file, apply does not delete it
+- event "deployment"
+    not in the file, apply does not delete it
++ event "deploy"
+    description: "Deployments"
+~ server "web 1"
+    state: "ENABLED" -> "INACTIVE"
+
+Plan: 1 to create, 1 to update, 3 to delete.`
+	if found := formatPlan(r.changes); found != expected {
+		t.Fatalf("expected: %s, found: %s", expected, found)
+	}
+	if found := formatPlan(nil); found != "No changes, the live objects are up to date." {
+		t.Fatalf("expected: no changes, found: %s", found)
+	}
+}
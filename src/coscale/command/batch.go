@@ -0,0 +1,128 @@
+package command
+
+import (
+	"coscale/api"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// The status of a batch in the summary of data insert.
+const (
+	batchInserted = "inserted"
+	batchFailed   = "failed"
+	batchSpooled  = "spooled"
+	batchSkipped  = "skipped"
+)
+
+// batchResult is the outcome of inserting one batch of data.
+type batchResult struct {
+	// Batch is the 1-based index of the batch.
+	Batch      int             `json:"batch"`
+	Subjects   []string        `json:"subjects"`
+	DataPoints int             `json:"datapoints"`
+	Status     string          `json:"status"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	// result and err are returned by the insert call.
+	result string
+	err    error
+}
+
+// insertBatches inserts the batches of data using insert with at most parallel concurrent calls.
+// Unless keepGoing is set, the batches that were not started when a batch failed are skipped.
+func insertBatches(insert func(map[string][]*api.ApiData) (string, error), callsData []map[string][]*api.ApiData,
+	parallel int, keepGoing bool) []*batchResult {
+
+	results := make([]*batchResult, len(callsData))
+	for i, callData := range callsData {
+		results[i] = newBatchResult(i+1, callData)
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var mu sync.Mutex
+	failed := false
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				mu.Lock()
+				skip := failed && !keepGoing
+				mu.Unlock()
+				if skip {
+					continue
+				}
+				result, err := insert(callsData[i])
+				results[i].setOutcome(result, err)
+				if err != nil {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range callsData {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// newBatchResult creates the result of a batch that was not inserted yet.
+func newBatchResult(batch int, callData map[string][]*api.ApiData) *batchResult {
+	result := &batchResult{Batch: batch, Subjects: []string{}, Status: batchSkipped}
+	for subject, data := range callData {
+		result.Subjects = append(result.Subjects, subject)
+		for _, apiData := range data {
+			result.DataPoints += len(apiData.Data)
+		}
+	}
+	sort.Strings(result.Subjects)
+	return result
+}
+
+// setOutcome records the result or error of the insert call of the batch.
+func (r *batchResult) setOutcome(result string, err error) {
+	r.result, r.err = result, err
+	switch err.(type) {
+	case nil:
+		r.Status = batchInserted
+		if json.Valid([]byte(result)) {
+			r.Result = json.RawMessage(result)
+		} else {
+			r.Result, _ = json.Marshal(result)
+		}
+		return
+	case api.SpooledError:
+		r.Status = batchSpooled
+	default:
+		r.Status = batchFailed
+	}
+	r.Error = err.Error()
+}
+
+// printBatchResults prints the result of a single batch as returned by the API, or a summary with
+// the outcome of every batch. The exit code is 1 if a batch was not inserted.
+func (c *Command) printBatchResults(results []*batchResult) {
+	if len(results) == 1 {
+		c.PrintResult(results[0].result, results[0].err)
+	}
+	exitCode := EXIT_SUCCESS
+	for _, result := range results {
+		if result.Status != batchInserted {
+			exitCode = EXIT_SUCCESS_ERROR
+		}
+	}
+	if results == nil {
+		results = []*batchResult{}
+	}
+	summary, _ := json.MarshalIndent(results, "", " ")
+	c.printResultAndExit(string(summary), nil, exitCode)
+}
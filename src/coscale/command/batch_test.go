@@ -0,0 +1,67 @@
+package command
+
+import (
+	"coscale/api"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test that insertBatches limits the concurrent calls and reports the outcome of every batch.
+func TestInsertBatches(t *testing.T) {
+	var callsData []map[string][]*api.ApiData
+	for i := 1; i <= 6; i++ {
+		subject := fmt.Sprintf("S%d", i)
+		apiData := &api.ApiData{MetricID: 1, SubjectID: subject, Data: []api.DataPoint{{SecondsAgo: 0, Data: "1"}, {SecondsAgo: -60, Data: "2"}}}
+		callsData = append(callsData, map[string][]*api.ApiData{subject: {apiData}})
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	insert := func(callData map[string][]*api.ApiData) (string, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if _, ok := callData["S2"]; ok {
+			return "", fmt.Errorf("failed")
+		}
+		return `"0"`, nil
+	}
+
+	results := insertBatches(insert, callsData, 3, true)
+	if maxRunning != 3 {
+		t.Fatalf("expected: 3 concurrent calls, found: %d", maxRunning)
+	}
+	for i, result := range results {
+		expected := batchInserted
+		if i == 1 {
+			expected = batchFailed
+		}
+		if result.Batch != i+1 || result.Status != expected || result.DataPoints != 2 || result.Subjects[0] != fmt.Sprintf("S%d", i+1) {
+			t.Fatalf("expected: batch %d %s, found: %+v", i+1, expected, result)
+		}
+	}
+	if string(results[0].Result) != `"0"` || results[1].Error != "failed" {
+		t.Fatalf("expected: the result and error, found: %+v %+v", results[0], results[1])
+	}
+
+	// Without keepGoing the batches after the failure are skipped.
+	maxRunning = 0
+	results = insertBatches(insert, callsData, 1, false)
+	if maxRunning != 1 || results[0].Status != batchInserted || results[1].Status != batchFailed {
+		t.Fatalf("expected: the first batch is inserted and the second failed, found: %+v %+v", results[0], results[1])
+	}
+	for _, result := range results[2:] {
+		if result.Status != batchSkipped {
+			t.Fatalf("expected: skipped, found: %+v", result)
+		}
+	}
+}
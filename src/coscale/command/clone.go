@@ -0,0 +1,227 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// CloneObject defines the clone command on the CLI.
+var CloneObject = &Command{
+	Name:      "clone",
+	UsageLine: "clone (--from-profile --to-profile) [--dry-run]",
+	Long: `
+Copy the server groups, dimensions, metrics, metric groups, events, alert types and triggers
+of an application to another application. Objects are matched by name: objects that do not
+exist in the target application are created, existing objects are updated. The references
+between the objects, e.g. the metric, server group and dimensions of a trigger, are mapped
+by name. Servers are not copied, the server of a trigger is mapped to the server with the
+same name in the target application. The changes are printed.
+
+A trigger is skipped if one of its references can not be mapped: a server that does not exist
+in the target application, an object that could not be found in the source application or
+dimension values that select dimension value ids, as these ids differ between applications.
+A metric is skipped if one of its dimensions can not be mapped, the metric groups and triggers
+of a skipped metric are skipped too.
+
+The flags for clone are:
+
+Mandatory:
+	--from-profile
+		The configuration profile of the source application.
+	--to-profile
+		The configuration profile of the target application.
+Optional:
+	--dry-run
+		Show the changes and the objects that can not be mapped as a diff, without making the changes.
+`,
+	Run: func(cmd *Command, args []string) {
+		var fromProfile, toProfile string
+		var dryRun bool
+		cmd.Flag.Usage = func() { cmd.PrintUsage() }
+		cmd.Flag.StringVar(&fromProfile, "from-profile", DEFAULT_STRING_FLAG_VALUE, "The profile of the source application.")
+		cmd.Flag.StringVar(&toProfile, "to-profile", DEFAULT_STRING_FLAG_VALUE, "The profile of the target application.")
+		cmd.Flag.BoolVar(&dryRun, "dry-run", false, "Show the changes without making them.")
+		cmd.ParseArgs(args)
+
+		if fromProfile == DEFAULT_STRING_FLAG_VALUE || toProfile == DEFAULT_STRING_FLAG_VALUE {
+			cmd.PrintUsage()
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		source, err := profileApi(cmd.Capi, fromProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		target, err := profileApi(cmd.Capi, toProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+
+		ctx := context.Background()
+		sourceState, err := loadAppState(ctx, source)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+		targetState, err := loadAppState(ctx, target)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+		spec, skipped, err := cloneSpec(sourceState, targetState)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+
+		r := &reconciler{ctx: ctx, capi: target, state: targetState, dryRun: dryRun}
+		if err := r.apply(spec); err != nil {
+			fmt.Fprintln(os.Stdout, formatChanges(append(r.changes, skipped...)))
+			cmd.PrintResult("", err)
+		}
+		changes := append(r.changes, skipped...)
+		if dryRun && !cmd.isFlagSet("output", "query", "format") {
+			fmt.Fprintln(os.Stdout, formatPlan(changes))
+			os.Exit(EXIT_SUCCESS)
+		}
+		cmd.PrintResult(formatChanges(changes), nil)
+	},
+}
+
+// profileApi returns an Api connector for the application of a profile in the configuration file.
+func profileApi(capi *api.Api, profile string) (*api.Api, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("Could not find the configuration file: %s", err)
+	}
+	config, err := api.ReadApiConfigurationProfile(configPath, profile)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read profile %s: %s", profile, err)
+	}
+	return capi.ForApp(config.BaseUrl, config.AccessToken, config.AppId), nil
+}
+
+// dimensionValueIDPattern matches dimension values that select value ids, e.g. "11,12" or "AVG(11)".
+var dimensionValueIDPattern = regexp.MustCompile(`[0-9]`)
+
+// cloneSpec converts the objects of the source application into a spec for the target application.
+// The objects with references that can not be mapped are left out and returned as skipped changes.
+func cloneSpec(source, target *appState) (*appSpec, []*specChange, error) {
+	spec, err := source.toSpec()
+	if err != nil {
+		return nil, nil, err
+	}
+	// Servers are not copied, they are added by the agents of the application.
+	spec.Servers = nil
+	var skipped []*specChange
+
+	// A server group is skipped if its parent is unknown or skipped.
+	skippedGroups := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		var serverGroups []*serverGroupSpec
+		for _, serverGroup := range spec.ServerGroups {
+			parent := stringValue(serverGroup.Parent, "")
+			if isUnknownName(parent) || skippedGroups[parent] {
+				skippedGroups[serverGroup.Name] = true
+				skipped = append(skipped, &specChange{Action: "skip", Kind: "servergroup", Name: serverGroup.Name,
+					Reason: fmt.Sprintf("parent %s can not be mapped", parent)})
+				changed = true
+				continue
+			}
+			serverGroups = append(serverGroups, serverGroup)
+		}
+		spec.ServerGroups = serverGroups
+	}
+
+	// A metric is skipped if one of its dimensions is not a dimension of the source application.
+	dimensions := make(map[string]bool)
+	for _, dimension := range spec.Dimensions {
+		dimensions[dimension.Name] = true
+	}
+	skippedMetrics := make(map[string]bool)
+	var metrics []*metricSpec
+	for _, metric := range spec.Metrics {
+		var unmapped []string
+		for _, dimension := range metric.Dimensions {
+			if !dimensions[dimension] {
+				unmapped = append(unmapped, dimension)
+			}
+		}
+		if len(unmapped) > 0 {
+			skippedMetrics[metric.Name] = true
+			skipped = append(skipped, &specChange{Action: "skip", Kind: "metric", Name: metric.Name,
+				Reason: fmt.Sprintf("dimension %s can not be mapped", strings.Join(unmapped, ", "))})
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	spec.Metrics = metrics
+
+	// A metric group is skipped if one of its metrics is unknown or skipped.
+	var metricGroups []*metricGroupSpec
+	for _, metricGroup := range spec.MetricGroups {
+		if metric := firstUnmappedName(metricGroup.Metrics, skippedMetrics); metric != "" {
+			skipped = append(skipped, &specChange{Action: "skip", Kind: "metricgroup", Name: metricGroup.Name,
+				Reason: fmt.Sprintf("metric %s can not be mapped", metric)})
+			continue
+		}
+		metricGroups = append(metricGroups, metricGroup)
+	}
+	spec.MetricGroups = metricGroups
+
+	for _, alertType := range spec.AlertTypes {
+		var triggers []*triggerSpec
+		for _, trigger := range alertType.Triggers {
+			if reason := unmappedTriggerReason(trigger, target, skippedGroups, skippedMetrics); reason != "" {
+				skipped = append(skipped, &specChange{Action: "skip", Kind: "trigger", Name: alertType.Name + "/" + trigger.Name, Reason: reason})
+				continue
+			}
+			triggers = append(triggers, trigger)
+		}
+		alertType.Triggers = triggers
+	}
+	return spec, skipped, nil
+}
+
+// unmappedTriggerReason returns why a reference of a trigger can not be mapped, empty if all
+// references can be mapped.
+func unmappedTriggerReason(trigger *triggerSpec, target *appState, skippedGroups, skippedMetrics map[string]bool) string {
+	if isUnknownName(trigger.Metric) || skippedMetrics[trigger.Metric] {
+		return fmt.Sprintf("metric %s can not be mapped", trigger.Metric)
+	}
+	if trigger.Server != nil {
+		if _, ok := target.servers[*trigger.Server]; !ok {
+			return fmt.Sprintf("server %s does not exist in the target application", *trigger.Server)
+		}
+	}
+	if trigger.ServerGroup != nil && (isUnknownName(*trigger.ServerGroup) || skippedGroups[*trigger.ServerGroup]) {
+		return fmt.Sprintf("servergroup %s can not be mapped", *trigger.ServerGroup)
+	}
+	for _, dimensionSpec := range trigger.DimensionSpecs {
+		if isUnknownName(dimensionSpec.Dimension) {
+			return fmt.Sprintf("dimension %s can not be mapped", dimensionSpec.Dimension)
+		}
+		if dimensionValueIDPattern.MatchString(dimensionSpec.Values) {
+			return fmt.Sprintf("the values %s of dimension %s are dimension value ids", dimensionSpec.Values, dimensionSpec.Dimension)
+		}
+	}
+	return ""
+}
+
+// firstUnmappedName returns the first name that is unknown or skipped, empty if all names can be mapped.
+func firstUnmappedName(names []string, skipped map[string]bool) string {
+	for _, name := range names {
+		if isUnknownName(name) || skipped[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// isUnknownName returns true for the names of objects that could not be found, see appState.
+func isUnknownName(name string) bool {
+	return strings.HasPrefix(name, "#")
+}
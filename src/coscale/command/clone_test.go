@@ -0,0 +1,78 @@
+package command
+
+import (
+	"coscale/api"
+	"testing"
+)
+
+// Test the objects that are skipped by cloneSpec because their references can not be mapped.
+func TestCloneSpec(t *testing.T) {
+	source := newAppState()
+	source.servers["web 1"] = &api.Server{ID: 1, Name: "web 1"}
+	source.servers["web 2"] = &api.Server{ID: 11, Name: "web 2"}
+	source.serverGroups["production"] = &api.ServerGroup{ID: 2, Name: "production", ParentID: 99}
+	source.serverGroups["frontend"] = &api.ServerGroup{ID: 8, Name: "frontend", ParentID: 2}
+	source.serverGroups["backend"] = &api.ServerGroup{ID: 12, Name: "backend"}
+	source.metrics["Queued messages"] = &api.Metric{ID: 3, Name: "Queued messages"}
+	source.metrics["Consumers"] = &api.Metric{ID: 9, Name: "Consumers"}
+	source.metricDimensions["Consumers"] = []string{"consumer", "queue"}
+	source.metricGroups["RabbitMQ"] = &api.MetricGroup{ID: 10, Name: "RabbitMQ"}
+	source.groupMetrics["RabbitMQ"] = []string{"Consumers", "Queued messages"}
+	source.metricGroups["Queues"] = &api.MetricGroup{ID: 13, Name: "Queues"}
+	source.groupMetrics["Queues"] = []string{"Queued messages"}
+	source.dimensions["queue"] = &api.Dimension{ID: 4, Name: "queue"}
+	source.alertTypes["Operations"] = &api.AlertType{ID: 5, Name: "Operations"}
+	source.triggers["Operations"] = map[string]*api.AlertTrigger{
+		"a": {ID: 20, Name: "a", Metric: 3, ServerID: 1},
+		"b": {ID: 21, Name: "b", Metric: 3, ServerID: 11},
+		"c": {ID: 22, Name: "c", Metric: 3, GroupID: 8},
+		"d": {ID: 23, Name: "d", Metric: 30, GroupID: 12},
+		"e": {ID: 24, Name: "e", Metric: 3, GroupID: 12, DimensionSpecs: `[[4,"AVG(*)"]]`},
+		"f": {ID: 25, Name: "f", Metric: 3, DimensionSpecs: `[[4,"11,12"]]`},
+		"g": {ID: 26, Name: "g", Metric: 9},
+	}
+
+	target := newAppState()
+	target.servers["web 1"] = &api.Server{ID: 101, Name: "web 1"}
+
+	spec, skipped, err := cloneSpec(source, target)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if spec.Servers != nil {
+		t.Fatalf("expected: no servers, found: %v", spec.Servers)
+	}
+	if len(spec.ServerGroups) != 1 || spec.ServerGroups[0].Name != "backend" {
+		t.Fatalf("expected: only the backend servergroup, found: %v", spec.ServerGroups)
+	}
+	if len(spec.Metrics) != 1 || spec.Metrics[0].Name != "Queued messages" {
+		t.Fatalf("expected: only the Queued messages metric, found: %v", spec.Metrics)
+	}
+	if len(spec.MetricGroups) != 1 || spec.MetricGroups[0].Name != "Queues" {
+		t.Fatalf("expected: only the Queues metricgroup, found: %v", spec.MetricGroups)
+	}
+	triggers := spec.AlertTypes[0].Triggers
+	if len(triggers) != 2 || triggers[0].Name != "a" || triggers[1].Name != "e" {
+		t.Fatalf("expected: triggers a and e, found: %v", triggers)
+	}
+
+	expected := []string{
+		"servergroup production: parent #99 can not be mapped",
+		"servergroup frontend: parent production can not be mapped",
+		"metric Consumers: dimension consumer can not be mapped",
+		"metricgroup RabbitMQ: metric Consumers can not be mapped",
+		"trigger Operations/b: server web 2 does not exist in the target application",
+		"trigger Operations/c: servergroup frontend can not be mapped",
+		"trigger Operations/d: metric #30 can not be mapped",
+		"trigger Operations/f: the values 11,12 of dimension queue are dimension value ids",
+		"trigger Operations/g: metric Consumers can not be mapped",
+	}
+	if len(skipped) != len(expected) {
+		t.Fatalf("expected: %d skipped objects, found: %s", len(expected), formatChanges(skipped))
+	}
+	for i, change := range skipped {
+		if found := change.Kind + " " + change.Name + ": " + change.Reason; found != expected[i] {
+			t.Fatalf("expected: %s, found: %s", expected[i], found)
+		}
+	}
+}
@@ -1,14 +1,12 @@
 package command
 
 import (
-	"bytes"
 	"coscale/api"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -34,6 +32,8 @@ const (
 	EXIT_AUTHENTICATION_ERROR int = 2
 	// EXIT_FLAG_ERROR is the exit code indicating the provided flags are invalid.
 	EXIT_FLAG_ERROR int = 3
+	// EXIT_CHANGES_PENDING is the exit code of plan indicating the live objects do not match the file.
+	EXIT_CHANGES_PENDING int = 4
 )
 
 // Command defines a CLI command containing all flags and subcommands for the command.
@@ -46,11 +46,21 @@ type Command struct {
 	Capi        *api.Api //api connector
 	Flag        flag.FlagSet
 	Run         func(cmd *Command, args []string)
+	// parent is the command containing this command as subcommand.
+	parent *Command
+	// output is the format used by PrintResult.
+	output string
+	// query selects the part of the result that is printed by PrintResult.
+	query queryFunc
+	// format is the --format template used by PrintResult instead of the output format.
+	format *template.Template
+	// rawOutput is true if the json is printed without indentation.
+	rawOutput bool
 }
 
 // NewCommand creates a new Command.
 func NewCommand(name, usage string, subCommands []*Command) *Command {
-	return &Command{
+	cmd := &Command{
 		Name:        name,
 		UsageLine:   usage,
 		SubCommands: subCommands,
@@ -61,6 +71,10 @@ func NewCommand(name, usage string, subCommands []*Command) *Command {
 			}
 		},
 	}
+	for _, subCmd := range subCommands {
+		subCmd.parent = cmd
+	}
+	return cmd
 }
 
 // Runnable returns true if the command is runnable, meaning it doesn't have any subcommands.
@@ -130,34 +144,75 @@ func (c *Command) PrintFullUsage() {
 	os.Exit(2)
 }
 
-// GetApi returns a Api object
-func (c *Command) GetApi(baseUrl, accessToken, appId string, rawOutput, verbose bool) *api.Api {
-	if accessToken == "" || appId == "" {
-		configPath, err := GetConfigPath()
-		if err != nil {
-			return api.NewFakeApi()
-		}
-		config, err := api.ReadApiConfiguration(configPath)
-		if err != nil {
-			return api.NewFakeApi()
+// GetApi returns a Api object, the configuration of profile is used when no credentials are provided.
+func (c *Command) GetApi(baseUrl, accessToken, appId, profile string, rawOutput, verbose bool) *api.Api {
+	config, _, err := ResolveConfiguration(baseUrl, accessToken, appId, profile)
+	if err != nil {
+		return api.NewFakeApi()
+	}
+	return api.NewApi(config.BaseUrl, config.AccessToken, config.AppId, rawOutput, verbose)
+}
+
+// ResolveConfiguration returns the api configuration and a description of its source. The credentials
+// are taken from the command line flags, else from the COSCALE_APP_ID, COSCALE_ACCESS_TOKEN and
+// COSCALE_API_URL environment variables, else from profile in the configuration file. baseUrl is empty
+// if --api-url was not set, COSCALE_API_URL is only used in that case.
+func ResolveConfiguration(baseUrl, accessToken, appId, profile string) (*api.ApiConfiguration, string, error) {
+	flagBaseUrl := baseUrl
+	if baseUrl == "" {
+		baseUrl = defaultBaseUrl
+	}
+	if accessToken != "" && appId != "" {
+		return &api.ApiConfiguration{BaseUrl: baseUrl, AccessToken: accessToken, AppId: appId}, "command line flags", nil
+	}
+
+	envAccessToken, envAppId := os.Getenv("COSCALE_ACCESS_TOKEN"), os.Getenv("COSCALE_APP_ID")
+	if envAccessToken != "" && envAppId != "" {
+		if envBaseUrl := os.Getenv("COSCALE_API_URL"); envBaseUrl != "" && flagBaseUrl == "" {
+			baseUrl = strings.Trim(envBaseUrl, "/")
 		}
-		baseUrl = config.BaseUrl
-		accessToken = config.AccessToken
-		appId = config.AppId
+		return &api.ApiConfiguration{BaseUrl: baseUrl, AccessToken: envAccessToken, AppId: envAppId}, "environment variables", nil
+	}
+
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, "", err
+	}
+	configurations, err := api.ReadApiConfigurations(configPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if profile == "" {
+		profile = configurations.Current
+	}
+	config, err := configurations.Get(profile)
+	if err != nil {
+		return nil, "", err
 	}
-	return api.NewApi(baseUrl, accessToken, appId, rawOutput, verbose)
+	return config, fmt.Sprintf("profile %s in %s", profile, configPath), nil
 }
 
 // ParseArgs takes the API configuration from the args and stores them in the Command.
 func (c *Command) ParseArgs(args []string) {
 	//add the flags for the api configuration
-	var baseUrl, accessToken, appId string
+	var baseUrl, accessToken, appId, profile, output, query, format string
 	var rawOutput, verbose bool
-	c.Flag.StringVar(&baseUrl, "api-url", "https://api.coscale.com", "Base url for the api.")
+	var maxAttempts int
+	var timeout time.Duration
+	c.Flag.StringVar(&baseUrl, "api-url", defaultBaseUrl, "Base url for the api.")
 	c.Flag.StringVar(&appId, "app-id", "", "The application id.")
 	c.Flag.StringVar(&accessToken, "access-token", "", "A valid access token for the given application.")
+	c.Flag.StringVar(&profile, "profile", "", "The configuration profile to use.")
 	c.Flag.BoolVar(&rawOutput, "rawOutput", false, "The returned json objects are returned formatted by default.")
+	c.Flag.StringVar(&output, "output", "json", "The output format: json, jsonl, yaml, table or csv.")
+	// A command can define --format for another use, e.g. the file format of data insert.
+	if c.Flag.Lookup("format") == nil {
+		c.Flag.StringVar(&format, "format", "", "A Go template used to print the result.")
+	}
+	c.Flag.StringVar(&query, "query", "", "A JMESPath expression selecting the part of the result to print.")
 	c.Flag.BoolVar(&verbose, "verbose", false, "Print the URLs of the API calls.")
+	c.Flag.IntVar(&maxAttempts, "max-attempts", api.DefaultRetryPolicy().MaxAttempts, "The number of attempts for a failing API call.")
+	c.Flag.DurationVar(&timeout, "timeout", 0, "The maximum duration of an API call including retries, e.g. 30s.")
 
 	c.Flag.Parse(args)
 	unknownArgs := c.Flag.Args()
@@ -165,14 +220,62 @@ func (c *Command) ParseArgs(args []string) {
 		fmt.Fprintf(os.Stderr, "Unknown field %s\n", unknownArgs[0])
 		os.Exit(EXIT_FLAG_ERROR)
 	}
-	c.Capi = c.GetApi(strings.Trim(baseUrl, "/"), accessToken, appId, rawOutput, verbose)
+	if !isOutputFormat(output) {
+		fmt.Fprintf(os.Stderr, "Unknown output format %s, use one of: %s\n", output, strings.Join(outputFormats, ", "))
+		os.Exit(EXIT_FLAG_ERROR)
+	}
+	c.output = output
+	if query != "" {
+		compiled, err := compileQuery(query)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		c.query = compiled
+	}
+	if format != "" {
+		t, err := parseFormat(format)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		c.format = t
+	}
+	c.rawOutput = rawOutput
+	if !c.isFlagSet("api-url") {
+		baseUrl = ""
+	}
+	c.Capi = c.GetApi(strings.Trim(baseUrl, "/"), accessToken, appId, profile, rawOutput, verbose)
+
+	retryPolicy := api.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = maxAttempts
+	c.Capi.SetRetryPolicy(retryPolicy)
+	c.Capi.SetTimeout(timeout)
 }
 
 // PrintResult formats the result or error and exits the process with the appropriate exit code.
 func (c *Command) PrintResult(result string, err error) {
+	c.printResultAndExit(result, err, EXIT_SUCCESS)
+}
+
+// printResultAndExit is like PrintResult, exitCode is used if there is no error.
+func (c *Command) printResultAndExit(result string, err error, exitCode int) {
+	if err == nil && c.query != nil {
+		result, err = applyQuery(result, c.query, c.rawOutput)
+	}
+	if err == nil && c.format != nil {
+		var objectType interface{}
+		if c.query == nil {
+			// The typed objects can only be used for the complete result.
+			objectType = objectTypes[c.objectKey()]
+		}
+		result, err = executeFormat(result, c.format, objectType)
+	} else if err == nil {
+		result, err = formatOutput(result, c.output, c.columns())
+	}
 	if err == nil {
 		fmt.Fprintln(os.Stdout, result)
-		os.Exit(EXIT_SUCCESS)
+		os.Exit(exitCode)
 	} else if api.IsInvalidConfig(err) {
 		fmt.Printf(`coscale-cli could not find valid credentials.
 
@@ -200,6 +303,17 @@ func GetErrorJson(err error) string {
 var authInfo = `The authentication configuration can be written using
     coscale-cli config set
 
+The configuration file is searched in $COSCALE_CONFIG, $XDG_CONFIG_HOME/coscale/api.conf,
+~/.coscale/api.conf and next to the coscale-cli binary.
+The credentials can also be provided using the environment variables
+COSCALE_API_URL, COSCALE_APP_ID and COSCALE_ACCESS_TOKEN.
+
+Multiple applications can be configured as named profiles using
+    coscale-cli config set --profile <name>
+and selected using
+	--profile
+		The configuration profile to use (default = the profile selected by "config use").
+
 If you do not wish to create a configuration file containing your credentials,
 the credentials can also be provided on the command line using:
 	--api-url
@@ -212,6 +326,11 @@ the credentials can also be provided on the command line using:
 
 	--verbose
 		Print the URLs of the API calls.
+	--max-attempts
+		The number of attempts for an API call that failed because of a network error,
+		a server error or throttling, retries are delayed with an exponential backoff (default = 3).
+	--timeout
+		The maximum duration of an API call including its retries, e.g. 30s or 2m (default = no limit).
 `
 
 var usageTemplate = `coscale-cli a tool for CoScale Api.
@@ -233,90 +352,95 @@ Usage:
 var usageOutputJson = `
 The json objects are returned formatted by default, but can be returned on 1 line by using:
 	--rawOutput
+
+The output format can be changed using:
+	--output
+		json (default), jsonl (one json object per line), yaml, table or csv.
+		Lists of objects are shown with the most important fields as columns in table and csv.
+
+A part of the result can be selected using:
+	--query
+		A JMESPath expression as used by aws --query, e.g. '[0].id', '[*].name' or
+		"[?state=='ENABLED'].{id: id, name: name}". Supported are fields, indexes [0], slices [1:3],
+		projections [*], * and [], filters [?<expression>] with ==, !=, <, <=, >, >=, &&, || and !,
+		literals 'text' and ` + "`34`" + `, the current value @, new lists [a, b] and objects {key: <expression>},
+		pipes and the functions length, keys, values and contains.
+		A pipe ends a projection: '[*].name | [0]' is the first name.
+
+The result can be formatted using a Go template, instead of the output format, using:
+	--format
+		E.g. '` + "{{`" + `{{range .}}{{.ID}} {{.Name}}{{"\n"}}{{end}}` + "`}}" + `'. The objects have the fields of the
+		CoScale objects (ID, Name, State, ...), other results and the result of --query have the
+		json field names. The functions json, time (a timestamp, with an optional Go time layout),
+		join, upper, lower and trim can be used, e.g. '` + "{{`" + `{{time .Timestamp "2006-01-02 15:04"}}` + "`}}" + `'.
 `
 
 var usageLastLine = `
 Use "coscale-cli [object] <help>" for more information about a command.
 `
 
-// GetConfigPath is used to return the absolut path of the api configuration file
+// configFile is the name of the api configuration file.
+var configFile = "api.conf"
+
+// defaultBaseUrl is the base url for the api when none is configured.
+var defaultBaseUrl = "https://api.coscale.com"
+
+// GetConfigPath is used to return the absolut path of the api configuration file. The first existing file is used from:
+//	$COSCALE_CONFIG
+//	$XDG_CONFIG_HOME/coscale/api.conf (default XDG_CONFIG_HOME: ~/.config)
+//	~/.coscale/api.conf
+//	api.conf or etc/api.conf in the directory of the coscale-cli binary
+// If none exists, the path where a new configuration file should be written is returned with the error.
 func GetConfigPath() (c string, e error) {
-	command := os.Args[0]
-	var configFile, backwardsConfigFile, dir, cmdName, carriageReturn string
-	var err error
-	configFile = "api.conf"
-	backwardsConfigFile = filepath.Join("etc", "api.conf")
-	if runtime.GOOS == "windows" {
-		cmdName = "where"
-		carriageReturn = "\r\n"
-	} else {
-		cmdName = "which"
-		carriageReturn = "\n"
+	if path := os.Getenv("COSCALE_CONFIG"); path != "" {
+		_, err := os.Stat(path)
+		return path, err
 	}
-	// we calculate folder where the command is
 
-	// 1. check if command is in PATH
-	// if we check a command that doesn't exist on linux we get error
-	response, err := GetCommandOutput(cmdName, 2*time.Second, command)
-	if err == nil {
-		command = string(bytes.Split(response, []byte(carriageReturn))[0])
+	candidates := getConfigPaths()
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
 	}
-	err = nil
-	// 2. check if it is a symlink
-	file, err := os.Lstat(command)
-	if err != nil {
-		return "", err
+	if len(candidates) == 0 {
+		return "", errors.New("Could not determine the CLI configuration path")
 	}
-	if file.Mode()&os.ModeSymlink == os.ModeSymlink {
-		// This is a symlink
-		command, err = filepath.EvalSymlinks(command)
-		if err != nil {
-			return "", err
-		}
+	// Write new configuration files in the first location.
+	_, err := os.Stat(candidates[0])
+	return candidates[0], err
+}
+
+// getConfigPaths returns the locations of the configuration file in order of preference.
+func getConfigPaths() []string {
+	var paths []string
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	home := getHomeDir()
+	if configHome == "" && home != "" {
+		configHome = filepath.Join(home, ".config")
 	}
-	// check if config file is in dir
-	dir, err = filepath.Abs(filepath.Dir(command))
-	if err != nil {
-		os.Exit(EXIT_FLAG_ERROR)
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "coscale", configFile))
 	}
-	configPath := filepath.Join(dir, configFile)
-	_, err = os.Stat(configPath)
-	if err == nil {
-		return configPath, nil
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".coscale", configFile))
 	}
-	// try the backwards compatible approach
-	backupConfigPath := filepath.Join(dir, backwardsConfigFile)
-	_, err = os.Stat(backupConfigPath)
-	if err == nil {
-		return backupConfigPath, nil
+
+	// The directory of the binary, where the configuration was written by older versions.
+	if command, err := os.Executable(); err == nil {
+		if command, err = filepath.EvalSymlinks(command); err == nil {
+			dir := filepath.Dir(command)
+			paths = append(paths, filepath.Join(dir, configFile), filepath.Join(dir, "etc", configFile))
+		}
 	}
-	return configPath, err
+	return paths
 }
 
-// GetCommandOutput returns stdout of command as a string
-func GetCommandOutput(command string, timeout time.Duration, arg ...string) ([]byte, error) {
-	var err error
-	var stdOut bytes.Buffer
-	var stdErr bytes.Buffer
-	var c = make(chan []byte)
-	cmd := exec.Command(command, arg...)
-	cmd.Stdout = &stdOut
-	cmd.Stderr = &stdErr
-	if err = cmd.Start(); err != nil {
-		return nil, fmt.Errorf("%s %s", err.Error(), stdErr.String())
-	}
-	go func() {
-		err = cmd.Wait()
-		c <- stdOut.Bytes()
-	}()
-	time.AfterFunc(timeout, func() {
-		cmd.Process.Kill()
-		err = errors.New("Maxruntime exceeded")
-		c <- nil
-	})
-	response := <-c
-	if err != nil {
-		return nil, fmt.Errorf("%s %s", err.Error(), stdErr.String())
+// getHomeDir returns the home directory of the current user.
+func getHomeDir() string {
+	if runtime.GOOS == "windows" {
+		return os.Getenv("USERPROFILE")
 	}
-	return response, nil
+	return os.Getenv("HOME")
 }
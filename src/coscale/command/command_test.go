@@ -0,0 +1,62 @@
+package command
+
+import (
+	"coscale/api"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test the order in which the api configuration sources are used.
+func TestResolveConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coscale-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, env := range []string{"COSCALE_CONFIG", "COSCALE_API_URL", "COSCALE_APP_ID", "COSCALE_ACCESS_TOKEN", "XDG_CONFIG_HOME", "HOME"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+	os.Setenv("HOME", dir)
+
+	// The XDG configuration directory is preferred.
+	path := filepath.Join(dir, ".config", "coscale", "api.conf")
+	os.MkdirAll(filepath.Dir(path), 0700)
+	if err := api.WriteApiConfiguration(path, &api.ApiConfiguration{BaseUrl: "https://file", AccessToken: "t1", AppId: "file"}); err != nil {
+		t.Fatal(err)
+	}
+	config, source, err := ResolveConfiguration("", "", "", "")
+	if err != nil || config.AppId != "file" || source != "profile default in "+path {
+		t.Fatalf("expected config file, found: %v %s %v", config, source, err)
+	}
+
+	// COSCALE_CONFIG overrides the search path.
+	os.Setenv("COSCALE_CONFIG", filepath.Join(dir, "missing.conf"))
+	if _, _, err := ResolveConfiguration("", "", "", ""); err == nil {
+		t.Fatalf("Expected error.")
+	}
+
+	// Environment variables are preferred over the config file.
+	os.Setenv("COSCALE_APP_ID", "env")
+	os.Setenv("COSCALE_ACCESS_TOKEN", "t2")
+	os.Setenv("COSCALE_API_URL", "https://env/")
+	config, source, err = ResolveConfiguration("", "", "", "")
+	if err != nil || config.AppId != "env" || config.BaseUrl != "https://env" || source != "environment variables" {
+		t.Fatalf("expected environment, found: %v %s %v", config, source, err)
+	}
+
+	// But --api-url is preferred over COSCALE_API_URL.
+	config, _, err = ResolveConfiguration("https://flag", "", "", "")
+	if err != nil || config.AppId != "env" || config.BaseUrl != "https://flag" {
+		t.Fatalf("expected the url of the flag, found: %v %v", config, err)
+	}
+
+	// Command line flags are preferred over everything.
+	config, source, err = ResolveConfiguration("", "t3", "flags", "")
+	if err != nil || config.AppId != "flags" || config.BaseUrl != "https://api.coscale.com" || source != "command line flags" {
+		t.Fatalf("expected command line flags, found: %v %s %v", config, source, err)
+	}
+}
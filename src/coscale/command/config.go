@@ -2,9 +2,11 @@ package command
 
 import (
 	"coscale/api"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -18,23 +20,29 @@ var ConfigObject = NewCommand(configObjectName, "config <action> [--<field>='<da
 var ConfigActions = []*Command{
 	{
 		Name:      "check",
-		UsageLine: "config check",
+		UsageLine: "config check [--profile]",
 		Long: `
-Check the CLI configuration.
+Check the CLI configuration and report where it was found: the environment variables
+COSCALE_APP_ID and COSCALE_ACCESS_TOKEN or a profile in the configuration file.
+
+Optional:
+	--profile
+		The profile to check. [default: the current profile]
 `,
 		Run: func(cmd *Command, args []string) {
-			// check for getting the config file path
-			file, err := GetConfigPath()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "No such file: "+file)
-				os.Exit(EXIT_SUCCESS_ERROR)
-			}
-			// check if the configuration file can be parsed
-			config, err := api.ReadApiConfiguration(file)
+			var profile string
+
+			var flags flag.FlagSet
+			flags.StringVar(&profile, "profile", "", "The profile to check")
+			flags.Parse(args)
+
+			// find the configuration from the environment or the config file.
+			config, source, err := ResolveConfiguration("", "", "", profile)
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "Could not parse configuration from "+file)
+				fmt.Fprintln(os.Stderr, "Could not find a valid configuration: "+err.Error())
 				os.Exit(EXIT_SUCCESS_ERROR)
 			}
+			fmt.Fprintln(os.Stderr, "Using the configuration from "+source)
 			// check if we can loggin with this configuration
 			api := api.NewApi(config.BaseUrl, config.AccessToken, config.AppId, false, false)
 			err = api.Login()
@@ -48,10 +56,10 @@ Check the CLI configuration.
 	},
 	{
 		Name:      "set",
-		UsageLine: "config set (--api-url --app-id --access-token)",
+		UsageLine: "config set (--api-url --app-id --access-token) [--profile]",
 		Long: `
-Write the CLI configuration file, a file api.conf will be created in the same directory as
-the coscale-cli binary.
+Write the CLI configuration file. An existing configuration file is updated, else the file
+$COSCALE_CONFIG or $XDG_CONFIG_HOME/coscale/api.conf (default ~/.config/coscale/api.conf) is created.
 
 Mandatory:
 	--api-url
@@ -60,6 +68,10 @@ Mandatory:
 		The application id.
 	--access-token
 		A valid access token for the given application.
+Optional:
+	--profile
+		The name of the profile to write, other profiles are kept. [default: "default"]
+		The first profile that is written becomes the current profile.
 `,
 		Run: func(cmd *Command, args []string) {
 			// get the config path
@@ -69,29 +81,160 @@ Mandatory:
 				os.Exit(EXIT_SUCCESS_ERROR)
 			}
 			// create the config json
-			var baseUrl, accessToken, appId string
+			var baseUrl, accessToken, appId, profile string
 
 			var flags flag.FlagSet
 			flags.StringVar(&baseUrl, "api-url", "https://api.coscale.com", "Base url for the api")
 			flags.StringVar(&appId, "app-id", "", "The application id")
 			flags.StringVar(&accessToken, "access-token", "", "A valid access token for the given application")
+			flags.StringVar(&profile, "profile", api.DefaultProfile, "The name of the profile")
 			flags.Parse(args)
 
-			config := &api.ApiConfiguration{strings.Trim(baseUrl, "/"), accessToken, appId}
+			config := &api.ApiConfiguration{
+				BaseUrl:     strings.Trim(baseUrl, "/"),
+				AccessToken: accessToken,
+				AppId:       appId,
+			}
 
-			if config.BaseUrl == "" || config.AccessToken == "" || config.AppId == "" {
+			if config.BaseUrl == "" || config.AccessToken == "" || config.AppId == "" || profile == "" {
 				cmd.PrintUsage()
 				os.Exit(EXIT_FLAG_ERROR)
 			}
 
-			err := api.WriteApiConfiguration(path, config)
+			// keep the other profiles of an existing configuration file.
+			configurations, err := api.ReadApiConfigurations(path)
+			if os.IsNotExist(err) {
+				configurations = api.NewApiConfigurations()
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not read configuration from %s: %s\n", path, err)
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+			configurations.Set(profile, config)
+
+			// write the json to the file
+			writeConfigurations(path, configurations)
+			fmt.Fprintln(os.Stderr, "Successfully wrote CLI configuration file "+path)
+			os.Exit(EXIT_SUCCESS)
+		},
+	},
+	{
+		Name:      "use",
+		UsageLine: "config use (--profile)",
+		Long: `
+Select the profile that is used when no --profile is provided.
+
+Mandatory:
+	--profile
+		The name of the profile.
+`,
+		Run: func(cmd *Command, args []string) {
+			var profile string
+
+			var flags flag.FlagSet
+			flags.StringVar(&profile, "profile", "", "The name of the profile")
+			flags.Parse(args)
+
+			if profile == "" {
+				cmd.PrintUsage()
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+
+			path, configurations := readConfigurations()
+			if err := configurations.Use(profile); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(EXIT_SUCCESS_ERROR)
+			}
+			writeConfigurations(path, configurations)
+			fmt.Fprintf(os.Stderr, "Now using profile %s.\n", profile)
+			os.Exit(EXIT_SUCCESS)
+		},
+	},
+	{
+		Name:      "list",
+		UsageLine: "config list",
+		Long: `
+List the profiles in the CLI configuration file, the access tokens are not shown.
+`,
+		Run: func(cmd *Command, args []string) {
+			_, configurations := readConfigurations()
+
+			type profileInfo struct {
+				Name    string `json:"name"`
+				BaseUrl string `json:"baseurl"`
+				AppId   string `json:"appid"`
+				Current bool   `json:"current"`
+			}
+			profiles := make([]profileInfo, 0, len(configurations.Profiles))
+			for _, name := range configurations.Names() {
+				config := configurations.Profiles[name]
+				profiles = append(profiles, profileInfo{name, config.BaseUrl, config.AppId, name == configurations.Current})
+			}
+			result, err := json.MarshalIndent(profiles, "", " ")
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "Failed to write the configuration file.")
+				fmt.Fprintln(os.Stderr, err.Error())
 				os.Exit(EXIT_SUCCESS_ERROR)
 			}
-			// write the json to the file
-			fmt.Fprintln(os.Stderr, "Successfully wrote CLI configuration file.")
+			fmt.Fprintln(os.Stdout, string(result))
 			os.Exit(EXIT_SUCCESS)
 		},
 	},
+	{
+		Name:      "delete",
+		UsageLine: "config delete (--profile)",
+		Long: `
+Delete a profile from the CLI configuration file.
+
+Mandatory:
+	--profile
+		The name of the profile.
+`,
+		Run: func(cmd *Command, args []string) {
+			var profile string
+
+			var flags flag.FlagSet
+			flags.StringVar(&profile, "profile", "", "The name of the profile")
+			flags.Parse(args)
+
+			if profile == "" {
+				cmd.PrintUsage()
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+
+			path, configurations := readConfigurations()
+			if err := configurations.Delete(profile); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(EXIT_SUCCESS_ERROR)
+			}
+			writeConfigurations(path, configurations)
+			fmt.Fprintf(os.Stderr, "Successfully deleted profile %s.\n", profile)
+			os.Exit(EXIT_SUCCESS)
+		},
+	},
+}
+
+// readConfigurations reads the profiles from the CLI configuration file or exits the process.
+func readConfigurations() (string, *api.ApiConfigurations) {
+	path, err := GetConfigPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "No such file: "+path)
+		os.Exit(EXIT_SUCCESS_ERROR)
+	}
+	configurations, err := api.ReadApiConfigurations(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not parse configuration from "+path)
+		os.Exit(EXIT_SUCCESS_ERROR)
+	}
+	return path, configurations
+}
+
+// writeConfigurations writes the profiles to the CLI configuration file or exits the process.
+func writeConfigurations(path string, configurations *api.ApiConfigurations) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create the configuration directory.")
+		os.Exit(EXIT_SUCCESS_ERROR)
+	}
+	if err := api.WriteApiConfigurations(path, configurations); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write the configuration file.")
+		os.Exit(EXIT_SUCCESS_ERROR)
+	}
 }
@@ -2,9 +2,18 @@ package command
 
 import (
 	"bufio"
+	"context"
 	"coscale/api"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 var dataObjectName = "data"
@@ -29,8 +38,10 @@ Mandatory:
 Optional:
 	--start
 		The start timestamp in seconds ago(negative values) or unix timestamp (positive values). [default: 0]
+		Also accepted: a duration ago such as -2h, 90m or 7d (units s, m, h, d and w), now, today,
+		yesterday or a time such as 2017-05-18T12:00:00Z or 2017-05-18 12:00 (local time).
 	--stop
-		The stop timestamp in seconds ago(negative values) or unix timestamp (positive values). [default: 0]
+		The stop timestamp in the format of --start. [default: 0]
 	--aggregator
 		The data aggregator(AVG, MIN, MAX) used to specify vertical aggregation of timeseries. [default: AVG]
 	--viewtype
@@ -60,14 +71,13 @@ Optional:
 		Boolean that indicates if the aggregated value over all subjectIds should be returned. [default: false]
 `,
 		Run: func(cmd *Command, args []string) {
-			var subjectIds, aggregator, viewType, dimensionsSpecs string
+			var subjectIds, aggregator, viewType, dimensionsSpecs, startExpr, stopExpr string
 			var aggregateSubjects bool
-			var start, stop int
 			var id int64
 			cmd.Flag.Usage = func() { cmd.PrintUsage() }
 			cmd.Flag.Int64Var(&id, "id", -1, "Unique identifier for metric.")
-			cmd.Flag.IntVar(&start, "start", 0, "The start timestamp in seconds ago.")
-			cmd.Flag.IntVar(&stop, "stop", 0, "The stop timestamp in seconds ago.")
+			cmd.Flag.StringVar(&startExpr, "start", "0", "The start timestamp in seconds ago.")
+			cmd.Flag.StringVar(&stopExpr, "stop", "0", "The stop timestamp in seconds ago.")
 			cmd.Flag.StringVar(&subjectIds, "subjectIds", DEFAULT_STRING_FLAG_VALUE, "The subject string.")
 			cmd.Flag.StringVar(&aggregator, "aggregator", "AVG", "The data aggregator (AVG, MIN, MAX).")
 			cmd.Flag.StringVar(&viewType, "viewType", "DEFAULT", "Defines how the data will be shown.")
@@ -78,12 +88,108 @@ Optional:
 				cmd.PrintUsage()
 				os.Exit(EXIT_FLAG_ERROR)
 			}
+			now := time.Now()
+			start, stop := parseTimeFlag("start", startExpr, now), parseTimeFlag("stop", stopExpr, now)
 			cmd.PrintResult(cmd.Capi.GetData(start, stop, id, subjectIds, aggregator, viewType, dimensionsSpecs, aggregateSubjects))
 		},
 	},
+	{
+		Name:      "export",
+		UsageLine: `data export (--metric --subjectIds --out) [--start --stop --window --file-format --time-format --aggregator --viewtype --dimensionsSpecs --aggregateSubjects]`,
+		Long: `
+Export data from the datastore to a file with a row for every value, e.g. to load the data
+in a notebook. The time range is split into windows, every window is retrieved separately.
+
+The flags for data export action are:
+Mandatory:
+	--metric
+		The id or name of the metric.
+	--subjectIds
+		The subject string eg. s1 for server 1, g2 for servergroup 2, a for application.
+	--out
+		The file for the data, "-" writes the data to stdout.
+Optional:
+	--start
+		The start time in the format of data get, e.g. -2h, 7d, yesterday or a unix timestamp. [default: -1d]
+	--stop
+		The stop time in the format of data get. [default: now]
+	--window
+		The maximum time range of a single API call, e.g. 6h. [default: 24h]
+	--file-format
+		The format of the file: csv or columns. The columns format is a json object with an
+		array for every column, e.g. for a data frame. [default: columns for a .json file, else csv]
+	--time-format
+		The format of the time column: rfc3339 (UTC) or unix. [default: rfc3339]
+	--aggregator, --viewtype, --dimensionsSpecs, --aggregateSubjects
+		See data get.
+
+The columns are time, metric, subject, dimensions and value. The dimensions are the dimension
+specification of the series, the value of a histogram is its json.
+`,
+		Run: func(cmd *Command, args []string) {
+			var metric, subjectIds, out, startExpr, stopExpr, fileFormat, timeFormat string
+			var aggregator, viewType, dimensionsSpecs string
+			var aggregateSubjects bool
+			var window time.Duration
+			cmd.Flag.Usage = func() { cmd.PrintUsage() }
+			cmd.Flag.StringVar(&metric, "metric", DEFAULT_STRING_FLAG_VALUE, "The id or name of the metric.")
+			cmd.Flag.StringVar(&subjectIds, "subjectIds", DEFAULT_STRING_FLAG_VALUE, "The subject string.")
+			cmd.Flag.StringVar(&out, "out", DEFAULT_STRING_FLAG_VALUE, "The file for the data.")
+			cmd.Flag.StringVar(&startExpr, "start", "-1d", "The start time.")
+			cmd.Flag.StringVar(&stopExpr, "stop", "now", "The stop time.")
+			cmd.Flag.DurationVar(&window, "window", 24*time.Hour, "The maximum time range of a single API call.")
+			cmd.Flag.StringVar(&fileFormat, "file-format", "", "The format of the file: csv or columns.")
+			cmd.Flag.StringVar(&timeFormat, "time-format", "rfc3339", "The format of the time column: rfc3339 or unix.")
+			cmd.Flag.StringVar(&aggregator, "aggregator", "AVG", "The data aggregator (AVG, MIN, MAX).")
+			cmd.Flag.StringVar(&viewType, "viewType", "DEFAULT", "Defines how the data will be shown.")
+			cmd.Flag.StringVar(&dimensionsSpecs, "dimensionsSpecs", "[]", "JSON containing ids of the dimensions.")
+			cmd.Flag.BoolVar(&aggregateSubjects, "aggregateSubjects", false, "Boolean that indicates if the aggregated value over all subjectIds should be returned.")
+			cmd.ParseArgs(args)
+			if metric == DEFAULT_STRING_FLAG_VALUE || subjectIds == DEFAULT_STRING_FLAG_VALUE || out == DEFAULT_STRING_FLAG_VALUE {
+				cmd.PrintUsage()
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+			format, err := dataFileFormat(out, fileFormat)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+			if timeFormat != "rfc3339" && timeFormat != "unix" {
+				fmt.Fprintln(os.Stderr, "Unknown time format, use rfc3339 or unix.")
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+			now := time.Now()
+			start, stop := parseTimeFlag("start", startExpr, now), parseTimeFlag("stop", stopExpr, now)
+			if api.UnixTime(start, now) >= api.UnixTime(stop, now) {
+				fmt.Fprintln(os.Stderr, "The start should be before the stop.")
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+
+			ctx := context.Background()
+			metricID, err := strconv.ParseInt(metric, 10, 64)
+			if err != nil {
+				if metricID, err = cmd.Capi.NewNameCache(ctx).ID("metric", metric); err != nil {
+					cmd.PrintResult("", err)
+				}
+			}
+			series, err := cmd.Capi.GetDataSeriesContext(ctx, start, stop, window, metricID, subjectIds, aggregator, viewType, dimensionsSpecs, aggregateSubjects)
+			if err != nil {
+				cmd.PrintResult("", err)
+			}
+			rows, err := writeDataFile(out, series, format, timeFormat)
+			if err != nil {
+				cmd.PrintResult("", err)
+			}
+			if out == "-" {
+				os.Exit(EXIT_SUCCESS)
+			}
+			result, _ := json.MarshalIndent(map[string]interface{}{"file": out, "series": len(series), "rows": rows}, "", " ")
+			cmd.PrintResult(string(result), nil)
+		},
+	},
 	{
 		Name:      "insert",
-		UsageLine: `data insert (--data <data> | --stdin)`,
+		UsageLine: `data insert (--data <data> | --stdin | --file <file> --metric --subject) [--validate --parallel --keep-going --raw-samples --percentile-width --spool --spool-max-size --spool-max-age --batch-size --flush-interval --format --time-col --value-col --dimension-cols --delimiter --subject-tag --precision --create]`,
 		Long: `
 Insert data for metrics into the datastore.
 
@@ -104,6 +210,12 @@ Optional:
 		Sending multiple data entries is possible by using semicolon as separator.
 			eg: --data="M1:S100:-60:1.2;M2:S100:0:2"
 
+		The metric and server can also be referenced by name, the names are looked up once:
+			"metric=<metric name>:server=<server name>:<time>:<value/s>"
+			eg: --data='metric="Response time":server="web-1":-60:1.2'
+		Names with a colon or semicolon are quoted with double quotes. Use A as subject for the application:
+			eg: --data='metric="Response time":A:-60:1.2'
+
 		The time is formatted as follows:
 		    Positive numbers are interpreted as unix timestamps in seconds.
 		    Zero is interpreted as the current time.
@@ -112,59 +224,536 @@ Optional:
 			we want to show the total number of queued messages, but we also want to be able
 			to split these into the number of queued messages per queue.
 			eg: --data='M1:S1:-60:1.3:{"Queue":"q1","Data Center":"data center 1"};M2:S1:-60:1.2'
+	--validate
+		Check the data against the definitions of its metrics before it is inserted, a batch with
+		issues is not inserted, see "data validate". [default: false]
+	--parallel
+		The number of batches of --data and --file that are sent at the same time. The data is
+		split into batches per subject and when it exceeds the maximum upload size. [default: 1]
+	--keep-going
+		Continue with the next batches when a batch failed, by default the batches that were not
+		sent yet are skipped. With multiple batches a summary with the outcome of every batch is
+		printed. [default: false]
+	--raw-samples
+		The HISTOGRAM values of --data and --stdin are lists of raw samples, e.g. latencies, the
+		histogram is computed by the CLI. The samples can not be negative. Values that are
+		already a histogram are not changed.
+			eg: --data="M1:S1:-60:[12.5,15,30.2,11]" --raw-samples
+		[default: false]
+	--percentile-width
+		With --raw-samples, the distance between the percentiles of the histogram, 100 should be
+		a multiple of the width. [default: 10]
+	--spool
+		A directory where the batches are stored that could not be sent because the API could
+		not be reached, e.g. during a network outage. The spooled batches are sent, oldest first,
+		before the first batch of a next insert with the same --spool, or by "data flush".
+	--spool-max-size
+		The maximum size in bytes of the spooled batches, the oldest batches are dropped when
+		it is exceeded. [default: 104857600]
+	--spool-max-age
+		The time after which a spooled batch is dropped, e.g. 24h. [default: 168h]
 	--stdin
-		Specify if the data will be interted on stdin. [default: false]
+		Read the data from stdin until the end of the input, every line contains data in the
+		format of --data. Lines with an error are reported on stderr and skipped. The data is
+		sent when --batch-size or --flush-interval is reached, nothing is printed on stdout,
+		e.g. for a collector: my-collector | coscale-cli data insert --stdin
+		With --format influx the lines are in the InfluxDB line protocol.
+		The exit code is 1 if a line or a batch failed. [default: false]
+	--batch-size
+		With --stdin, the size in bytes of the data that is sent at once. [default: 1048576]
+	--flush-interval
+		With --stdin, the maximum time data is kept before it is sent, e.g. 10s or 1m. [default: 10s]
+	--file
+		Insert the data in a file, "-" reads the file from stdin. The first row of a CSV file
+		contains the column names, columns are referenced by name or by their 1-based index.
+		Rows without a value are skipped.
+			eg: --file data.csv --metric "Response time" --subject web-1
+	--format
+		The format of the file: csv or influx, for --stdin: influx. [default: csv]
+	--metric
+		The id or name of the metric for the data in the CSV file.
+	--subject
+		The subject for the data in the file: A for the application, S<server id> or a server name.
+		For the influx format the subject of the lines without --subject-tag. [default: A]
+	--time-col
+		The column with the time: a unix timestamp, negative seconds ago or a time such as
+		2017-05-18T12:00:00Z or 2017-05-18 12:00:00 (local time). [default: time]
+	--value-col
+		The column with the value. [default: value]
+	--dimension-cols
+		Comma separated columns with dimension values, the column names are the dimension names.
+	--delimiter
+		The field delimiter of the CSV file. [default: ,]
+
+	The influx format is the InfluxDB line protocol, every numeric field is a data point:
+		<measurement>[,<tag>=<value>...] <field>=<value>[,<field>=<value>...] [<timestamp>]
+		eg: cpu,host=web-1,core=0 usage_idle=92.5,processes=210i 1465839830100400200
+	The metric name is <measurement>.<field>, or <measurement> for a field named value. The tags
+	are the dimension values, the tag names are the dimension names. String and boolean fields
+	are skipped.
+	--subject-tag
+		The tag with the server name of the data, e.g. host. The tag is not used as dimension.
+	--precision
+		The unit of the timestamps: ns, us, ms or s. [default: ns]
+	--create
+		Create the metrics and dimensions that do not exist. The metrics are created with data
+		type DOUBLE and a period of 60 seconds. [default: false]
 `,
 		Run: func(cmd *Command, args []string) {
-			var err error
-			var datapoint, data string
-			var stdin bool
+			runDataInsert(cmd, args, false)
+		},
+	},
+	{
+		Name:      "validate",
+		UsageLine: `data validate (--data <data> | --stdin | --file <file> --metric --subject) [--raw-samples --percentile-width --batch-size --flush-interval --format --time-col --value-col --dimension-cols --delimiter --subject-tag --precision]`,
+		Long: `
+Check data against the definitions of its metrics, the data is never inserted.
 
-			cmd.Flag.Usage = func() { cmd.PrintUsage() }
+The flags are the flags of "data insert", see "data insert help". The following is checked:
+	The value is a number for DOUBLE metrics and a histogram for HISTOGRAM metrics.
+	Unix timestamps are a multiple of the period of the metric, the relative times are
+	aligned by the API.
+	The dimensions are dimensions of the metric.
+	The subject is S<server id> for SERVER metrics and A for APPLICATION metrics.
 
-			cmd.Flag.StringVar(&datapoint, "datapoint", DEFAULT_STRING_FLAG_VALUE, "")
-			cmd.Flag.StringVar(&data, "data", DEFAULT_STRING_FLAG_VALUE, "")
-			cmd.Flag.BoolVar(&stdin, "stdin", false, "Specify if the data will be interted on stdin.")
+The issues are printed and the exit code is 1 if the data does not match.
+`,
+		Run: func(cmd *Command, args []string) {
+			runDataInsert(cmd, args, true)
+		},
+	},
+	{
+		Name:      "flush",
+		UsageLine: `data flush (--spool) [--spool-max-size --spool-max-age]`,
+		Long: `
+Send the batches that were spooled by "data insert --spool", oldest first.
+
+The flags for data flush action are:
+Mandatory:
+	--spool
+		The spool directory used by data insert.
+Optional:
+	--spool-max-size
+		The maximum size in bytes of the spooled batches, the oldest batches are dropped when
+		it is exceeded. [default: 104857600]
+	--spool-max-age
+		The time after which a spooled batch is dropped, e.g. 24h. [default: 168h]
+
+The flush stops at the first batch that could not be sent because the API could not be
+reached. Batches that are refused by the API are kept with the extension .rejected.
+`,
+		Run: func(cmd *Command, args []string) {
+			var spoolDir string
+			var spoolMaxSize int64
+			var spoolMaxAge time.Duration
+			cmd.Flag.Usage = func() { cmd.PrintUsage() }
+			cmd.Flag.StringVar(&spoolDir, "spool", DEFAULT_STRING_FLAG_VALUE, "The directory for the batches that could not be sent.")
+			cmd.Flag.Int64Var(&spoolMaxSize, "spool-max-size", defaultSpoolMaxSize, "The maximum size in bytes of the spooled batches.")
+			cmd.Flag.DurationVar(&spoolMaxAge, "spool-max-age", defaultSpoolMaxAge, "The time after which a spooled batch is dropped.")
 			cmd.ParseArgs(args)
+			if spoolDir == DEFAULT_STRING_FLAG_VALUE {
+				cmd.PrintUsage()
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+			spool := api.NewSpool(spoolDir, cmd.Capi.AppID, spoolMaxSize, spoolMaxAge)
+			result, err := cmd.Capi.ReplaySpool(context.Background(), spool)
+			if err != nil {
+				cmd.PrintResult("", err)
+			}
+			cmd.PrintResult(spoolResultJson(result))
+		},
+	},
+}
 
-			if stdin {
-				message := fmt.Sprintf("%s\n\nPlease insert the data followed by a new line to submit...\n\n", cmd.Long)
-				fmt.Fprintln(os.Stdout, message)
+// runDataInsert runs data insert, if validateOnly is true the data is validated and not inserted.
+func runDataInsert(cmd *Command, args []string, validateOnly bool) {
+	var err error
+	var datapoint, data string
+	var stdin bool
 
-				in := bufio.NewReader(os.Stdin)
-				data, err = in.ReadString('\n')
+	cmd.Flag.Usage = func() { cmd.PrintUsage() }
 
-				if err != nil {
-					cmd.PrintResult("", err)
-				}
+	cmd.Flag.StringVar(&datapoint, "datapoint", DEFAULT_STRING_FLAG_VALUE, "")
+	cmd.Flag.StringVar(&data, "data", DEFAULT_STRING_FLAG_VALUE, "")
+	cmd.Flag.BoolVar(&stdin, "stdin", false, "Read the data line by line from stdin.")
+	var validate bool
+	cmd.Flag.BoolVar(&validate, "validate", false, "Check the data against the definitions of its metrics.")
+	var parallel int
+	var keepGoing bool
+	cmd.Flag.IntVar(&parallel, "parallel", 1, "The number of batches that are sent at the same time.")
+	cmd.Flag.BoolVar(&keepGoing, "keep-going", false, "Continue with the next batches when a batch failed.")
+	var rawSamples bool
+	var percentileWidth int
+	cmd.Flag.BoolVar(&rawSamples, "raw-samples", false, "The histogram values are lists of raw samples.")
+	cmd.Flag.IntVar(&percentileWidth, "percentile-width", 10, "The distance between the percentiles of the histogram.")
+	var batchSize int
+	var flushInterval time.Duration
+	cmd.Flag.IntVar(&batchSize, "batch-size", 1024*1024, "The size in bytes of the data that is sent at once.")
+	cmd.Flag.DurationVar(&flushInterval, "flush-interval", 10*time.Second, "The maximum time data is kept before it is sent.")
+	var file, format, metric, subject, timeColumn, valueColumn, dimensionColumns, delimiter string
+	cmd.Flag.StringVar(&file, "file", DEFAULT_STRING_FLAG_VALUE, "The file with the data.")
+	cmd.Flag.StringVar(&format, "format", DEFAULT_STRING_FLAG_VALUE, "The format of the file: csv or influx.")
+	cmd.Flag.StringVar(&metric, "metric", DEFAULT_STRING_FLAG_VALUE, "The id or name of the metric.")
+	cmd.Flag.StringVar(&subject, "subject", DEFAULT_STRING_FLAG_VALUE, "The subject: A, S<server id> or a server name.")
+	cmd.Flag.StringVar(&timeColumn, "time-col", "time", "The column with the time.")
+	cmd.Flag.StringVar(&valueColumn, "value-col", "value", "The column with the value.")
+	cmd.Flag.StringVar(&dimensionColumns, "dimension-cols", "", "Comma separated columns with dimension values.")
+	cmd.Flag.StringVar(&delimiter, "delimiter", ",", "The field delimiter.")
+	var subjectTag, precision string
+	var create bool
+	cmd.Flag.StringVar(&subjectTag, "subject-tag", "", "The tag with the server name.")
+	cmd.Flag.StringVar(&precision, "precision", "ns", "The unit of the timestamps: ns, us, ms or s.")
+	cmd.Flag.BoolVar(&create, "create", false, "Create the metrics and dimensions that do not exist.")
+	var spoolDir string
+	var spoolMaxSize int64
+	var spoolMaxAge time.Duration
+	cmd.Flag.StringVar(&spoolDir, "spool", "", "The directory for the batches that could not be sent.")
+	cmd.Flag.Int64Var(&spoolMaxSize, "spool-max-size", defaultSpoolMaxSize, "The maximum size in bytes of the spooled batches.")
+	cmd.Flag.DurationVar(&spoolMaxAge, "spool-max-age", defaultSpoolMaxAge, "The time after which a spooled batch is dropped.")
+	cmd.ParseArgs(args)
+	// Validating the data has no side effects, metrics are not created.
+	create = create && !validateOnly
+	if parallel < 1 {
+		fmt.Fprintln(os.Stderr, "The number of parallel uploads should be at least 1")
+		os.Exit(EXIT_FLAG_ERROR)
+	}
+	if rawSamples && !api.ValidPercentileWidth(percentileWidth) {
+		fmt.Fprintln(os.Stderr, "Invalid percentile width, 100 should be a multiple of the width.")
+		os.Exit(EXIT_FLAG_ERROR)
+	}
+
+	insert := cmd.Capi.InsertData
+	if spoolDir != "" && !validateOnly {
+		insert = spoolingInsert(cmd.Capi, api.NewSpool(spoolDir, cmd.Capi.AppID, spoolMaxSize, spoolMaxAge), os.Stderr)
+	}
+	if validate || validateOnly {
+		insert = validatingInsert(newMetricDefinitions(context.Background(), cmd.Capi), insert, validateOnly)
+	}
+
+	// newConverter creates the converter for the influx format.
+	newConverter := func() *influxConverter {
+		unit, ok := influxPrecisions[precision]
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Invalid precision, use ns, us, ms or s.")
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		ctx := context.Background()
+		subjectID := "A"
+		if subject != DEFAULT_STRING_FLAG_VALUE {
+			if subjectID, err = resolveSubject(cmd.Capi.NewNameCache(ctx), subject); err != nil {
+				cmd.PrintResult("", err)
 			}
+		}
+		return newInfluxConverter(ctx, cmd.Capi, unit, subjectTag, subjectID, create)
+	}
 
-			if datapoint == DEFAULT_STRING_FLAG_VALUE && data == DEFAULT_STRING_FLAG_VALUE {
-				cmd.PrintUsage()
-				os.Exit(EXIT_FLAG_ERROR)
+	if file != DEFAULT_STRING_FLAG_VALUE && format == "influx" {
+		callsData, err := readInfluxData(file, newConverter().convert)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+		cmd.printBatchResults(insertBatches(insert, callsData, parallel, keepGoing))
+	}
+
+	if file != DEFAULT_STRING_FLAG_VALUE {
+		if metric == DEFAULT_STRING_FLAG_VALUE || subject == DEFAULT_STRING_FLAG_VALUE {
+			cmd.PrintUsage()
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		if format != DEFAULT_STRING_FLAG_VALUE && format != "csv" {
+			fmt.Fprintf(os.Stderr, "Unknown file format %s, use csv or influx\n", format)
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		if utf8.RuneCountInString(delimiter) != 1 {
+			fmt.Fprintln(os.Stderr, "The delimiter should be a single character")
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		mapping := &api.CSVMapping{TimeColumn: timeColumn, ValueColumn: valueColumn, Comma: []rune(delimiter)[0]}
+		if dimensionColumns != "" {
+			mapping.DimensionColumns = strings.Split(dimensionColumns, ",")
+		}
+		names := cmd.Capi.NewNameCache(context.Background())
+		if mapping.MetricID, mapping.SubjectID, err = resolveDataRefs(names, metric, subject); err != nil {
+			cmd.PrintResult("", err)
+		}
+		callsData, err := readCSVData(file, mapping)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+		cmd.printBatchResults(insertBatches(insert, callsData, parallel, keepGoing))
+	}
+
+	if stdin {
+		parse := dataLineParser(cmd.Capi.NewNameCache(context.Background()).ID)
+		if format == "influx" {
+			parse = newConverter().convert
+		} else if format != DEFAULT_STRING_FLAG_VALUE {
+			fmt.Fprintf(os.Stderr, "Unknown format %s for --stdin, use influx\n", format)
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		if rawSamples && format != "influx" {
+			parse = rawSamplesParser(parse, percentileWidth)
+		}
+		if failed := streamData(os.Stdin, os.Stderr, ignoreResult(insert), parse, batchSize, flushInterval); failed {
+			os.Exit(EXIT_SUCCESS_ERROR)
+		}
+		os.Exit(EXIT_SUCCESS)
+	}
+
+	if datapoint == DEFAULT_STRING_FLAG_VALUE && data == DEFAULT_STRING_FLAG_VALUE {
+		cmd.PrintUsage()
+		os.Exit(EXIT_FLAG_ERROR)
+	}
+
+	timeInSecAgo := false
+	if data == DEFAULT_STRING_FLAG_VALUE {
+		timeInSecAgo = true
+		data = datapoint
+	}
+
+	if rawSamples {
+		if data, err = api.ExpandRawSamples(data, percentileWidth); err != nil {
+			cmd.PrintResult("", err)
+		}
+	}
+
+	// Replace the metric and server names by their ids.
+	data, err = api.ResolveDataPointNames(data, cmd.Capi.NewNameCache(context.Background()).ID)
+	if err != nil {
+		cmd.PrintResult("", err)
+	}
+
+	// ParseDataPoint could return data for multiple calls for same metricIDs with different subjectID
+	callsData, err := api.ParseDataPoint(data, timeInSecAgo)
+	if err != nil {
+		cmd.PrintResult("", err)
+	}
+	cmd.printBatchResults(insertBatches(insert, callsData, parallel, keepGoing))
+}
+
+// The default limits of a spool.
+const (
+	defaultSpoolMaxSize = 100 * 1024 * 1024
+	defaultSpoolMaxAge  = 7 * 24 * time.Hour
+)
+
+// sendData inserts data in batches that don't exceed MaxUploadSize using insert. Errors are
+// reported on errOut, false is returned if there were errors.
+func sendData(data []*api.ApiData, insert func(map[string][]*api.ApiData) error, errOut io.Writer) bool {
+	buffer := api.NewDataBuffer()
+	for _, apiData := range data {
+		buffer.Add(apiData)
+	}
+	callsData, err := buffer.Flush()
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return false
+	}
+	ok := true
+	for _, callData := range callsData {
+		if err := insert(callData); err != nil {
+			fmt.Fprintln(errOut, GetErrorJson(err))
+			ok = false
+		}
+	}
+	return ok
+}
+
+// insertFunc returns a function that inserts data using the API of the command.
+func (c *Command) insertFunc() func(map[string][]*api.ApiData) error {
+	return func(callData map[string][]*api.ApiData) error {
+		_, err := c.Capi.InsertData(callData)
+		return err
+	}
+}
+
+// parseTimeFlag parses the time expression of a flag, see api.ParseTime. The process exits if the
+// expression is invalid.
+func parseTimeFlag(name, expression string, now time.Time) int {
+	t, err := api.ParseTime(expression, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--%s: %s\n", name, err)
+		os.Exit(EXIT_FLAG_ERROR)
+	}
+	return t
+}
+
+// ignoreResult converts an insert function for streamData, which only needs the error.
+func ignoreResult(insert func(map[string][]*api.ApiData) (string, error)) func(map[string][]*api.ApiData) error {
+	return func(callData map[string][]*api.ApiData) error {
+		_, err := insert(callData)
+		return err
+	}
+}
+
+// spoolingInsert returns a function that inserts data and adds it to spool if the API could not be
+// reached. Before the first insert the spooled batches are sent, the outcome is reported on errOut.
+func spoolingInsert(capi *api.Api, spool *api.Spool, errOut io.Writer) func(map[string][]*api.ApiData) (string, error) {
+	var replay sync.Once
+	return func(callData map[string][]*api.ApiData) (string, error) {
+		replay.Do(func() {
+			result, err := capi.ReplaySpool(context.Background(), spool)
+			if err != nil {
+				fmt.Fprintln(errOut, GetErrorJson(err))
+			}
+			if result != nil && (result.Sent > 0 || result.Rejected > 0 || result.Dropped > 0) {
+				if summary, err := spoolResultJson(result); err == nil {
+					fmt.Fprintln(errOut, summary)
+				}
 			}
+		})
+		return capi.InsertDataSpooled(context.Background(), callData, spool)
+	}
+}
 
-			timeInSecAgo := false
-			if data == DEFAULT_STRING_FLAG_VALUE {
-				timeInSecAgo = true
-				data = datapoint
+// spoolResultJson formats the result of a spool replay.
+func spoolResultJson(result *api.SpoolResult) (string, error) {
+	b, err := json.Marshal(result)
+	return string(b), err
+}
+
+// subjectPattern matches the subjects that are not a server name.
+var subjectPattern = regexp.MustCompile(`^(A|S[0-9]+)$`)
+
+// resolveDataRefs returns the id of a metric given by id or name and the subject for A, S<id> or
+// a server name.
+func resolveDataRefs(names *api.NameCache, metric, subject string) (int64, string, error) {
+	metricID, err := strconv.ParseInt(metric, 10, 64)
+	if err != nil {
+		if metricID, err = names.ID("metric", metric); err != nil {
+			return 0, "", err
+		}
+	}
+	subjectID, err := resolveSubject(names, subject)
+	if err != nil {
+		return 0, "", err
+	}
+	return metricID, subjectID, nil
+}
+
+// resolveSubject returns the subject for A, S<id> or a server name.
+func resolveSubject(names *api.NameCache, subject string) (string, error) {
+	if subjectPattern.MatchString(subject) {
+		return subject, nil
+	}
+	serverID, err := names.ID("server", subject)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("S%d", serverID), nil
+}
+
+// dataLineParser returns a function that parses a line in the format of --data, the metric and
+// server names are resolved using resolve.
+func dataLineParser(resolve func(objectName, name string) (int64, error)) func(line string) ([]*api.ApiData, error) {
+	return func(line string) ([]*api.ApiData, error) {
+		data, err := api.ResolveDataPointNames(line, resolve)
+		if err != nil {
+			return nil, err
+		}
+		return api.SplitDataPoints(data, false)
+	}
+}
+
+// rawSamplesParser returns a function that replaces the raw samples in a line by their histogram
+// before it is parsed by parse.
+func rawSamplesParser(parse func(line string) ([]*api.ApiData, error), width int) func(line string) ([]*api.ApiData, error) {
+	return func(line string) ([]*api.ApiData, error) {
+		line, err := api.ExpandRawSamples(line, width)
+		if err != nil {
+			return nil, err
+		}
+		return parse(line)
+	}
+}
+
+// readCSVData reads a CSV file with data, "-" reads stdin.
+func readCSVData(file string, mapping *api.CSVMapping) ([]map[string][]*api.ApiData, error) {
+	if file == "-" {
+		return api.ParseCSVData(os.Stdin, mapping)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	callsData, err := api.ParseCSVData(f, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return callsData, nil
+}
+
+// streamData reads data line by line from r until the end of the input, converts the lines using
+// parse and inserts the data using insert. The data is inserted when the buffered data exceeds batchSize bytes or flushInterval passed since
+// the first buffered line. Errors are reported on errOut, true is returned if there were errors.
+func streamData(r io.Reader, errOut io.Writer, insert func(map[string][]*api.ApiData) error,
+	parse func(line string) ([]*api.ApiData, error), batchSize int, flushInterval time.Duration) bool {
+
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), api.MaxUploadSize)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		readErr <- scanner.Err()
+		close(lines)
+	}()
+
+	failed := false
+	buffer := api.NewDataBuffer()
+	flush := func() {
+		callsData, err := buffer.Flush()
+		if err != nil {
+			fmt.Fprintln(errOut, err.Error())
+			failed = true
+		}
+		for _, callData := range callsData {
+			if err := insert(callData); err != nil {
+				fmt.Fprintln(errOut, GetErrorJson(err))
+				failed = true
 			}
+		}
+	}
 
-			// ParseDataPoint could return data for multiple calls for same metricIDs with different subjectID
-			callsData, err := api.ParseDataPoint(data, timeInSecAgo)
+	var timer <-chan time.Time
+	number := 0
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				flush()
+				if err := <-readErr; err != nil {
+					fmt.Fprintln(errOut, err.Error())
+					failed = true
+				}
+				return failed
+			}
+			number++
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			data, err := parse(line)
 			if err != nil {
-				cmd.PrintResult("", err)
+				fmt.Fprintf(errOut, "line %d: %s\n", number, err)
+				failed = true
+				continue
 			}
-			var result string
-			var resErr error
-			// if datapoint contain multiple subject id, then we will have multiple api calls
-			for _, callData := range callsData {
-				result, resErr = cmd.Capi.InsertData(callData)
-				if resErr != nil {
-					break
-				}
+			for _, apiData := range data {
+				buffer.Add(apiData)
 			}
-			cmd.PrintResult(result, resErr)
-		},
-	},
+			if buffer.Size() >= batchSize {
+				flush()
+				timer = nil
+			} else if timer == nil {
+				timer = time.After(flushInterval)
+			}
+		case <-timer:
+			flush()
+			timer = nil
+		}
+	}
 }
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"coscale/api"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dataFileFormats are the file formats of data export.
+var dataFileFormats = map[string]bool{"csv": true, "columns": true}
+
+// dataColumns are the columns of an exported data file.
+var dataColumns = []string{"time", "metric", "subject", "dimensions", "value"}
+
+// dataFileFormat returns the format of an exported data file: the format flag or else the format
+// for the extension of the file.
+func dataFileFormat(file, format string) (string, error) {
+	if format == "" {
+		format = "csv"
+		if strings.ToLower(filepath.Ext(file)) == ".json" {
+			format = "columns"
+		}
+	}
+	if !dataFileFormats[format] {
+		return "", fmt.Errorf("Unknown file format %s, use csv or columns", format)
+	}
+	return format, nil
+}
+
+// formatDataTime formats the time of a value, as unix timestamp or RFC3339 in UTC.
+func formatDataTime(t int64, timeFormat string) string {
+	if timeFormat == "unix" {
+		return strconv.FormatInt(t, 10)
+	}
+	return time.Unix(t, 0).UTC().Format(time.RFC3339)
+}
+
+// writeDataRows writes the values of the series as rows with the dataColumns in format and
+// returns the number of rows. The csv format has a header row, the columns format is a json object
+// with an array of values for every column, e.g. for a data frame.
+func writeDataRows(w io.Writer, series []*api.DataSeries, format, timeFormat string) (int, error) {
+	columns := make(map[string][]interface{})
+	writer := csv.NewWriter(w)
+	if format == "csv" {
+		if err := writer.Write(dataColumns); err != nil {
+			return 0, err
+		}
+	}
+
+	rows := 0
+	for _, s := range series {
+		for _, value := range s.Values {
+			row := []string{formatDataTime(value.Time, timeFormat), strconv.FormatInt(s.MetricID, 10), s.SubjectID, s.Dimensions, value.Value}
+			rows++
+			if format == "csv" {
+				if err := writer.Write(row); err != nil {
+					return rows, err
+				}
+				continue
+			}
+			for i, column := range dataColumns {
+				columns[column] = append(columns[column], columnValue(column, row[i], value))
+			}
+		}
+	}
+
+	if format == "csv" {
+		writer.Flush()
+		return rows, writer.Error()
+	}
+	for _, column := range dataColumns {
+		if columns[column] == nil {
+			columns[column] = []interface{}{}
+		}
+	}
+	return rows, json.NewEncoder(w).Encode(columns)
+}
+
+// columnValue returns the value of a cell for the columns format: numbers for the times, metric
+// ids and values, the histograms and dimensions as json.
+func columnValue(column, cell string, value api.DataValue) interface{} {
+	switch column {
+	case "time":
+		if t, err := strconv.ParseInt(cell, 10, 64); err == nil {
+			return t
+		}
+	case "metric":
+		id, _ := strconv.ParseInt(cell, 10, 64)
+		return id
+	case "value":
+		return json.RawMessage(value.Value)
+	}
+	return cell
+}
+
+// writeDataFile writes the series to file, "-" writes to stdout.
+func writeDataFile(file string, series []*api.DataSeries, format, timeFormat string) (int, error) {
+	if file == "-" {
+		return writeDataRows(os.Stdout, series, format, timeFormat)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := writeDataRows(f, series, format, timeFormat)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return rows, err
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"bytes"
+	"coscale/api"
+	"testing"
+)
+
+// Test the rows of data export in the csv and columns formats.
+func TestWriteDataRows(t *testing.T) {
+	series := []*api.DataSeries{
+		{MetricID: 1, SubjectID: "s1", Dimensions: `[[1,"*"]]`, Values: []api.DataValue{{Time: 1495108620, Value: "1.5"}}},
+		{MetricID: 1, SubjectID: "s2", Dimensions: "[]", Values: []api.DataValue{{Time: 1495108620, Value: "[2,50,[1,2,3]]"}}},
+	}
+
+	var buffer bytes.Buffer
+	rows, err := writeDataRows(&buffer, series, "csv", "rfc3339")
+	if err != nil || rows != 2 {
+		t.Fatalf("expected: 2 rows, found: %d %v", rows, err)
+	}
+	expected := `time,metric,subject,dimensions,value
+2017-05-18T11:57:00Z,1,s1,"[[1,""*""]]",1.5
+2017-05-18T11:57:00Z,1,s2,[],"[2,50,[1,2,3]]"
+`
+	if buffer.String() != expected {
+		t.Fatalf("expected: %s, found: %s", expected, buffer.String())
+	}
+
+	buffer.Reset()
+	if _, err := writeDataRows(&buffer, series, "columns", "unix"); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	expected = `{"dimensions":["[[1,\"*\"]]","[]"],"metric":[1,1],"subject":["s1","s2"],"time":[1495108620,1495108620],"value":[1.5,[2,50,[1,2,3]]]}` + "\n"
+	if buffer.String() != expected {
+		t.Fatalf("expected: %s, found: %s", expected, buffer.String())
+	}
+
+	for file, format := range map[string]string{"data.csv": "csv", "data.JSON": "columns", "-": "csv"} {
+		if found, err := dataFileFormat(file, ""); err != nil || found != format {
+			t.Fatalf("expected: %s for %s, found: %s %v", format, file, found, err)
+		}
+	}
+	if _, err := dataFileFormat("data.csv", "parquet"); err == nil {
+		t.Fatalf("expected an error for the parquet format")
+	}
+}
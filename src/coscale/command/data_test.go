@@ -0,0 +1,111 @@
+package command
+
+import (
+	"bytes"
+	"coscale/api"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that --format csv of data insert is the format of the file, not the template of the
+// result, and that the file is parsed as CSV.
+func TestDataInsertFormat(t *testing.T) {
+	var format string
+	cmd := &Command{Name: "insert"}
+	cmd.Flag.StringVar(&format, "format", DEFAULT_STRING_FLAG_VALUE, "The format of the file: csv or influx.")
+	cmd.ParseArgs([]string{"--format", "csv", "--app-id", "app", "--access-token", "token"})
+	if format != "csv" || cmd.format != nil {
+		t.Fatalf("expected: the csv file format and no template, found: %q, %v", format, cmd.format)
+	}
+
+	dir, err := ioutil.TempDir("", "coscale-data")
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "data.csv")
+	if err := ioutil.WriteFile(file, []byte("time,value\n-60,1.5\n0,2\n"), 0600); err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	mapping := &api.CSVMapping{TimeColumn: "time", ValueColumn: "value", Comma: ',', MetricID: 1, SubjectID: "S2"}
+	callsData, err := readCSVData(file, mapping)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	if len(callsData) != 1 || len(callsData[0]["S2"]) != 1 || len(callsData[0]["S2"][0].Data) != 2 {
+		t.Fatalf("expected: 2 data points for S2, found: %v", callsData)
+	}
+}
+
+// Test that streamData inserts all lines and reports the lines with errors.
+func TestStreamData(t *testing.T) {
+	input := "M1:S1:-60:1.2\n\nM1:S1:0:1.3\nbad\nmetric=x:S1:0:1\nmetric=cpu:S2:0:2;M3:A:-60:1\n"
+	resolve := func(objectName, name string) (int64, error) {
+		if name == "cpu" {
+			return 2, nil
+		}
+		return 0, fmt.Errorf("The %s %q was not found", objectName, name)
+	}
+	var batches []map[string][]*api.ApiData
+	insert := func(callData map[string][]*api.ApiData) error {
+		batches = append(batches, callData)
+		return nil
+	}
+
+	var errOut bytes.Buffer
+	// A batch size of 1 byte sends every line separately.
+	failed := streamData(strings.NewReader(input), &errOut, insert, dataLineParser(resolve), 1, time.Hour)
+	if !failed {
+		t.Fatalf("expected: failed for the bad lines")
+	}
+	expectedErrors := "line 4: Bad datapoint format\nline 5: The metric \"x\" was not found\n"
+	if errOut.String() != expectedErrors {
+		t.Fatalf("expected: %q, found: %q", expectedErrors, errOut.String())
+	}
+	if len(batches) != 3 || len(batches[2]) != 2 || batches[2]["S2"][0].MetricID != 2 {
+		t.Fatalf("expected: 3 batches, found: %v", batches)
+	}
+
+	// Without errors and with a large batch size all lines are sent at the end of the input.
+	batches = nil
+	if failed := streamData(strings.NewReader("M1:S1:-60:1.2\nM1:S1:0:1.3"), &errOut, insert, dataLineParser(resolve), 1024, time.Hour); failed {
+		t.Fatalf("expected: no errors")
+	}
+	if len(batches) != 1 || len(batches[0]["S1"][0].Data) != 2 {
+		t.Fatalf("expected: 1 batch with 2 data points, found: %v", batches)
+	}
+}
+
+// Test that streamData sends the buffered data after the flush interval.
+func TestStreamDataFlushInterval(t *testing.T) {
+	reader, writer := io.Pipe()
+	inserted := make(chan map[string][]*api.ApiData, 1)
+	insert := func(callData map[string][]*api.ApiData) error {
+		inserted <- callData
+		return nil
+	}
+	done := make(chan bool)
+	go func() {
+		done <- streamData(reader, &bytes.Buffer{}, insert, dataLineParser(nil), 1024, 10*time.Millisecond)
+	}()
+
+	fmt.Fprintln(writer, "M1:S1:-60:1.2")
+	select {
+	case callData := <-inserted:
+		if callData["S1"][0].Data[0].Data != "1.2" {
+			t.Fatalf("expected: the data point, found: %v", callData)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected: the data to be sent after the flush interval")
+	}
+	writer.Close()
+	if failed := <-done; failed {
+		t.Fatalf("expected: no errors")
+	}
+}
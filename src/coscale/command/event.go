@@ -3,6 +3,8 @@ package command
 import (
 	"coscale/api"
 	"os"
+	"strings"
+	"time"
 )
 
 var eventObjectName = "event"
@@ -122,20 +124,27 @@ The name or id should be specified
 	--name
 		the event name of the event.
 	--since
-		list event data newer then the since UNIX timestamp.
+		list event data newer then the since UNIX timestamp. Also accepted: negative seconds ago,
+		a duration ago such as -2h, 90m or 7d (units s, m, h, d and w), now, today, yesterday or
+		a time such as 2017-05-18T12:00:00Z or 2017-05-18 12:00 (local time).
+		-1 means no limit, use -1s for one second ago. [default: no limit]
 	--before
-		list event data older then the before UNIX timestamp.
+		list event data older then the before UNIX timestamp, in the format of --since.
+		-1 means no limit. [default: no limit]
 `,
 		Run: func(cmd *Command, args []string) {
-			var name string
-			var id, since, before int64
+			var name, sinceExpr, beforeExpr string
+			var id int64
 			cmd.Flag.Usage = func() { cmd.PrintUsage() }
 			cmd.Flag.StringVar(&name, "name", DEFAULT_STRING_FLAG_VALUE, "The name of the event.")
 			cmd.Flag.Int64Var(&id, "id", -1, "Unique identifier of the event")
-			cmd.Flag.Int64Var(&since, "since", -1, "list event data newer then the since UNIX timestamp.")
-			cmd.Flag.Int64Var(&before, "before", -1, "list event data older then the before UNIX timestamp.")
+			cmd.Flag.StringVar(&sinceExpr, "since", DEFAULT_STRING_FLAG_VALUE, "list event data newer then the since UNIX timestamp.")
+			cmd.Flag.StringVar(&beforeExpr, "before", DEFAULT_STRING_FLAG_VALUE, "list event data older then the before UNIX timestamp.")
 			cmd.ParseArgs(args)
 
+			now := time.Now()
+			since, before := eventTimeFlag("since", sinceExpr, now), eventTimeFlag("before", beforeExpr, now)
+
 			var eventObj = &api.Event{}
 			var err error
 			if id != -1 {
@@ -150,7 +159,7 @@ The name or id should be specified
 				cmd.PrintResult("", err)
 			}
 
-			cmd.PrintResult(cmd.Capi.ListEventData(eventObj.ID, since, before))
+			cmd.PrintResult(cmd.Capi.ListEventDataRange(eventObj.ID, since, before))
 		},
 	},
 	{
@@ -375,3 +384,15 @@ Mandatory:
 		},
 	},
 }
+
+// eventTimeFlag parses the time expression of --since or --before of event listdata, see
+// parseTimeFlag. The result is nil if the flag is not set or -1, which leaves the start or stop
+// open. Other expressions are times, e.g. -1s is one second ago.
+func eventTimeFlag(name, expression string, now time.Time) *int {
+	expression = strings.TrimSpace(expression)
+	if expression == DEFAULT_STRING_FLAG_VALUE || expression == "-1" {
+		return nil
+	}
+	t := parseTimeFlag(name, expression, now)
+	return &t
+}
@@ -0,0 +1,24 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+// Test that only an unset flag or -1 leaves the event data range open and that -1s is a time.
+func TestEventTimeFlag(t *testing.T) {
+	now := time.Unix(1495108650, 0)
+	if found := eventTimeFlag("since", DEFAULT_STRING_FLAG_VALUE, now); found != nil {
+		t.Fatalf("expected: no limit without the flag, found: %d", *found)
+	}
+	if found := eventTimeFlag("since", "-1", now); found != nil {
+		t.Fatalf("expected: no limit for -1, found: %d", *found)
+	}
+	tests := map[string]int{"-1s": -1, "-2h": -7200, "0": 0, "1495100000": 1495100000}
+	for expression, expected := range tests {
+		found := eventTimeFlag("before", expression, now)
+		if found == nil || *found != expected {
+			t.Fatalf("%s expected: %d, found: %v", expression, expected, found)
+		}
+	}
+}
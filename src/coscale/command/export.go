@@ -0,0 +1,136 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ExportObject defines the export command on the CLI.
+var ExportObject = &Command{
+	Name:      "export",
+	UsageLine: "export (--dir) [--file-format]",
+	Long: `
+Export the servers, server groups, dimensions, metrics, metric groups, events, alert types and
+triggers of the application. Every kind of object is written to a file in the directory, e.g.
+servers.yaml, in the format used by apply. The objects and fields are sorted, so exporting an
+application that did not change gives the same files. The written files are printed.
+
+The directory can be used with apply and plan to restore the objects, also in another application:
+	coscale-cli plan -f ./snapshot
+
+The flags for export are:
+
+Mandatory:
+	--dir
+		The directory for the files, the directory is created if it does not exist.
+Optional:
+	--file-format
+		The format of the files: yaml or json. [default: yaml]
+
+References to objects that could not be found, e.g. the metric of a trigger, are written as #<id>.
+`,
+	Run: func(cmd *Command, args []string) {
+		var dir, fileFormat string
+		cmd.Flag.Usage = func() { cmd.PrintUsage() }
+		cmd.Flag.StringVar(&dir, "dir", DEFAULT_STRING_FLAG_VALUE, "The directory for the files.")
+		cmd.Flag.StringVar(&fileFormat, "file-format", "yaml", "The format of the files: yaml or json.")
+		cmd.ParseArgs(args)
+
+		if dir == DEFAULT_STRING_FLAG_VALUE {
+			cmd.PrintUsage()
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		if fileFormat != "yaml" && fileFormat != "json" {
+			fmt.Fprintln(os.Stderr, "Invalid file format, use yaml or json.")
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+
+		state, err := loadAppState(context.Background(), cmd.Capi)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+		files, err := exportAppState(state, dir, fileFormat)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+		result, _ := json.MarshalIndent(files, "", " ")
+		cmd.PrintResult(string(result), nil)
+	},
+}
+
+// exportedFile describes a file written by export.
+type exportedFile struct {
+	Kind  string `json:"kind"`
+	File  string `json:"file"`
+	Count int    `json:"count"`
+}
+
+// exportAppState writes a file in dir for every kind of object, fileFormat is yaml or json.
+func exportAppState(state *appState, dir, fileFormat string) ([]*exportedFile, error) {
+	spec, err := state.toSpec()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	kinds := []struct {
+		name    string
+		objects interface{}
+		count   int
+	}{
+		{"servers", spec.Servers, len(spec.Servers)},
+		{"servergroups", spec.ServerGroups, len(spec.ServerGroups)},
+		{"dimensions", spec.Dimensions, len(spec.Dimensions)},
+		{"metrics", spec.Metrics, len(spec.Metrics)},
+		{"metricgroups", spec.MetricGroups, len(spec.MetricGroups)},
+		{"events", spec.Events, len(spec.Events)},
+		{"alerttypes", spec.AlertTypes, len(spec.AlertTypes)},
+	}
+	var files []*exportedFile
+	for _, kind := range kinds {
+		// A file is written for every kind, also without objects, so the snapshot is complete.
+		data, err := encodeSpecFile(map[string]interface{}{kind.name: kind.objects}, fileFormat)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, kind.name+"."+fileFormat)
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, &exportedFile{kind.name, path, kind.count})
+	}
+	return files, nil
+}
+
+// encodeSpecFile encodes a part of a spec as YAML or json with sorted keys.
+func encodeSpecFile(value interface{}, fileFormat string) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	if fileFormat == "yaml" {
+		return marshalYAML(generic), nil
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", " ")
+	if err := encoder.Encode(generic); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"coscale/api"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that an exported application is read back by apply without changes.
+func TestExportRoundTrip(t *testing.T) {
+	state := newAppState()
+	state.servers["web 1"] = &api.Server{ID: 1, Name: "web 1", Description: "Web: nginx # 1", Type: "", State: "ENABLED"}
+	state.serverGroups["production"] = &api.ServerGroup{ID: 2, Name: "production", State: "ENABLED"}
+	state.serverGroups["frontend"] = &api.ServerGroup{ID: 8, Name: "frontend", State: "ENABLED", ParentID: 2}
+	state.metrics["Queued messages"] = &api.Metric{ID: 3, Name: "Queued messages", DataType: "DOUBLE", Subject: "SERVER", Period: 60, Unit: "%"}
+	state.metricDimensions["Queued messages"] = []string{"queue"}
+	state.metricGroups["RabbitMQ"] = &api.MetricGroup{ID: 9, Name: "RabbitMQ", Subject: "SERVER", State: "ENABLED"}
+	state.groupMetrics["RabbitMQ"] = []string{"Queued messages"}
+	state.dimensions["queue"] = &api.Dimension{ID: 4, Name: "queue"}
+	state.events["deployment"] = &api.Event{ID: 10, Name: "deployment", Description: "true", AttributeDescriptions: `[{"name":"version"}]`}
+	state.alertTypes["Operations"] = &api.AlertType{ID: 5, Name: "Operations", Description: "multi\nline",
+		Handle: `[{"type":"EMAIL","address":"ops@example.com"}]`, BackupSeconds: 600}
+	state.triggers["Operations"] = map[string]*api.AlertTrigger{
+		"Queue too long": {ID: 6, Name: "Queue too long", Metric: 3, Config: "avg(300) > 1000", GroupID: 8, DimensionSpecs: `[[4,"AVG(*)"]]`},
+	}
+
+	for _, fileFormat := range []string{"yaml", "json"} {
+		dir, err := ioutil.TempDir("", "coscale-export")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer os.RemoveAll(dir)
+
+		files, err := exportAppState(state, dir, fileFormat)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(files) != 7 || files[0].File != filepath.Join(dir, "servers."+fileFormat) || files[1].Count != 2 {
+			t.Fatalf("expected: 7 files with the number of objects, found: %v", files)
+		}
+
+		spec, err := readAppSpec(dir)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %s", fileFormat, err)
+		}
+		r := &reconciler{state: state, dryRun: true, reportMissing: true}
+		if err := r.apply(spec); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(r.changes) > 0 {
+			t.Fatalf("expected: no changes for %s, found: %s", fileFormat, formatChanges(r.changes))
+		}
+	}
+}
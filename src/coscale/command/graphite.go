@@ -0,0 +1,177 @@
+package command
+
+import (
+	"bufio"
+	"coscale/api"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// graphiteRule maps the Graphite paths that match pattern to a metric, a server and dimension
+// values. The names and values can contain $1, $2, ... for the parts matched by the wildcards.
+type graphiteRule struct {
+	pattern    *regexp.Regexp
+	metric     string
+	server     string
+	dimensions map[string]string
+}
+
+// graphiteRuleRef matches the references to the wildcards of the pattern.
+var graphiteRuleRef = regexp.MustCompile(`\$([0-9]+)`)
+
+// parseGraphiteRule parses a rule: <pattern> metric=<name> [server=<name>] [<dimension>=<value>...]
+func parseGraphiteRule(rule string) (*graphiteRule, error) {
+	fields := strings.Fields(rule)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("Bad rule %q, expected a pattern and a metric", rule)
+	}
+
+	var expression strings.Builder
+	for _, part := range strings.Split(fields[0], "*") {
+		if expression.Len() > 0 {
+			expression.WriteString(`([^.]+)`)
+		}
+		expression.WriteString(regexp.QuoteMeta(part))
+	}
+	pattern := regexp.MustCompile("^" + expression.String() + "$")
+
+	r := &graphiteRule{pattern: pattern}
+	for _, field := range fields[1:] {
+		pair := strings.SplitN(field, "=", 2)
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, fmt.Errorf("Bad rule %q at %q, expected name=value", rule, field)
+		}
+		for _, ref := range graphiteRuleRef.FindAllStringSubmatch(pair[1], -1) {
+			if index, _ := strconv.Atoi(ref[1]); index < 1 || index > pattern.NumSubexp() {
+				return nil, fmt.Errorf("Bad rule %q, the pattern has no wildcard %s", rule, ref[0])
+			}
+		}
+		switch pair[0] {
+		case "metric":
+			r.metric = pair[1]
+		case "server":
+			r.server = pair[1]
+		default:
+			if r.dimensions == nil {
+				r.dimensions = make(map[string]string)
+			}
+			r.dimensions[pair[0]] = pair[1]
+		}
+	}
+	if r.metric == "" {
+		return nil, fmt.Errorf("Bad rule %q, the metric is missing", rule)
+	}
+	return r, nil
+}
+
+// match returns the metric, the server and the dimension values for path, false if the path does
+// not match the rule.
+func (r *graphiteRule) match(path string) (string, string, map[string]string, bool) {
+	parts := r.pattern.FindStringSubmatch(path)
+	if parts == nil {
+		return "", "", nil, false
+	}
+	expand := func(template string) string {
+		return graphiteRuleRef.ReplaceAllStringFunc(template, func(ref string) string {
+			index, _ := strconv.Atoi(ref[1:])
+			return parts[index]
+		})
+	}
+	var dimensionValues map[string]string
+	for name, value := range r.dimensions {
+		if dimensionValues == nil {
+			dimensionValues = make(map[string]string)
+		}
+		dimensionValues[name] = expand(value)
+	}
+	return expand(r.metric), expand(r.server), dimensionValues, true
+}
+
+// readGraphiteRules reads a file with a rule on every line, lines that start with # are comments.
+func readGraphiteRules(file string) ([]*graphiteRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []*graphiteRule
+	scanner := bufio.NewScanner(f)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseGraphiteRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %s", file, number, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s: the file contains no rules", file)
+	}
+	return rules, nil
+}
+
+// graphiteConverter converts lines of the Carbon plaintext protocol into data for the API.
+type graphiteConverter struct {
+	*metricResolver
+	// rules map the paths to metrics, without rules the path is the metric name.
+	rules []*graphiteRule
+	// subject is used for the paths without server.
+	subject string
+}
+
+// convert converts a line into data, the tags of a tagged path are added to the dimension values.
+func (c *graphiteConverter) convert(line string) ([]*api.ApiData, error) {
+	if strings.TrimSpace(line) == "" {
+		return nil, nil
+	}
+	point, err := api.ParseGraphiteLine(line)
+	if err != nil {
+		return nil, err
+	}
+
+	metric, server, dimensionValues := point.Path, "", map[string]string(nil)
+	if len(c.rules) > 0 {
+		matched := false
+		for _, rule := range c.rules {
+			if metric, server, dimensionValues, matched = rule.match(point.Path); matched {
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("No rule matches the path %s", point.Path)
+		}
+	}
+	for name, value := range point.Tags {
+		if dimensionValues == nil {
+			dimensionValues = make(map[string]string)
+		}
+		dimensionValues[name] = value
+	}
+
+	subject := c.subject
+	if server != "" {
+		if subject, err = c.serverSubject(server); err != nil {
+			return nil, err
+		}
+	}
+	metricID, err := c.metricID(metric, "DOUBLE", subject, dimensionValues)
+	if err != nil {
+		return nil, err
+	}
+	return []*api.ApiData{{
+		MetricID:        metricID,
+		SubjectID:       subject,
+		Data:            []api.DataPoint{{SecondsAgo: point.Time, Data: point.Value}},
+		DimensionValues: dimensionValues,
+	}}, nil
+}
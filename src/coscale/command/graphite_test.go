@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test the parsing and matching of the Graphite rules.
+func TestGraphiteRule(t *testing.T) {
+	rule, err := parseGraphiteRule("servers.*.cpu-*.load   metric=cpu.$2.load server=$1 core=cpu$2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	metric, server, dimensionValues, ok := rule.match("servers.web-1.cpu-0.load")
+	if !ok || metric != "cpu.0.load" || server != "web-1" || !reflect.DeepEqual(dimensionValues, map[string]string{"core": "cpu0"}) {
+		t.Fatalf("expected: the expanded rule, found: %s %s %v %t", metric, server, dimensionValues, ok)
+	}
+	for _, path := range []string{"servers.web.1.cpu-0.load", "servers.web-1.cpu-0.load.avg", "servers.web-1.cpu.load"} {
+		if _, _, _, ok := rule.match(path); ok {
+			t.Fatalf("expected: no match for %s", path)
+		}
+	}
+
+	for _, invalid := range []string{"servers.*", "servers.* server=$1", "servers.* metric=$2", "servers.* metric"} {
+		if _, err := parseGraphiteRule(invalid); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
+
+// Test that the Graphite converter maps the paths with the rules.
+func TestGraphiteConverter(t *testing.T) {
+	capi := api.NewApi("http://coscale.test", "token", "app", true, false)
+	capi.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"token":"token"}`
+		switch req.URL.Query().Get("selectByName") {
+		case "web-1":
+			body = `[{"id":12,"name":"web-1"}]`
+		case "cpu.load":
+			body = `[{"id":5,"name":"cpu.load"}]`
+		case "requests":
+			body = `[{"id":6,"name":"requests"}]`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+
+	rules := []*graphiteRule{}
+	for _, line := range []string{"servers.*.cpu.load metric=cpu.load server=$1", "app.requests.* metric=requests status=$1"} {
+		rule, err := parseGraphiteRule(line)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		rules = append(rules, rule)
+	}
+	converter := &graphiteConverter{metricResolver: newMetricResolver(context.Background(), capi, false), rules: rules, subject: "A"}
+
+	var found []*api.ApiData
+	for _, line := range []string{"servers.web-1.cpu.load 0.5 1465839830", "app.requests.200;region=eu 12", ""} {
+		data, err := converter.convert(line)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		found = append(found, data...)
+	}
+	expected := []*api.ApiData{
+		{MetricID: 5, SubjectID: "S12", Data: []api.DataPoint{{SecondsAgo: 1465839830, Data: "0.5"}}},
+		{MetricID: 6, SubjectID: "A", Data: []api.DataPoint{{SecondsAgo: 0, Data: "12"}}, DimensionValues: map[string]string{"status": "200", "region": "eu"}},
+	}
+	if !reflect.DeepEqual(expected, found) {
+		t.Fatalf("expected: %v, found: %v", expected, found)
+	}
+
+	if _, err := converter.convert("servers.web-1.memory 1"); err == nil || err.Error() != "No rule matches the path servers.web-1.memory" {
+		t.Fatalf("expected: no rule error, found: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"coscale/api"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// influxPrecisions are the units of the timestamps in the InfluxDB line protocol.
+var influxPrecisions = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// influxConverter converts lines in the InfluxDB line protocol into data for the API.
+type influxConverter struct {
+	*metricResolver
+	precision time.Duration
+	// subjectTag is the tag with the server name, subject is used for lines without the tag.
+	subjectTag string
+	subject    string
+}
+
+// newInfluxConverter creates an influxConverter, subject is A or S<server id>. If create is true,
+// the metrics and dimensions that do not exist are created.
+func newInfluxConverter(ctx context.Context, capi *api.Api, precision time.Duration, subjectTag, subject string, create bool) *influxConverter {
+	return &influxConverter{
+		metricResolver: newMetricResolver(ctx, capi, create),
+		precision:      precision,
+		subjectTag:     subjectTag,
+		subject:        subject,
+	}
+}
+
+// convert converts a line into data, the tags except the subject tag are the dimension values.
+// Empty lines and comments return no data.
+func (c *influxConverter) convert(line string) ([]*api.ApiData, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+	points, err := api.ParseInfluxLine(line, c.precision)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []*api.ApiData
+	for _, point := range points {
+		subject := c.subject
+		var dimensionValues map[string]string
+		for name, value := range point.Tags {
+			if name == c.subjectTag {
+				if subject, err = c.serverSubject(value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if dimensionValues == nil {
+				dimensionValues = make(map[string]string)
+			}
+			dimensionValues[name] = value
+		}
+		metricID, err := c.metricID(point.Metric, "DOUBLE", subject, dimensionValues)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, &api.ApiData{
+			MetricID:        metricID,
+			SubjectID:       subject,
+			Data:            []api.DataPoint{{SecondsAgo: point.Time, Data: point.Value}},
+			DimensionValues: dimensionValues,
+		})
+	}
+	return data, nil
+}
+
+// readInfluxData reads a file in the InfluxDB line protocol, "-" reads stdin. The lines are
+// converted using convert and split into batches for InsertData.
+func readInfluxData(file string, convert func(line string) ([]*api.ApiData, error)) ([]map[string][]*api.ApiData, error) {
+	if file == "-" {
+		return parseInfluxData(os.Stdin, convert)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	callsData, err := parseInfluxData(f, convert)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	return callsData, nil
+}
+
+// parseInfluxData converts all lines of r, errors contain the line number.
+func parseInfluxData(r io.Reader, convert func(line string) ([]*api.ApiData, error)) ([]map[string][]*api.ApiData, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), api.MaxUploadSize)
+	buffer := api.NewDataBuffer()
+	for number := 1; scanner.Scan(); number++ {
+		data, err := convert(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", number, err)
+		}
+		for _, apiData := range data {
+			buffer.Add(apiData)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if buffer.Size() == 0 {
+		return nil, fmt.Errorf("The file contains no values")
+	}
+	return buffer.Flush()
+}
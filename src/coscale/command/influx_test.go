@@ -0,0 +1,79 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripFunc is a http.RoundTripper for testing.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Test that the influx converter creates the missing metrics and dimensions once.
+func TestInfluxConverter(t *testing.T) {
+	responses := map[string]string{
+		"POST /api/v1/app/app/login/":                     `{"token":"token"}`,
+		"GET /api/v1/app/app/servers/?selectByName=web-1": `[{"id":12,"name":"web-1"}]`,
+		"POST /api/v1/app/app/metrics/":                   `{"id":5,"name":"cpu.usage"}`,
+		"GET /api/v1/app/app/metrics/5/dimensions/":       `[{"id":1,"name":"core"}]`,
+		"POST /api/v1/app/app/dimensions/":                `{"id":2,"name":"region"}`,
+		"POST /api/v1/app/app/metrics/5/dimensions/2/":    `{}`,
+	}
+	var requests []string
+	capi := api.NewApi("http://coscale.test", "token", "app", true, false)
+	capi.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		request := req.Method + " " + req.URL.Path
+		if req.URL.RawQuery != "" {
+			request += "?" + req.URL.RawQuery
+		}
+		requests = append(requests, request)
+		body, ok := responses[request]
+		if !ok {
+			return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(strings.NewReader(`{"msg":"Not Found"}`)), Header: http.Header{}}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+
+	converter := newInfluxConverter(context.Background(), capi, time.Nanosecond, "host", "A", true)
+	var found []*api.ApiData
+	for _, line := range []string{"# comment", "cpu,host=web-1,core=0,region=eu usage=1.5 1465839830100400200", "cpu,core=1,region=us usage=2"} {
+		data, err := converter.convert(line)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		found = append(found, data...)
+	}
+
+	expected := []*api.ApiData{
+		{MetricID: 5, SubjectID: "S12", Data: []api.DataPoint{{SecondsAgo: 1465839830, Data: "1.5"}}, DimensionValues: map[string]string{"core": "0", "region": "eu"}},
+		{MetricID: 5, SubjectID: "A", Data: []api.DataPoint{{SecondsAgo: 0, Data: "2"}}, DimensionValues: map[string]string{"core": "1", "region": "us"}},
+	}
+	if !reflect.DeepEqual(expected, found) {
+		t.Fatalf("expected: %v, found: %v", expected, found)
+	}
+	var calls []string
+	for _, request := range requests {
+		if !strings.HasSuffix(request, "/login/") {
+			calls = append(calls, request)
+		}
+	}
+	expectedCalls := []string{
+		"GET /api/v1/app/app/servers/?selectByName=web-1",
+		"POST /api/v1/app/app/metrics/",
+		"GET /api/v1/app/app/metrics/5/dimensions/",
+		"POST /api/v1/app/app/dimensions/",
+		"POST /api/v1/app/app/metrics/5/dimensions/2/",
+	}
+	if !reflect.DeepEqual(expectedCalls, calls) {
+		t.Fatalf("expected: %v, found: %v", expectedCalls, calls)
+	}
+}
@@ -0,0 +1,257 @@
+package command
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// outputFormats are the values for the --output flag.
+var outputFormats = []string{"json", "jsonl", "yaml", "table", "csv"}
+
+// objectColumns defines the default columns of the table and csv output for the objects
+// returned by a command. The key is the path of the command without the action, or with
+// the action if the action returns another type of object.
+var objectColumns = map[string][]string{
+	"server":           {"id", "name", "type", "state", "source"},
+	"servergroup":      {"id", "name", "type", "state", "parentId"},
+	"metric":           {"id", "name", "dataType", "subject", "period", "unit", "state"},
+	"metricgroup":      {"id", "name", "type", "subject", "state"},
+	"metric dimension": {"id", "name"},
+	"event":            {"id", "name", "type", "state", "source"},
+	"event listdata":   {"id", "timestamp", "stoptime", "subject", "message"},
+	"event newdata":    {"id", "timestamp", "stoptime", "subject", "message"},
+	"event updatedata": {"id", "timestamp", "stoptime", "subject", "message"},
+	"alert":            {"id", "name", "description"},
+	"alert type":       {"id", "name", "description", "backupSeconds", "escalationSeconds"},
+	"alert trigger":    {"id", "name", "metric", "config", "onApp", "autoresolveSeconds"},
+	"apply":            {"action", "kind", "name"},
+	"plan":             {"action", "kind", "name"},
+	"export":           {"kind", "file", "count"},
+	"clone":            {"action", "kind", "name", "reason"},
+}
+
+// isOutputFormat checks if format is a supported output format.
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the names of the command and its parents, without the name of the binary.
+func (c *Command) path() []string {
+	var names []string
+	for cmd := c; cmd != nil && cmd.parent != nil; cmd = cmd.parent {
+		names = append([]string{cmd.Name}, names...)
+	}
+	return names
+}
+
+// objectKey returns the key of the command in objectColumns and objectTypes: the path of the
+// command if it is registered, else the path without the action.
+func (c *Command) objectKey() string {
+	path := c.path()
+	if _, ok := objectColumns[strings.Join(path, " ")]; ok || len(path) < 2 {
+		return strings.Join(path, " ")
+	}
+	return strings.Join(path[:len(path)-1], " ")
+}
+
+// columns returns the default columns for the objects returned by the command. A query
+// selects its own fields, so the columns are guessed from its result.
+func (c *Command) columns() []string {
+	if c.query != nil {
+		return nil
+	}
+	return objectColumns[c.objectKey()]
+}
+
+// formatOutput converts the json result of a command into the requested output format.
+func formatOutput(result, format string, columns []string) (string, error) {
+	if format == "json" || format == "" {
+		return result, nil
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(result))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		// Not json, e.g. an empty result: there is nothing to format.
+		return result, nil
+	}
+
+	switch format {
+	case "jsonl":
+		return formatJSONLines(value)
+	case "yaml":
+		return strings.TrimSuffix(string(marshalYAML(value)), "\n"), nil
+	case "table", "csv":
+		rows, ok := toRows(value)
+		if !ok {
+			// A single value, there are no columns.
+			return formatCell(value), nil
+		}
+		if len(columns) == 0 || len(rows) > 0 && !hasColumns(rows, columns) {
+			// No default columns or other objects, e.g. after a query.
+			columns = guessColumns(rows)
+		}
+		if format == "csv" {
+			return formatCSV(rows, columns)
+		}
+		return formatTable(rows, columns), nil
+	}
+	return "", fmt.Errorf("Unknown output format %s, use one of: %s", format, strings.Join(outputFormats, ", "))
+}
+
+// formatJSONLines writes every element of a list as compact json on its own line.
+func formatJSONLines(value interface{}) (string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+	lines := make([]string, 0, len(list))
+	for _, item := range list {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, string(line))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// toRows converts a json object or a list of json objects into rows.
+func toRows(value interface{}) ([]map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, true
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				// A list of values, show every value as a row.
+				row = map[string]interface{}{"value": item}
+			}
+			rows = append(rows, row)
+		}
+		return rows, true
+	}
+	return nil, false
+}
+
+// guessColumns returns the columns for unknown objects: id and name first, then
+// the other fields with a scalar value in alphabetical order.
+func guessColumns(rows []map[string]interface{}) []string {
+	found := make(map[string]bool)
+	for _, row := range rows {
+		for key, value := range row {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				found[key] = true
+			}
+		}
+	}
+	var columns, others []string
+	for _, key := range []string{"id", "name"} {
+		if found[key] {
+			columns = append(columns, key)
+			delete(found, key)
+		}
+	}
+	for key := range found {
+		others = append(others, key)
+	}
+	sort.Strings(others)
+	return append(columns, others...)
+}
+
+// hasColumns checks if the rows contain at least one of the columns.
+func hasColumns(rows []map[string]interface{}, columns []string) bool {
+	for _, row := range rows {
+		for _, column := range columns {
+			if getField(row, column) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getField returns the value of a field, the name of the field is case insensitive.
+func getField(row map[string]interface{}, column string) interface{} {
+	if value, ok := row[column]; ok {
+		return value
+	}
+	for key, value := range row {
+		if strings.EqualFold(key, column) {
+			return value
+		}
+	}
+	return nil
+}
+
+// formatCell formats a value for a table or csv cell.
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprintf("%t", v)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// formatTable writes the rows as a table with aligned columns.
+func formatTable(rows []map[string]interface{}, columns []string) string {
+	var buffer bytes.Buffer
+	writer := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = strings.ToUpper(column)
+	}
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			// Keep every row on one line.
+			cells[i] = strings.Replace(formatCell(getField(row, column)), "\n", " ", -1)
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	writer.Flush()
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// formatCSV writes the rows as csv with a header line.
+func formatCSV(rows []map[string]interface{}, columns []string) (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write(columns)
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = formatCell(getField(row, column))
+		}
+		writer.Write(cells)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"testing"
+)
+
+var servers = `[
+ {"id": 34, "name": "web 1", "type": "", "state": "ENABLED", "source": "CoScale Agent", "attributes": []},
+ {"id": 35, "name": "db", "type": "mysql", "state": "INACTIVE", "source": "cli", "attributes": [{"key": "os"}]}
+]`
+
+// Test formatOutput for the different formats.
+func TestFormatOutput(t *testing.T) {
+	columns := []string{"id", "name", "type", "state"}
+	tests := []struct {
+		format   string
+		columns  []string
+		result   string
+		expected string
+	}{
+		{"json", columns, servers, servers},
+		{"table", columns, servers, "ID  NAME   TYPE   STATE\n34  web 1         ENABLED\n35  db     mysql  INACTIVE"},
+		{"csv", columns, servers, "id,name,type,state\n34,web 1,,ENABLED\n35,db,mysql,INACTIVE"},
+		{"table", nil, `{"name": "q", "id": 1, "size": 2.5, "tags": ["a"]}`, "ID  NAME  SIZE\n1   q     2.5"},
+		{"table", nil, `12`, "12"},
+		{"csv", nil, `["a", "b"]`, "value\na\nb"},
+		{"jsonl", nil, servers, `{"attributes":[],"id":34,"name":"web 1","source":"CoScale Agent","state":"ENABLED","type":""}` + "\n" +
+			`{"attributes":[{"key":"os"}],"id":35,"name":"db","source":"cli","state":"INACTIVE","type":"mysql"}`},
+		{"yaml", nil, servers, `- attributes: []
+  id: 34
+  name: web 1
+  source: CoScale Agent
+  state: ENABLED
+  type: ""
+- attributes:
+    - key: os
+  id: 35
+  name: db
+  source: cli
+  state: INACTIVE
+  type: mysql`},
+		{"yaml", nil, `{"config": "avg(300) > 25", "handle": "[{\"type\":\"EMAIL\"}]", "onApp": true, "version": "1", "empty": {}}`, `config: avg(300) > 25
+empty: {}
+handle: "[{\"type\":\"EMAIL\"}]"
+onApp: true
+version: "1"`},
+		{"table", columns, `Not json`, `Not json`},
+		{"table", columns, `[]`, "ID  NAME  TYPE  STATE"},
+		{"table", columns, `[{"host": "web 1", "cpu": 2}]`, "CPU  HOST\n2    web 1"},
+	}
+	for _, test := range tests {
+		obtained, err := formatOutput(test.result, test.format, test.columns)
+		if err != nil {
+			t.Fatalf("Error occured while formatting %s: %s", test.format, err)
+		}
+		if obtained != test.expected {
+			t.Fatalf("%s expected: \n%s\n, found: \n%s\n", test.format, test.expected, obtained)
+		}
+	}
+}
+
+// Test that the columns are found by the path of the command.
+func TestColumns(t *testing.T) {
+	list := &Command{Name: "list"}
+	listData := &Command{Name: "listdata"}
+	root := NewCommand("coscale-cli", "", []*Command{
+		NewCommand("event", "", []*Command{list, listData}),
+		NewCommand("data", "", []*Command{{Name: "get"}}),
+	})
+	if obtained := list.columns(); len(obtained) == 0 || obtained[1] != "name" {
+		t.Fatalf("expected event columns, found: %v", obtained)
+	}
+	if obtained := listData.columns(); len(obtained) == 0 || obtained[1] != "timestamp" {
+		t.Fatalf("expected event data columns, found: %v", obtained)
+	}
+	if obtained := root.SubCommands[1].SubCommands[0].columns(); obtained != nil {
+		t.Fatalf("expected no columns, found: %v", obtained)
+	}
+	list.query, _ = compileQuery("[].{id: id, host: name}")
+	if obtained := list.columns(); obtained != nil {
+		t.Fatalf("expected no columns after a query, found: %v", obtained)
+	}
+}
+
+// Test that the table and csv output show the fields selected by a query.
+func TestQueryOutput(t *testing.T) {
+	list := &Command{Name: "list"}
+	NewCommand("coscale-cli", "", []*Command{NewCommand("server", "", []*Command{list})})
+	list.query, _ = compileQuery("[].{id: id, host: name}")
+	result, err := applyQuery(servers, list.query, false)
+	if err != nil {
+		t.Fatalf("Error occured: %s", err)
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"table", "ID  HOST\n34  web 1\n35  db"},
+		{"csv", "id,host\n34,web 1\n35,db"},
+	}
+	for _, test := range tests {
+		obtained, err := formatOutput(result, test.format, list.columns())
+		if err != nil {
+			t.Fatalf("Error occured: %s", err)
+		}
+		if obtained != test.expected {
+			t.Fatalf("%s expected: \n%s\n, found: \n%s\n", test.format, test.expected, obtained)
+		}
+	}
+}
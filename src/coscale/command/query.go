@@ -0,0 +1,932 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The --query flag selects a part of the json result using a JMESPath expression, like
+// aws --query, without the dependency. The supported subset of https://jmespath.org is:
+//	name, "a key"                      a field of an object
+//	a.b                                the field b of the field a
+//	[0], [-1]                          an element of a list
+//	[1:3], [::-1]                      a slice of a list
+//	[*], *                             every element of a list, every value of an object
+//	[]                                 every element of the flattened list
+//	[?<expression>]                    every element of a list for which the expression is true
+//	==, !=, <, <=, >, >=               comparisons, < and > only compare numbers
+//	&&, ||, !, (...)                   and, or, not and grouping
+//	'text', `34`, `{"a": 1}`           a raw string or a json literal
+//	@                                  the current value
+//	[id, name], {id: id, host: name}   a new list or object
+//	a | b                              b applied on the result of a
+//	length(@), keys(@), values(@), contains(tags, 'web')
+// The expression after a slice, [*], *, [] or a filter is applied on every element and the null
+// results are dropped, e.g. [*].name is the list of names. A pipe ends this projection:
+// [*].name | [0] is the first name. As in JMESPath false, null, "", [] and {} are false, 0 is true.
+
+// queryFunc evaluates a compiled query on a value decoded by encoding/json.
+type queryFunc func(value interface{}) interface{}
+
+// queryTokenKind is the kind of a token of a query.
+type queryTokenKind int
+
+const (
+	tokenEOF queryTokenKind = iota
+	tokenIdentifier
+	tokenQuotedIdentifier
+	tokenRawString
+	tokenLiteral
+	tokenNumber
+	tokenDot
+	tokenStar
+	tokenFlatten
+	tokenFilter
+	tokenLbracket
+	tokenRbracket
+	tokenLbrace
+	tokenRbrace
+	tokenLparen
+	tokenRparen
+	tokenComma
+	tokenColon
+	tokenCurrent
+	tokenPipe
+	tokenOr
+	tokenAnd
+	tokenNot
+	tokenComparator
+)
+
+// bindingPowers are the binding powers of the JMESPath grammar, a token with a higher binding
+// power binds tighter to the expression on its left. A projection applies the tokens with a
+// binding power of at least 10 on every element.
+var bindingPowers = map[queryTokenKind]int{
+	tokenPipe:       1,
+	tokenOr:         2,
+	tokenAnd:        3,
+	tokenComparator: 5,
+	tokenFlatten:    9,
+	tokenStar:       20,
+	tokenFilter:     21,
+	tokenDot:        40,
+	tokenNot:        45,
+	tokenLbrace:     50,
+	tokenLbracket:   55,
+	tokenLparen:     60,
+}
+
+// queryToken is a token of a query, value is the decoded string, number or json literal.
+type queryToken struct {
+	kind  queryTokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// queryFunctions are the supported functions and their number of arguments.
+var queryFunctions = map[string]struct {
+	arguments int
+	call      func(arguments []interface{}) interface{}
+}{
+	"length":   {1, lengthFunction},
+	"keys":     {1, keysFunction},
+	"values":   {1, valuesFunction},
+	"contains": {2, containsFunction},
+}
+
+// queryParser compiles a query using the top down operator precedence of JMESPath.
+type queryParser struct {
+	tokens []queryToken
+	index  int
+}
+
+// compileQuery parses a query expression.
+func compileQuery(input string) (queryFunc, error) {
+	tokens, err := tokenizeQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	query, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != tokenEOF {
+		return nil, p.unexpected(token)
+	}
+	return query, nil
+}
+
+// applyQuery applies the query on a json result and returns the selected part as json.
+func applyQuery(result string, query queryFunc, rawOutput bool) (string, error) {
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(result))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("The result is not json, --query can not be used: %s", err)
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if !rawOutput {
+		encoder.SetIndent("", " ")
+	}
+	if err := encoder.Encode(query(value)); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+func queryError(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid query at position %d: %s", pos+1, fmt.Sprintf(format, args...))
+}
+
+// tokenizeQuery splits a query into tokens, the last token is tokenEOF.
+func tokenizeQuery(input string) ([]queryToken, error) {
+	var tokens []queryToken
+	symbols := map[byte]queryTokenKind{
+		'.': tokenDot, '*': tokenStar, ']': tokenRbracket, '{': tokenLbrace, '}': tokenRbrace,
+		'(': tokenLparen, ')': tokenRparen, ',': tokenComma, ':': tokenColon, '@': tokenCurrent,
+	}
+	pos := 0
+	for pos < len(input) {
+		c := input[pos]
+		start := pos
+		token := queryToken{pos: start}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+			continue
+		case symbols[c] != tokenEOF:
+			token.kind = symbols[c]
+			pos++
+		case isIdentStart(c):
+			for pos < len(input) && (isIdentStart(input[pos]) || isDigit(input[pos])) {
+				pos++
+			}
+			token.kind = tokenIdentifier
+			token.value = input[start:pos]
+		case isDigit(c) || c == '-':
+			pos++
+			for pos < len(input) && isDigit(input[pos]) {
+				pos++
+			}
+			number, err := strconv.Atoi(input[start:pos])
+			if err != nil {
+				return nil, queryError(start, "invalid number %q", input[start:pos])
+			}
+			token.kind = tokenNumber
+			token.value = number
+		case c == '"' || c == '\'' || c == '`':
+			end := closingQuote(input, pos)
+			if end < 0 {
+				return nil, queryError(start, "missing closing %c", c)
+			}
+			pos = end + 1
+			text := input[start+1 : end]
+			switch c {
+			case '"':
+				var name string
+				if err := json.Unmarshal([]byte(input[start:pos]), &name); err != nil {
+					return nil, queryError(start, "invalid quoted identifier %s", input[start:pos])
+				}
+				token.kind = tokenQuotedIdentifier
+				token.value = name
+			case '\'':
+				token.kind = tokenRawString
+				token.value = strings.Replace(text, `\'`, `'`, -1)
+			case '`':
+				decoder := json.NewDecoder(strings.NewReader(strings.Replace(text, "\\`", "`", -1)))
+				decoder.UseNumber()
+				if err := decoder.Decode(&token.value); err != nil {
+					return nil, queryError(start, "invalid json literal %s", input[start:pos])
+				}
+				token.kind = tokenLiteral
+			}
+		case c == '[':
+			pos++
+			token.kind = tokenLbracket
+			if pos < len(input) && input[pos] == ']' {
+				token.kind = tokenFlatten
+				pos++
+			} else if pos < len(input) && input[pos] == '?' {
+				token.kind = tokenFilter
+				pos++
+			}
+		case c == '|' || c == '&':
+			if strings.HasPrefix(input[pos:], "||") {
+				token.kind = tokenOr
+				pos += 2
+			} else if strings.HasPrefix(input[pos:], "&&") {
+				token.kind = tokenAnd
+				pos += 2
+			} else if c == '|' {
+				token.kind = tokenPipe
+				pos++
+			} else {
+				return nil, queryError(start, "expression references are not supported")
+			}
+		case c == '!' || c == '=' || c == '<' || c == '>':
+			pos++
+			if pos < len(input) && input[pos] == '=' {
+				pos++
+			}
+			token.kind = tokenComparator
+			switch input[start:pos] {
+			case "!":
+				token.kind = tokenNot
+			case "=":
+				return nil, queryError(start, "use == to compare")
+			}
+		default:
+			return nil, queryError(start, "unexpected %q", c)
+		}
+		token.text = input[start:pos]
+		tokens = append(tokens, token)
+	}
+	return append(tokens, queryToken{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// closingQuote returns the position of the quote that closes the string at start, a quote
+// preceded by a backslash is escaped.
+func closingQuote(input string, start int) int {
+	for pos := start + 1; pos < len(input); pos++ {
+		switch input[pos] {
+		case '\\':
+			pos++
+		case input[start]:
+			return pos
+		}
+	}
+	return -1
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.index]
+}
+
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.index]
+	if token.kind != tokenEOF {
+		p.index++
+	}
+	return token
+}
+
+func (p *queryParser) unexpected(token queryToken) error {
+	if token.kind == tokenEOF {
+		return queryError(token.pos, "unexpected end of the query")
+	}
+	return queryError(token.pos, "unexpected %q", token.text)
+}
+
+func (p *queryParser) expect(kind queryTokenKind, text string) error {
+	if token := p.next(); token.kind != kind {
+		if token.kind == tokenEOF {
+			return queryError(token.pos, "expected %q", text)
+		}
+		return queryError(token.pos, "expected %q instead of %q", text, token.text)
+	}
+	return nil
+}
+
+// parseExpression parses the tokens that bind tighter than bindingPower.
+func (p *queryParser) parseExpression(bindingPower int) (queryFunc, error) {
+	left, err := p.nud(p.next())
+	if err != nil {
+		return nil, err
+	}
+	for bindingPower < bindingPowers[p.peek().kind] {
+		left, err = p.led(p.next(), left)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+// nud parses an expression that starts with the token.
+func (p *queryParser) nud(token queryToken) (queryFunc, error) {
+	switch token.kind {
+	case tokenRawString, tokenLiteral:
+		return literalQuery(token.value), nil
+	case tokenIdentifier:
+		if p.peek().kind == tokenLparen {
+			return p.parseFunction(token)
+		}
+		return fieldQuery(token.value.(string)), nil
+	case tokenQuotedIdentifier:
+		if p.peek().kind == tokenLparen {
+			return nil, queryError(token.pos, "a quoted identifier can not be a function name")
+		}
+		return fieldQuery(token.value.(string)), nil
+	case tokenCurrent:
+		return currentQuery, nil
+	case tokenStar:
+		right, err := p.parseProjection(bindingPowers[tokenStar])
+		if err != nil {
+			return nil, err
+		}
+		return valuesProjection(currentQuery, right), nil
+	case tokenFlatten:
+		right, err := p.parseProjection(bindingPowers[tokenFlatten])
+		if err != nil {
+			return nil, err
+		}
+		return listProjection(flattenQuery(currentQuery), right), nil
+	case tokenFilter:
+		return p.parseFilter(currentQuery)
+	case tokenLbracket:
+		if kind := p.peek().kind; kind == tokenNumber || kind == tokenColon {
+			return p.parseIndex(currentQuery)
+		}
+		if p.peek().kind == tokenStar && p.tokens[p.index+1].kind == tokenRbracket {
+			p.index += 2
+			return p.parseListProjection(currentQuery)
+		}
+		return p.parseMultiSelectList()
+	case tokenLbrace:
+		return p.parseMultiSelectHash()
+	case tokenNot:
+		expression, err := p.parseExpression(bindingPowers[tokenNot])
+		if err != nil {
+			return nil, err
+		}
+		return func(value interface{}) interface{} {
+			return !isTrue(expression(value))
+		}, nil
+	case tokenLparen:
+		expression, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		return expression, p.expect(tokenRparen, ")")
+	}
+	return nil, p.unexpected(token)
+}
+
+// led parses the token that follows the expression left.
+func (p *queryParser) led(token queryToken, left queryFunc) (queryFunc, error) {
+	switch token.kind {
+	case tokenDot:
+		if p.peek().kind == tokenStar {
+			p.next()
+			right, err := p.parseProjection(bindingPowers[tokenDot])
+			if err != nil {
+				return nil, err
+			}
+			return valuesProjection(left, right), nil
+		}
+		right, err := p.parseDotExpression(bindingPowers[tokenDot])
+		if err != nil {
+			return nil, err
+		}
+		return chainQuery(left, right), nil
+	case tokenPipe:
+		right, err := p.parseExpression(bindingPowers[tokenPipe])
+		if err != nil {
+			return nil, err
+		}
+		return chainQuery(left, right), nil
+	case tokenOr, tokenAnd:
+		right, err := p.parseExpression(bindingPowers[token.kind])
+		if err != nil {
+			return nil, err
+		}
+		isOr := token.kind == tokenOr
+		return func(value interface{}) interface{} {
+			// Like JMESPath the value of the deciding operand is returned.
+			if result := left(value); isTrue(result) == isOr {
+				return result
+			}
+			return right(value)
+		}, nil
+	case tokenComparator:
+		right, err := p.parseExpression(bindingPowers[tokenComparator])
+		if err != nil {
+			return nil, err
+		}
+		return compareQuery(left, token.text, right), nil
+	case tokenFlatten:
+		right, err := p.parseProjection(bindingPowers[tokenFlatten])
+		if err != nil {
+			return nil, err
+		}
+		return listProjection(flattenQuery(left), right), nil
+	case tokenFilter:
+		return p.parseFilter(left)
+	case tokenLbracket:
+		if kind := p.peek().kind; kind == tokenNumber || kind == tokenColon {
+			return p.parseIndex(left)
+		}
+		if p.peek().kind == tokenStar && p.tokens[p.index+1].kind == tokenRbracket {
+			p.index += 2
+			return p.parseListProjection(left)
+		}
+		return nil, p.unexpected(p.peek())
+	}
+	return nil, p.unexpected(token)
+}
+
+// parseProjection parses the expression that a projection applies on every element, it is
+// the current element if the projection is not followed by such an expression.
+func (p *queryParser) parseProjection(bindingPower int) (queryFunc, error) {
+	token := p.peek()
+	switch {
+	case bindingPowers[token.kind] < 10:
+		return currentQuery, nil
+	case token.kind == tokenLbracket || token.kind == tokenFilter:
+		return p.parseExpression(bindingPower)
+	case token.kind == tokenDot:
+		p.next()
+		return p.parseDotExpression(bindingPower)
+	}
+	return nil, p.unexpected(token)
+}
+
+// parseDotExpression parses the expression after a dot.
+func (p *queryParser) parseDotExpression(bindingPower int) (queryFunc, error) {
+	switch token := p.peek(); token.kind {
+	case tokenIdentifier, tokenQuotedIdentifier, tokenStar:
+		return p.parseExpression(bindingPower)
+	case tokenLbracket:
+		p.next()
+		return p.parseMultiSelectList()
+	case tokenLbrace:
+		p.next()
+		return p.parseMultiSelectHash()
+	default:
+		return nil, p.unexpected(token)
+	}
+}
+
+func (p *queryParser) parseListProjection(left queryFunc) (queryFunc, error) {
+	right, err := p.parseProjection(bindingPowers[tokenStar])
+	if err != nil {
+		return nil, err
+	}
+	return listProjection(left, right), nil
+}
+
+// parseFilter parses the condition and the projection of a filter, [? is already parsed.
+func (p *queryParser) parseFilter(left queryFunc) (queryFunc, error) {
+	condition, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenRbracket, "]"); err != nil {
+		return nil, err
+	}
+	right, err := p.parseProjection(bindingPowers[tokenFilter])
+	if err != nil {
+		return nil, err
+	}
+	return listProjection(func(value interface{}) interface{} {
+		list, ok := left(value).([]interface{})
+		if !ok {
+			return nil
+		}
+		matches := []interface{}{}
+		for _, item := range list {
+			if isTrue(condition(item)) {
+				matches = append(matches, item)
+			}
+		}
+		return matches
+	}, right), nil
+}
+
+// parseIndex parses an index [0] or a slice [start:stop:step], [ is already parsed.
+func (p *queryParser) parseIndex(left queryFunc) (queryFunc, error) {
+	var parts [3]*int
+	part, colons := 0, 0
+	for {
+		token := p.next()
+		switch {
+		case token.kind == tokenRbracket:
+			if colons == 0 {
+				return chainQuery(left, indexQuery(*parts[0])), nil
+			}
+			if parts[2] != nil && *parts[2] == 0 {
+				return nil, queryError(token.pos, "the step of a slice can not be 0")
+			}
+			return p.parseListProjection(sliceQuery(left, parts))
+		case token.kind == tokenNumber && parts[part] == nil:
+			number := token.value.(int)
+			parts[part] = &number
+		case token.kind == tokenColon && colons < 2:
+			colons++
+			part++
+		default:
+			return nil, p.unexpected(token)
+		}
+	}
+}
+
+// parseMultiSelectList parses a list of expressions [a, b], [ is already parsed.
+func (p *queryParser) parseMultiSelectList() (queryFunc, error) {
+	var queries []queryFunc
+	for {
+		query, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+		if token := p.next(); token.kind == tokenRbracket {
+			break
+		} else if token.kind != tokenComma {
+			return nil, p.unexpected(token)
+		}
+	}
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+		list := make([]interface{}, 0, len(queries))
+		for _, query := range queries {
+			list = append(list, query(value))
+		}
+		return list
+	}, nil
+}
+
+// parseMultiSelectHash parses an object {key: expression, ...}, { is already parsed.
+func (p *queryParser) parseMultiSelectHash() (queryFunc, error) {
+	var keys []string
+	var queries []queryFunc
+	for {
+		token := p.next()
+		if token.kind != tokenIdentifier && token.kind != tokenQuotedIdentifier {
+			return nil, p.unexpected(token)
+		}
+		if err := p.expect(tokenColon, ":"); err != nil {
+			return nil, err
+		}
+		query, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.value.(string))
+		queries = append(queries, query)
+		if token := p.next(); token.kind == tokenRbrace {
+			break
+		} else if token.kind != tokenComma {
+			return nil, p.unexpected(token)
+		}
+	}
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+		object := make(map[string]interface{}, len(keys))
+		for i, key := range keys {
+			object[key] = queries[i](value)
+		}
+		return object
+	}, nil
+}
+
+// parseFunction parses the arguments of a function call.
+func (p *queryParser) parseFunction(name queryToken) (queryFunc, error) {
+	function, ok := queryFunctions[name.text]
+	if !ok {
+		return nil, queryError(name.pos, "unknown function %s()", name.text)
+	}
+	p.next()
+	var arguments []queryFunc
+	for p.peek().kind != tokenRparen {
+		if len(arguments) > 0 {
+			if err := p.expect(tokenComma, ","); err != nil {
+				return nil, err
+			}
+		}
+		argument, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+	p.next()
+	if len(arguments) != function.arguments {
+		return nil, queryError(name.pos, "%s() takes %d argument(s), found %d", name.text, function.arguments, len(arguments))
+	}
+	return func(value interface{}) interface{} {
+		values := make([]interface{}, len(arguments))
+		for i, argument := range arguments {
+			values[i] = argument(value)
+		}
+		return function.call(values)
+	}, nil
+}
+
+func currentQuery(value interface{}) interface{} {
+	return value
+}
+
+func literalQuery(literal interface{}) queryFunc {
+	return func(interface{}) interface{} {
+		return literal
+	}
+}
+
+func chainQuery(first, second queryFunc) queryFunc {
+	return func(value interface{}) interface{} {
+		return second(first(value))
+	}
+}
+
+func fieldQuery(name string) queryFunc {
+	return func(value interface{}) interface{} {
+		if object, ok := value.(map[string]interface{}); ok {
+			return object[name]
+		}
+		return nil
+	}
+}
+
+func indexQuery(index int) queryFunc {
+	return func(value interface{}) interface{} {
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		i := index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil
+		}
+		return list[i]
+	}
+}
+
+// sliceQuery returns the slice of a list, parts are the optional start, stop and step.
+func sliceQuery(left queryFunc, parts [3]*int) queryFunc {
+	return func(value interface{}) interface{} {
+		list, ok := left(value).([]interface{})
+		if !ok {
+			return nil
+		}
+		step := 1
+		if parts[2] != nil {
+			step = *parts[2]
+		}
+		start, stop := 0, len(list)
+		if step < 0 {
+			start, stop = len(list)-1, -1
+		}
+		if parts[0] != nil {
+			start = clampIndex(*parts[0], len(list), step)
+		}
+		if parts[1] != nil {
+			stop = clampIndex(*parts[1], len(list), step)
+		}
+		slice := []interface{}{}
+		for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+			slice = append(slice, list[i])
+		}
+		return slice
+	}
+}
+
+// clampIndex converts a negative index and limits the index to the list.
+func clampIndex(index, length, step int) int {
+	if index < 0 {
+		index += length
+	}
+	switch {
+	case index < 0 && step < 0:
+		return -1
+	case index < 0:
+		return 0
+	case index >= length && step < 0:
+		return length - 1
+	case index >= length:
+		return length
+	}
+	return index
+}
+
+// flattenQuery merges the lists in a list into the list.
+func flattenQuery(left queryFunc) queryFunc {
+	return func(value interface{}) interface{} {
+		list, ok := left(value).([]interface{})
+		if !ok {
+			return nil
+		}
+		flattened := []interface{}{}
+		for _, item := range list {
+			if inner, ok := item.([]interface{}); ok {
+				flattened = append(flattened, inner...)
+			} else {
+				flattened = append(flattened, item)
+			}
+		}
+		return flattened
+	}
+}
+
+// listProjection applies right on every element of the list, the null results are dropped.
+func listProjection(left, right queryFunc) queryFunc {
+	return func(value interface{}) interface{} {
+		list, ok := left(value).([]interface{})
+		if !ok {
+			return nil
+		}
+		return projectList(list, right)
+	}
+}
+
+// valuesProjection applies right on every value of the object, in the order of the keys.
+func valuesProjection(left, right queryFunc) queryFunc {
+	return func(value interface{}) interface{} {
+		object, ok := left(value).(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		return projectList(valuesFunction([]interface{}{object}).([]interface{}), right)
+	}
+}
+
+func projectList(list []interface{}, query queryFunc) []interface{} {
+	result := []interface{}{}
+	for _, item := range list {
+		if value := query(item); value != nil {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// compareQuery compares the results of two expressions, the result is null if the values
+// can not be compared.
+func compareQuery(left queryFunc, operator string, right queryFunc) queryFunc {
+	return func(value interface{}) interface{} {
+		a, b := left(value), right(value)
+		switch operator {
+		case "==":
+			return equalValues(a, b)
+		case "!=":
+			return !equalValues(a, b)
+		}
+		x, okX := toNumber(a)
+		y, okY := toNumber(b)
+		if !okX || !okY {
+			return nil
+		}
+		switch operator {
+		case "<":
+			return x < y
+		case "<=":
+			return x <= y
+		case ">":
+			return x > y
+		}
+		return x >= y
+	}
+}
+
+// equalValues compares json values, numbers are equal if they have the same value.
+func equalValues(a, b interface{}) bool {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalValues(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equalValues(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	if number, ok := value.(json.Number); ok {
+		f, err := number.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// isTrue returns the truth value of a json value in JMESPath.
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// lengthFunction returns the number of characters of a string or the number of elements of a
+// list or an object.
+func lengthFunction(arguments []interface{}) interface{} {
+	length := 0
+	switch v := arguments[0].(type) {
+	case string:
+		length = utf8.RuneCountInString(v)
+	case []interface{}:
+		length = len(v)
+	case map[string]interface{}:
+		length = len(v)
+	default:
+		return nil
+	}
+	return json.Number(strconv.Itoa(length))
+}
+
+// keysFunction returns the sorted keys of an object.
+func keysFunction(arguments []interface{}) interface{} {
+	object, ok := arguments[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	keys := sortedKeys(object)
+	result := make([]interface{}, len(keys))
+	for i, key := range keys {
+		result[i] = key
+	}
+	return result
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valuesFunction returns the values of an object in the order of the keys.
+func valuesFunction(arguments []interface{}) interface{} {
+	object, ok := arguments[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	keys := keysFunction(arguments).([]interface{})
+	for i, key := range keys {
+		keys[i] = object[key.(string)]
+	}
+	return keys
+}
+
+// containsFunction checks if a list contains a value or a string contains a string.
+func containsFunction(arguments []interface{}) interface{} {
+	switch subject := arguments[0].(type) {
+	case string:
+		search, ok := arguments[1].(string)
+		return ok && strings.Contains(subject, search)
+	case []interface{}:
+		for _, item := range subject {
+			if equalValues(item, arguments[1]) {
+				return true
+			}
+		}
+		return false
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package command
+
+import (
+	"testing"
+)
+
+// Test the queries that select a part of a list of servers.
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`[0].id`, `34`},
+		{`[1].name`, `"db"`},
+		{`[-1].name`, `"db"`},
+		{`[5].name`, `null`},
+		{`[*].id`, `[34,35]`},
+		{`[].name`, `["web 1","db"]`},
+		{`name`, `null`},
+		{`[1:].id`, `[35]`},
+		{`[?state=='ENABLED'].name`, `["web 1"]`},
+		{`[?state == 'INACTIVE'].id`, `[35]`},
+		{`[?id > ` + "`34`" + ` && type].name`, `["db"]`},
+		{`[?!(id >= ` + "`35`" + `) || type == 'mysql'] | length(@)`, `2`},
+		{`[?id == ` + "`35`" + `] | [0].source`, `"cli"`},
+		{`[?contains(name, 'web')].id`, `[34]`},
+		{`[].attributes[]`, `[{"key":"os"}]`},
+		{`[].attributes[].key`, `["os"]`},
+		{`[].{id: id, os: attributes[0].key}`, `[{"id":34,"os":null},{"id":35,"os":"os"}]`},
+		{`[].[id, name]`, `[[34,"web 1"],[35,"db"]]`},
+		{`[*].name | [0]`, `"web 1"`},
+		{`[0] | keys(@)`, `["attributes","id","name","source","state","type"]`},
+		{`length(@)`, `2`},
+		{`@`, `[{"attributes":[],"id":34,"name":"web 1","source":"CoScale Agent","state":"ENABLED","type":""},{"attributes":[{"key":"os"}],"id":35,"name":"db","source":"cli","state":"INACTIVE","type":"mysql"}]`},
+	}
+	for _, test := range tests {
+		query, err := compileQuery(test.query)
+		if err != nil {
+			t.Fatalf("Error occured while compiling %s: %s", test.query, err)
+		}
+		obtained, err := applyQuery(servers, query, true)
+		if err != nil {
+			t.Fatalf("Error occured while applying %s: %s", test.query, err)
+		}
+		if obtained != test.expected {
+			t.Fatalf("%s expected: %s, found: %s", test.query, test.expected, obtained)
+		}
+	}
+}
+
+// queryDocument is a nested result to test the JMESPath semantics of every supported expression.
+var queryDocument = `{
+ "app": "shop",
+ "servers": [
+  {"id": 1, "name": "web", "cpu": 0.5, "up": true, "tags": ["a", "b"], "disk": {"size": 100}},
+  {"id": 2, "name": "db", "cpu": 1.5, "up": false, "tags": [], "disk": {"size": 200}},
+  {"id": 3, "name": "cache", "cpu": null, "up": true, "tags": ["b"], "disk": null}
+ ],
+ "a key": {"name": "quoted"}
+}`
+
+// Test the supported JMESPath expressions on a nested result.
+func TestQueryGrammar(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		// Fields, the field of a value that is not an object is null.
+		{`app`, `"shop"`},
+		{`@.app`, `"shop"`},
+		{`servers[0].disk.size`, `100`},
+		{`"a key".name`, `"quoted"`},
+		{`"a\u0020key".name`, `"quoted"`},
+		{`servers.name`, `null`},
+		{`app.name`, `null`},
+		{`missing.name`, `null`},
+
+		// Indexes and slices, a slice is a projection.
+		{`servers[-1].id`, `3`},
+		{`servers[-4]`, `null`},
+		{`app[0]`, `null`},
+		{`servers[:2].id`, `[1,2]`},
+		{`servers[1:2].id`, `[2]`},
+		{`servers[-2:].id`, `[2,3]`},
+		{`servers[2:1]`, `[]`},
+		{`servers[5:].id`, `[]`},
+		{`servers[::2].id`, `[1,3]`},
+		{`servers[::-1].id`, `[3,2,1]`},
+		{`app[:1]`, `null`},
+
+		// Projections drop the null results, [] flattens the nested lists.
+		{`servers[*].tags`, `[["a","b"],[],["b"]]`},
+		{`servers[*].tags[0]`, `["a","b"]`},
+		{`servers[*].disk.size`, `[100,200]`},
+		{`servers[].tags[]`, `["a","b","b"]`},
+		{`servers[*].tags[]`, `["a","b","b"]`},
+		{`[servers[0].tags, servers[2].tags][]`, `["a","b","b"]`},
+		{`servers[0].disk.*`, `[100]`},
+		{`*.name`, `["quoted"]`},
+		{`servers[0].disk[*]`, `null`},
+		{`app[]`, `null`},
+
+		// Filters with every comparison operator, < and > only compare numbers.
+		{`servers[?id == ` + "`2`" + `].name`, `["db"]`},
+		{`servers[?id != ` + "`2`" + `].name`, `["web","cache"]`},
+		{`servers[?id < ` + "`2`" + `].name`, `["web"]`},
+		{`servers[?id <= ` + "`2`" + `].name`, `["web","db"]`},
+		{`servers[?id > ` + "`2`" + `].name`, `["cache"]`},
+		{`servers[?id >= ` + "`2`" + `].name`, `["db","cache"]`},
+		{`servers[?cpu > ` + "`1`" + `].name`, `["db"]`},
+		{`servers[?cpu == ` + "`0.50`" + `].id`, `[1]`},
+		{`servers[?name > 'cache'].id`, `[]`},
+		{`servers[?'db' == name].id`, `[2]`},
+		{`servers[?cpu == ` + "`null`" + `].id`, `[3]`},
+		{`servers[?up == ` + "`true`" + `].id`, `[1,3]`},
+		{`servers[?tags == ` + "`[\"b\"]`" + `].id`, `[3]`},
+		{`servers[?tags[0] == 'b'].id`, `[3]`},
+		{`servers[?disk.size >= ` + "`200`" + `].id`, `[2]`},
+
+		// Truth values, !, && and || with parentheses, && binds tighter than ||.
+		{`servers[?tags].id`, `[1,3]`},
+		{`servers[?id].id`, `[1,2,3]`},
+		{`servers[?!up].id`, `[2]`},
+		{`servers[?up && cpu].id`, `[1]`},
+		{`servers[?id == ` + "`1`" + ` || id == ` + "`2`" + ` && up].id`, `[1]`},
+		{`servers[?(id == ` + "`1`" + ` || id == ` + "`3`" + `) && !(name == 'web')].name`, `["cache"]`},
+		{`app || 'none'`, `"shop"`},
+		{`missing || 'none'`, `"none"`},
+		{`app && missing`, `null`},
+		{"`0` && 'zero is true'", `"zero is true"`},
+		{`!app`, `false`},
+
+		// Literals.
+		{`'it\'s'`, `"it's"`},
+		{"`{\"a\": [1, 2]}`.a[1]", `2`},
+
+		// Multi-select lists and hashes, they are null on null.
+		{`servers[].[id, length(tags)]`, `[[1,2],[2,0],[3,1]]`},
+		{`servers[0].{id: id, size: disk.size}`, `{"id":1,"size":100}`},
+		{`{names: length(servers), app: app}`, `{"app":"shop","names":3}`},
+		{`{"a key": "a key".name}`, `{"a key":"quoted"}`},
+		{`missing.[a, b]`, `null`},
+		{`missing.{a: a}`, `null`},
+
+		// Functions, they return null for arguments of the wrong type.
+		{`keys(@)`, `["a key","app","servers"]`},
+		{`values(servers[0].disk)`, `[100]`},
+		{`length(app)`, `4`},
+		{`length('été')`, `3`},
+		{`length(servers[0].tags)`, `2`},
+		{`length(servers[0].id)`, `null`},
+		{`contains(servers[0].tags, 'b')`, `true`},
+		{`contains(app, 'ho')`, `true`},
+		{`contains(servers[].name, 'db')`, `true`},
+		{`servers[?contains(tags, 'b')].name`, `["web","cache"]`},
+
+		// A pipe ends the projection, the expression after it is applied on the whole list.
+		{`servers[].id | [0]`, `1`},
+		{`servers[].id[0]`, `[]`},
+		{`servers[].name | length(@)`, `3`},
+		{`servers | [0] | name`, `"web"`},
+		{`servers[?disk.size > ` + "`150`" + `] | [0].name`, `"db"`},
+		{`servers[?up] | [-1] | tags | [0]`, `"b"`},
+	}
+	for _, test := range tests {
+		query, err := compileQuery(test.query)
+		if err != nil {
+			t.Fatalf("Error occured while compiling %s: %s", test.query, err)
+		}
+		obtained, err := applyQuery(queryDocument, query, true)
+		if err != nil {
+			t.Fatalf("Error occured while applying %s: %s", test.query, err)
+		}
+		if obtained != test.expected {
+			t.Fatalf("%s expected: %s, found: %s", test.query, test.expected, obtained)
+		}
+	}
+}
+
+// Test that invalid queries are reported.
+func TestQueryErrors(t *testing.T) {
+	invalid := []string{``, `[0`, `[?name=='x]`, `id |`, `{id: }`, `{id}`, `{}`, `[0]]`, `length(`,
+		`[1:x]`, `[::0]`, `[?id ==]`, `[?id == 1]`, `[?id = ` + "`1`" + `]`, `[?id == ` + "`[1`" + `]`,
+		`[?id == ` + "`1" + `]`, `[?(id == ` + "`1`" + `]`, `"a\x"`, `"a"(@)`, `+ 1`, `&id`,
+		`.name`, `$[0]`, `..name`, `select(up)`, `length(a, b)`, `keys()`}
+	for _, query := range invalid {
+		if _, err := compileQuery(query); err == nil {
+			t.Fatalf("expected an error for query %s", query)
+		}
+	}
+}
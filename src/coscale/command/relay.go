@@ -0,0 +1,338 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"coscale/api"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var relayObjectName = "relay"
+
+// RelayObject defines the relay command on the CLI.
+var RelayObject = NewCommand(relayObjectName, "relay <protocol> [--<field>='<data>']", RelayActions)
+
+// RelayActions defines the relay protocols on the CLI.
+var RelayActions = []*Command{
+	{
+		Name:      "graphite",
+		UsageLine: "relay graphite [--listen --rules --subject --create --batch-size --flush-interval]",
+		Long: `
+Receive data in the Carbon plaintext protocol of Graphite and insert it into the datastore.
+The relay listens on TCP and UDP on the same port until it is stopped with SIGINT or SIGTERM,
+the buffered data is sent before the relay stops. Every line contains a dotted path, a value
+and a unix timestamp:
+	<path> <value> [<timestamp>]
+	eg: servers.web-1.cpu.load 0.82 1465839830
+
+Without rules the path is the metric name and the data is inserted for --subject. The tags of
+tagged paths, e.g. cpu.load;core=0, are the dimension values. Lines with an error and paths
+without a matching rule are reported on stderr and skipped.
+
+The flags for graphite relay are:
+
+Optional:
+	--listen
+		The address to listen on for TCP and UDP. [default: :2003]
+	--rules
+		A file with rules that map the paths to metrics, servers and dimensions. Every line is
+		a rule, the first rule that matches the path is used:
+			<pattern> metric=<name> [server=<name>] [<dimension>=<value>...]
+		The * in a pattern matches one part of the path, the parts are used in the names and
+		values as $1, $2, ... Lines that start with # are comments.
+			eg: servers.*.cpu.* metric=cpu.$2 server=$1
+			    app.requests.*.count metric=requests status=$1
+	--subject
+		The subject for the data without server: A for the application, S<server id> or a
+		server name. [default: A]
+	--create
+		Create the metrics and dimensions that do not exist. The metrics are created with data
+		type DOUBLE and a period of 60 seconds. [default: false]
+	--batch-size
+		The size in bytes of the data that is sent at once. [default: 1048576]
+	--flush-interval
+		The maximum time data is kept before it is sent, e.g. 10s or 1m. [default: 10s]
+`,
+		Run: func(cmd *Command, args []string) {
+			var listen, rulesFile, subject string
+			var create bool
+			var batchSize int
+			var flushInterval time.Duration
+			cmd.Flag.Usage = func() { cmd.PrintUsage() }
+			cmd.Flag.StringVar(&listen, "listen", ":2003", "The address to listen on for TCP and UDP.")
+			cmd.Flag.StringVar(&rulesFile, "rules", DEFAULT_STRING_FLAG_VALUE, "A file with rules that map the paths to metrics.")
+			cmd.Flag.StringVar(&subject, "subject", "A", "The subject: A, S<server id> or a server name.")
+			cmd.Flag.BoolVar(&create, "create", false, "Create the metrics and dimensions that do not exist.")
+			cmd.Flag.IntVar(&batchSize, "batch-size", 1024*1024, "The size in bytes of the data that is sent at once.")
+			cmd.Flag.DurationVar(&flushInterval, "flush-interval", 10*time.Second, "The maximum time data is kept before it is sent.")
+			cmd.ParseArgs(args)
+
+			var rules []*graphiteRule
+			if rulesFile != DEFAULT_STRING_FLAG_VALUE {
+				var err error
+				if rules, err = readGraphiteRules(rulesFile); err != nil {
+					fmt.Fprintln(os.Stderr, err.Error())
+					os.Exit(EXIT_FLAG_ERROR)
+				}
+			}
+			ctx := context.Background()
+			subjectID, err := resolveSubject(cmd.Capi.NewNameCache(ctx), subject)
+			if err != nil {
+				cmd.PrintResult("", err)
+			}
+			converter := &graphiteConverter{metricResolver: newMetricResolver(ctx, cmd.Capi, create), rules: rules, subject: subjectID}
+			runRelay(cmd, listen, converter.convert, batchSize, flushInterval)
+		},
+	},
+	{
+		Name:      "statsd",
+		UsageLine: "relay statsd [--listen --subject --subject-tag --percentile-width --create --flush-interval]",
+		Long: `
+Receive StatsD counters, gauges, timers and sets, aggregate them and insert the aggregated data
+into the datastore every flush interval. The relay listens on UDP and TCP on the same port until
+it is stopped with SIGINT or SIGTERM, the aggregated data is sent before the relay stops. Every
+line contains a sample, DogStatsD tags are the dimension values:
+	<name>:<value>|<type>[|@<sample rate>][|#<tag>:<value>,...]
+	eg: api.requests:1|c|@0.1|#status:200
+
+The name is the metric name, the data of a flush interval is sent as follows:
+	c (counter)
+		The sum of the values, corrected for the sample rate.
+	g (gauge)
+		The last value, a value with a + or - sign changes the gauge.
+	ms or h (timer)
+		A histogram of the values: [<samples>,<percentile width>,[<percentiles>]]
+	s (set)
+		The number of unique values.
+Only the metrics that received samples in the flush interval are sent. Lines with an error are
+reported on stderr and skipped.
+
+The flags for statsd relay are:
+
+Optional:
+	--listen
+		The address to listen on for UDP and TCP. [default: :8125]
+	--subject
+		The subject for the data: A for the application, S<server id> or a server name. [default: A]
+	--subject-tag
+		The tag with the server name of the data, e.g. host. The tag is not used as dimension.
+	--percentile-width
+		The width of the percentiles of the timer histograms, 100 should be a multiple of the
+		width. [default: 10]
+	--create
+		Create the metrics and dimensions that do not exist. The timers are created with data
+		type HISTOGRAM, the other metrics with DOUBLE. The period of the metrics is the flush
+		interval. [default: false]
+	--flush-interval
+		The interval of the aggregation, e.g. 10s or 1m. [default: 10s]
+`,
+		Run: func(cmd *Command, args []string) {
+			var listen, subject, subjectTag string
+			var create bool
+			var percentileWidth int
+			var flushInterval time.Duration
+			cmd.Flag.Usage = func() { cmd.PrintUsage() }
+			cmd.Flag.StringVar(&listen, "listen", ":8125", "The address to listen on for UDP and TCP.")
+			cmd.Flag.StringVar(&subject, "subject", "A", "The subject: A, S<server id> or a server name.")
+			cmd.Flag.StringVar(&subjectTag, "subject-tag", "", "The tag with the server name.")
+			cmd.Flag.IntVar(&percentileWidth, "percentile-width", 10, "The width of the percentiles of the timer histograms.")
+			cmd.Flag.BoolVar(&create, "create", false, "Create the metrics and dimensions that do not exist.")
+			cmd.Flag.DurationVar(&flushInterval, "flush-interval", 10*time.Second, "The interval of the aggregation.")
+			cmd.ParseArgs(args)
+
+			if !api.ValidPercentileWidth(percentileWidth) {
+				fmt.Fprintln(os.Stderr, "Invalid percentile width, 100 should be a multiple of the width.")
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+			if flushInterval < time.Second {
+				fmt.Fprintln(os.Stderr, "The flush interval should be at least 1s.")
+				os.Exit(EXIT_FLAG_ERROR)
+			}
+			ctx := context.Background()
+			subjectID, err := resolveSubject(cmd.Capi.NewNameCache(ctx), subject)
+			if err != nil {
+				cmd.PrintResult("", err)
+			}
+			aggregator := newStatsdAggregator(newMetricResolver(ctx, cmd.Capi, create), subjectTag, subjectID, percentileWidth)
+			aggregator.period = int(flushInterval / time.Second)
+
+			listener, err := listenLines(listen)
+			if err != nil {
+				cmd.PrintResult("", err)
+			}
+			stopOnSignal(listener)
+			flush := func() ([]*api.ApiData, []error) {
+				return aggregator.flush(int(time.Now().Unix()))
+			}
+			if failed := aggregateData(listener, os.Stderr, cmd.insertFunc(), aggregator.add, flush, flushInterval); failed {
+				os.Exit(EXIT_SUCCESS_ERROR)
+			}
+			os.Exit(EXIT_SUCCESS)
+		},
+	},
+}
+
+// runRelay receives lines on address and inserts the data until the relay is stopped with SIGINT
+// or SIGTERM, the lines are converted using parse.
+func runRelay(cmd *Command, address string, parse func(line string) ([]*api.ApiData, error), batchSize int, flushInterval time.Duration) {
+	listener, err := listenLines(address)
+	if err != nil {
+		cmd.PrintResult("", err)
+	}
+	stopOnSignal(listener)
+	if failed := streamData(listener, os.Stderr, cmd.insertFunc(), parse, batchSize, flushInterval); failed {
+		os.Exit(EXIT_SUCCESS_ERROR)
+	}
+	os.Exit(EXIT_SUCCESS)
+}
+
+// stopOnSignal closes the listener on SIGINT or SIGTERM.
+func stopOnSignal(listener *lineListener) {
+	fmt.Fprintf(os.Stderr, "Listening on %s (tcp and udp)\n", listener.Addr())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+}
+
+// lineListener receives lines over TCP and UDP on the same port. The lines of all connections and
+// datagrams are read with Read, after Close Read returns io.EOF once the received lines are read.
+type lineListener struct {
+	tcp    net.Listener
+	udp    net.PacketConn
+	reader *io.PipeReader
+	writer *io.PipeWriter
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+// listenLines starts a lineListener on address, e.g. :2003.
+func listenLines(address string) (*lineListener, error) {
+	tcp, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	// The UDP port is the TCP port, also if the port was chosen by the system.
+	udp, err := net.ListenPacket("udp", tcp.Addr().String())
+	if err != nil {
+		tcp.Close()
+		return nil, err
+	}
+	reader, writer := io.Pipe()
+	l := &lineListener{tcp: tcp, udp: udp, reader: reader, writer: writer, conns: make(map[net.Conn]bool)}
+	l.wg.Add(2)
+	go l.acceptTCP()
+	go l.readUDP()
+	return l, nil
+}
+
+// Addr returns the address of the listener.
+func (l *lineListener) Addr() net.Addr {
+	return l.tcp.Addr()
+}
+
+// Read reads the received lines, a write of a connection or datagram is never split.
+func (l *lineListener) Read(p []byte) (int, error) {
+	return l.reader.Read(p)
+}
+
+// Close stops the listener and closes the open connections.
+func (l *lineListener) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	l.tcp.Close()
+	l.udp.Close()
+	// The pipe is closed when the lines that are being written are read.
+	go func() {
+		l.wg.Wait()
+		l.writer.Close()
+	}()
+	return nil
+}
+
+// track adds or removes an open connection, false is returned if the listener is closed.
+func (l *lineListener) track(conn net.Conn, open bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !open {
+		delete(l.conns, conn)
+		return true
+	}
+	if l.closed {
+		return false
+	}
+	l.conns[conn] = true
+	return true
+}
+
+func (l *lineListener) acceptTCP() {
+	defer l.wg.Done()
+	for {
+		conn, err := l.tcp.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if !l.track(conn, true) {
+			conn.Close()
+			return
+		}
+		l.wg.Add(1)
+		go l.readTCP(conn)
+	}
+}
+
+func (l *lineListener) readTCP(conn net.Conn) {
+	defer l.wg.Done()
+	defer l.track(conn, false)
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), api.MaxUploadSize)
+	for scanner.Scan() {
+		if _, err := io.WriteString(l.writer, scanner.Text()+"\n"); err != nil {
+			return
+		}
+	}
+}
+
+func (l *lineListener) readUDP() {
+	defer l.wg.Done()
+	buffer := make([]byte, 64*1024)
+	for {
+		n, _, err := l.udp.ReadFrom(buffer)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil || n == 0 {
+			continue
+		}
+		// A datagram can contain several lines.
+		lines := string(buffer[:n])
+		if !strings.HasSuffix(lines, "\n") {
+			lines += "\n"
+		}
+		if _, err := io.WriteString(l.writer, lines); err != nil {
+			return
+		}
+	}
+}
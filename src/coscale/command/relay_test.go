@@ -0,0 +1,66 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Test that lineListener receives the lines over TCP and UDP and ends after Close.
+func TestLineListener(t *testing.T) {
+	listener, err := listenLines("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(listener)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	tcp, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	fmt.Fprint(tcp, "a 1\nb 2\n")
+	udp, err := net.Dial("udp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	fmt.Fprint(udp, "c 3\nd 4")
+	udp.Close()
+
+	var found []string
+	for len(found) < 4 {
+		select {
+		case line := <-lines:
+			found = append(found, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected: 4 lines, found: %v", found)
+		}
+	}
+	sort.Strings(found)
+	if expected := []string{"a 1", "b 2", "c 3", "d 4"}; !reflect.DeepEqual(expected, found) {
+		t.Fatalf("expected: %v, found: %v", expected, found)
+	}
+
+	// Close also closes the open TCP connection.
+	listener.Close()
+	select {
+	case line, ok := <-lines:
+		if ok {
+			t.Fatalf("expected: the end of the lines, found: %s", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected: the end of the lines after Close")
+	}
+	tcp.Close()
+}
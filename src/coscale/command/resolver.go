@@ -0,0 +1,132 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"fmt"
+	"strings"
+)
+
+// metricResolver finds the metrics for data by name, if create is true the metrics and
+// dimensions that do not exist are created.
+type metricResolver struct {
+	ctx    context.Context
+	capi   *api.Api
+	names  *api.NameCache
+	create bool
+	// period is the period in seconds of the created metrics.
+	period  int
+	metrics map[string]*resolvedMetric
+	// dimensions contains the dimension ids by name, only used if create is true.
+	dimensions map[string]int64
+}
+
+// resolvedMetric is a metric that was found or created by a metricResolver.
+type resolvedMetric struct {
+	id int64
+	// dimensions contains the names of the dimensions of the metric, only used if create is true.
+	dimensions map[string]bool
+}
+
+// newMetricResolver creates a metricResolver, ctx is used for the API calls.
+func newMetricResolver(ctx context.Context, capi *api.Api, create bool) *metricResolver {
+	return &metricResolver{
+		ctx:        ctx,
+		capi:       capi,
+		names:      capi.NewNameCache(ctx),
+		create:     create,
+		period:     60,
+		metrics:    make(map[string]*resolvedMetric),
+		dimensions: make(map[string]int64),
+	}
+}
+
+// serverSubject returns the subject S<server id> for a server name.
+func (c *metricResolver) serverSubject(server string) (string, error) {
+	serverID, err := c.names.ID("server", server)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("S%d", serverID), nil
+}
+
+// metricID returns the id of a metric. If create is true, the metric is created with dataType and
+// the subject type of subject and the dimensions are added to the metric if they are missing.
+func (c *metricResolver) metricID(name, dataType, subject string, dimensionValues map[string]string) (int64, error) {
+	metric, ok := c.metrics[name]
+	if !ok {
+		var err error
+		if metric, err = c.findMetric(name, dataType, subject); err != nil {
+			return 0, err
+		}
+		c.metrics[name] = metric
+	}
+	if !c.create {
+		return metric.id, nil
+	}
+
+	for _, dimension := range objectNames(dimensionValues) {
+		if metric.dimensions[dimension] {
+			continue
+		}
+		dimensionID, err := c.dimensionID(dimension)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := c.capi.AddMetricDimensionContext(c.ctx, metric.id, dimensionID); err != nil {
+			if duplicate, _ := api.IsDuplicate(err); !duplicate {
+				return 0, err
+			}
+		}
+		metric.dimensions[dimension] = true
+	}
+	return metric.id, nil
+}
+
+// findMetric finds a metric by name. If create is true, the metric is created if it does not
+// exist and the names of its dimensions are loaded.
+func (c *metricResolver) findMetric(name, dataType, subject string) (*resolvedMetric, error) {
+	if !c.create {
+		id, err := c.names.ID("metric", name)
+		if err != nil {
+			return nil, err
+		}
+		return &resolvedMetric{id: id}, nil
+	}
+
+	subjectType := "APPLICATION"
+	if strings.HasPrefix(subject, "S") {
+		subjectType = "SERVER"
+	}
+	// CreateMetric returns the existing metric if there is a metric with the name.
+	var metric api.Metric
+	result, err := c.capi.CreateMetricContext(c.ctx, name, "", dataType, "", subjectType, c.period)
+	if err := decode(result, err, &metric); err != nil {
+		return nil, err
+	}
+	var dimensions []*api.Dimension
+	result, err = c.capi.GetDimensionsContext(c.ctx, metric.ID)
+	if err := decode(result, err, &dimensions); err != nil {
+		return nil, err
+	}
+	found := &resolvedMetric{id: metric.ID, dimensions: make(map[string]bool)}
+	for _, dimension := range dimensions {
+		found.dimensions[dimension.Name] = true
+	}
+	return found, nil
+}
+
+// dimensionID returns the id of a dimension, the dimension is created if it does not exist.
+func (c *metricResolver) dimensionID(name string) (int64, error) {
+	if id, ok := c.dimensions[name]; ok {
+		return id, nil
+	}
+	// CreateDimension returns the existing dimension if there is a dimension with the name.
+	var dimension api.Dimension
+	result, err := c.capi.CreateDimensionContext(c.ctx, name)
+	if err := decode(result, err, &dimension); err != nil {
+		return 0, err
+	}
+	c.dimensions[name] = dimension.ID
+	return dimension.ID, nil
+}
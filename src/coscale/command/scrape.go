@@ -0,0 +1,227 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ScrapeObject defines the scrape command on the CLI.
+var ScrapeObject = &Command{
+	Name:      "scrape",
+	UsageLine: "scrape (--url) [--interval --timeout --subject --subject-label --create]",
+	Long: `
+Scrape an endpoint in the Prometheus text or OpenMetrics format, e.g. of an exporter, and insert
+the samples into the datastore. The endpoint is scraped every interval until the command is
+stopped with SIGINT or SIGTERM. Errors are reported on stderr.
+
+The name of a sample is the metric name, e.g. http_requests_total, and the labels are the
+dimension values. The metrics are created with the data type of the metric family:
+	counter
+		COUNTER
+	histogram, summary
+		COUNTER for the _bucket, _sum and _count samples, DOUBLE for the quantiles of a summary.
+	gauge, untyped, ...
+		DOUBLE
+The _created samples and the samples with the value NaN or +/-Inf are skipped.
+
+The flags for scrape are:
+
+Mandatory:
+	--url
+		The URL of the endpoint, e.g. http://localhost:9100/metrics.
+Optional:
+	--interval
+		The interval between the scrapes, e.g. 60s. 0 scrapes the endpoint once. [default: 60s]
+	--timeout
+		The timeout of a scrape. [default: 10s]
+	--subject
+		The subject for the data: A for the application, S<server id> or a server name. [default: A]
+	--subject-label
+		The label with the server name of the data, e.g. instance. The label is not used as dimension.
+	--create
+		Create the metrics and dimensions that do not exist. The period of the metrics is the
+		interval. [default: false]
+`,
+	Run: func(cmd *Command, args []string) {
+		var url, subject, subjectLabel string
+		var interval, timeout time.Duration
+		var create bool
+		cmd.Flag.Usage = func() { cmd.PrintUsage() }
+		cmd.Flag.StringVar(&url, "url", DEFAULT_STRING_FLAG_VALUE, "The URL of the endpoint.")
+		cmd.Flag.DurationVar(&interval, "interval", 60*time.Second, "The interval between the scrapes.")
+		cmd.Flag.DurationVar(&timeout, "timeout", 10*time.Second, "The timeout of a scrape.")
+		cmd.Flag.StringVar(&subject, "subject", "A", "The subject: A, S<server id> or a server name.")
+		cmd.Flag.StringVar(&subjectLabel, "subject-label", "", "The label with the server name.")
+		cmd.Flag.BoolVar(&create, "create", false, "Create the metrics and dimensions that do not exist.")
+		cmd.ParseArgs(args)
+
+		if url == DEFAULT_STRING_FLAG_VALUE {
+			cmd.PrintUsage()
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		if interval != 0 && interval < time.Second {
+			fmt.Fprintln(os.Stderr, "The interval should be at least 1s.")
+			os.Exit(EXIT_FLAG_ERROR)
+		}
+		ctx := context.Background()
+		subjectID, err := resolveSubject(cmd.Capi.NewNameCache(ctx), subject)
+		if err != nil {
+			cmd.PrintResult("", err)
+		}
+		scraper := &prometheusScraper{
+			metricResolver: newMetricResolver(ctx, cmd.Capi, create),
+			client:         &http.Client{Timeout: timeout},
+			url:            url,
+			subjectLabel:   subjectLabel,
+			subject:        subjectID,
+		}
+		if interval > 0 {
+			scraper.period = int(interval / time.Second)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		var ticker *time.Ticker
+		if interval > 0 {
+			ticker = time.NewTicker(interval)
+			defer ticker.Stop()
+		}
+		failed := false
+		for stopped := false; !stopped; {
+			data, errs := scraper.scrape(int(time.Now().Unix()))
+			for _, err := range errs {
+				fmt.Fprintln(os.Stderr, err.Error())
+				failed = true
+			}
+			if !sendData(data, cmd.insertFunc(), os.Stderr) {
+				failed = true
+			}
+			if ticker == nil {
+				break
+			}
+			select {
+			case <-ticker.C:
+			case <-signals:
+				stopped = true
+			}
+		}
+		if failed {
+			os.Exit(EXIT_SUCCESS_ERROR)
+		}
+		os.Exit(EXIT_SUCCESS)
+	},
+}
+
+// prometheusScraper scrapes an endpoint in the Prometheus text or OpenMetrics format.
+type prometheusScraper struct {
+	*metricResolver
+	client *http.Client
+	url    string
+	// subjectLabel is the label with the server name, subject is used for the samples without the label.
+	subjectLabel string
+	subject      string
+}
+
+// scrape gets the samples of the endpoint and converts them into data, timestamp is the time of the
+// samples without timestamp. The errors of the samples are returned once per metric.
+func (s *prometheusScraper) scrape(timestamp int) ([]*api.ApiData, []error) {
+	samples, err := s.fetch()
+	if err != nil {
+		return nil, []error{fmt.Errorf("Could not scrape %s: %s", s.url, err)}
+	}
+
+	var data []*api.ApiData
+	var errs []error
+	failed := make(map[string]bool)
+	for _, sample := range samples {
+		dataType := prometheusDataType(sample)
+		if dataType == "" || failed[sample.Name] {
+			continue
+		}
+		apiData, err := s.convert(sample, dataType, timestamp)
+		if err != nil {
+			failed[sample.Name] = true
+			errs = append(errs, fmt.Errorf("%s: %s", sample.Name, err))
+			continue
+		}
+		data = append(data, apiData)
+	}
+	return data, errs
+}
+
+// fetch gets and parses the samples of the endpoint.
+func (s *prometheusScraper) fetch() ([]*api.PrometheusSample, error) {
+	req, err := http.NewRequest("GET", s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5,*/*;q=0.1")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	openMetrics := strings.HasPrefix(resp.Header.Get("Content-Type"), "application/openmetrics-text")
+	return api.ParsePrometheusText(resp.Body, openMetrics)
+}
+
+// convert converts a sample into data, the labels except the subject label are the dimension values.
+func (s *prometheusScraper) convert(sample *api.PrometheusSample, dataType string, timestamp int) (*api.ApiData, error) {
+	subject := s.subject
+	var dimensionValues map[string]string
+	for name, value := range sample.Labels {
+		if name == s.subjectLabel {
+			var err error
+			if subject, err = s.serverSubject(value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if dimensionValues == nil {
+			dimensionValues = make(map[string]string)
+		}
+		dimensionValues[name] = value
+	}
+	metricID, err := s.metricID(sample.Name, dataType, subject, dimensionValues)
+	if err != nil {
+		return nil, err
+	}
+	if sample.Time != 0 {
+		timestamp = sample.Time
+	}
+	return &api.ApiData{
+		MetricID:        metricID,
+		SubjectID:       subject,
+		Data:            []api.DataPoint{{SecondsAgo: timestamp, Data: sample.Value}},
+		DimensionValues: dimensionValues,
+	}, nil
+}
+
+// prometheusDataType returns the data type of the metric of a sample, empty for the samples that
+// are skipped.
+func prometheusDataType(sample *api.PrometheusSample) string {
+	if sample.Name != sample.Family && strings.HasSuffix(sample.Name, "_created") {
+		return ""
+	}
+	switch sample.Type {
+	case "counter":
+		return "COUNTER"
+	case "histogram", "summary":
+		// The quantiles of a summary have the name of the family.
+		if sample.Name == sample.Family {
+			return "DOUBLE"
+		}
+		return "COUNTER"
+	}
+	return "DOUBLE"
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test a scrape of an endpoint in the Prometheus text format.
+func TestPrometheusScraper(t *testing.T) {
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, `# TYPE http_requests_total counter
+http_requests_total{instance="web-1",code="200"} 1027
+http_requests_total_created{instance="web-1",code="200"} 1395066363
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.05
+rpc_duration_seconds_count 12
+# TYPE unknown_metric gauge
+unknown_metric 1
+unknown_metric{a="b"} 2
+`)
+	}))
+	defer exporter.Close()
+
+	capi := api.NewApi("http://coscale.test", "token", "app", true, false)
+	capi.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"token":"token"}`
+		switch req.URL.Query().Get("selectByName") {
+		case "web-1":
+			body = `[{"id":12,"name":"web-1"}]`
+		case "http_requests_total":
+			body = `[{"id":1,"name":"http_requests_total"}]`
+		case "rpc_duration_seconds":
+			body = `[{"id":2,"name":"rpc_duration_seconds"}]`
+		case "rpc_duration_seconds_count":
+			body = `[{"id":3,"name":"rpc_duration_seconds_count"}]`
+		case "unknown_metric":
+			body = `[]`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+
+	scraper := &prometheusScraper{
+		metricResolver: newMetricResolver(context.Background(), capi, false),
+		client:         exporter.Client(),
+		url:            exporter.URL,
+		subjectLabel:   "instance",
+		subject:        "A",
+	}
+	data, errs := scraper.scrape(100)
+	point := func(value string) []api.DataPoint { return []api.DataPoint{{SecondsAgo: 100, Data: value}} }
+	expected := []*api.ApiData{
+		{MetricID: 1, SubjectID: "S12", Data: point("1027"), DimensionValues: map[string]string{"code": "200"}},
+		{MetricID: 2, SubjectID: "A", Data: point("0.05"), DimensionValues: map[string]string{"quantile": "0.5"}},
+		{MetricID: 3, SubjectID: "A", Data: point("12")},
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Fatalf("expected: %v, found: %v", expected, data)
+	}
+	if len(errs) != 1 || errs[0].Error() != `unknown_metric: The metric "unknown_metric" was not found` {
+		t.Fatalf("expected: one error for the unknown metric, found: %v", errs)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	scraper.url = missing.URL
+	if _, errs := scraper.scrape(100); len(errs) != 1 || !strings.Contains(errs[0].Error(), "404 Not Found") {
+		t.Fatalf("expected: the status of the endpoint, found: %v", errs)
+	}
+}
+
+// Test the data types of the samples.
+func TestPrometheusDataType(t *testing.T) {
+	tests := []struct {
+		sample   api.PrometheusSample
+		expected string
+	}{
+		{api.PrometheusSample{Name: "jobs_total", Family: "jobs", Type: "counter"}, "COUNTER"},
+		{api.PrometheusSample{Name: "jobs_created", Family: "jobs", Type: "counter"}, ""},
+		{api.PrometheusSample{Name: "latency_bucket", Family: "latency", Type: "histogram"}, "COUNTER"},
+		{api.PrometheusSample{Name: "latency", Family: "latency", Type: "summary"}, "DOUBLE"},
+		{api.PrometheusSample{Name: "temperature", Family: "temperature", Type: "gauge"}, "DOUBLE"},
+		{api.PrometheusSample{Name: "up", Family: "up", Type: "untyped"}, "DOUBLE"},
+	}
+	for _, test := range tests {
+		if found := prometheusDataType(&test.sample); found != test.expected {
+			t.Fatalf("expected: %s for %s, found: %s", test.expected, test.sample.Name, found)
+		}
+	}
+}
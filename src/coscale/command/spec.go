@@ -0,0 +1,410 @@
+package command
+
+import (
+	"bytes"
+	"coscale/api"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The configuration of an application is described by an appSpec, which is read from a YAML or
+// json file. Objects are identified by their name and refer to other objects by name, so a spec
+// can be applied on any application. The optional fields are pointers: fields that are not in the
+// spec are not changed.
+
+// appSpec describes the configuration objects of an application.
+type appSpec struct {
+	Servers      []*serverSpec      `json:"servers,omitempty"`
+	ServerGroups []*serverGroupSpec `json:"servergroups,omitempty"`
+	Dimensions   []*dimensionSpec   `json:"dimensions,omitempty"`
+	Metrics      []*metricSpec      `json:"metrics,omitempty"`
+	MetricGroups []*metricGroupSpec `json:"metricgroups,omitempty"`
+	Events       []*eventSpec       `json:"events,omitempty"`
+	AlertTypes   []*alertTypeSpec   `json:"alerttypes,omitempty"`
+}
+
+type serverSpec struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Type        *string `json:"type,omitempty"`
+	State       *string `json:"state,omitempty"`
+}
+
+type serverGroupSpec struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Type        *string `json:"type,omitempty"`
+	State       *string `json:"state,omitempty"`
+	// Parent is the name of the parent server group, empty for a root group.
+	Parent *string `json:"parent,omitempty"`
+}
+
+type dimensionSpec struct {
+	Name string `json:"name"`
+}
+
+type metricSpec struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	DataType    *string `json:"dataType,omitempty"`
+	Period      *int    `json:"period,omitempty"`
+	Unit        *string `json:"unit,omitempty"`
+	Subject     *string `json:"subject,omitempty"`
+	// Dimensions are the names of the dimensions of the metric, dimensions are only added.
+	Dimensions []string `json:"dimensions,omitempty"`
+}
+
+type metricGroupSpec struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Type        *string `json:"type,omitempty"`
+	State       *string `json:"state,omitempty"`
+	Subject     *string `json:"subject,omitempty"`
+	// Metrics are the names of the metrics in the group, without metrics they are not changed.
+	Metrics []string `json:"metrics,omitempty"`
+}
+
+type eventSpec struct {
+	Name                  string  `json:"name"`
+	Description           *string `json:"description,omitempty"`
+	AttributeDescriptions *string `json:"attributeDescriptions,omitempty"`
+	Type                  *string `json:"type,omitempty"`
+}
+
+type alertTypeSpec struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	// The handles use the format of the --handle flag, e.g. "EMAIL:support@coscale.com".
+	Handle            *string        `json:"handle,omitempty"`
+	BackupHandle      *string        `json:"backupHandle,omitempty"`
+	BackupSeconds     *int64         `json:"backupSeconds,omitempty"`
+	EscalationHandle  *string        `json:"escalationHandle,omitempty"`
+	EscalationSeconds *int64         `json:"escalationSeconds,omitempty"`
+	Triggers          []*triggerSpec `json:"triggers,omitempty"`
+}
+
+type triggerSpec struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	// Metric is the name of the metric.
+	Metric string `json:"metric"`
+	Config string `json:"config"`
+	// Server or ServerGroup is the name of the server or server group, without both the
+	// trigger is for the application.
+	Server             *string                 `json:"server,omitempty"`
+	ServerGroup        *string                 `json:"servergroup,omitempty"`
+	AutoResolveSeconds *int64                  `json:"autoresolveSeconds,omitempty"`
+	DimensionSpecs     []*triggerDimensionSpec `json:"dimensionSpecs,omitempty"`
+}
+
+// triggerDimensionSpec selects the values of a dimension of the metric of a trigger.
+type triggerDimensionSpec struct {
+	// Dimension is the name of the dimension.
+	Dimension string `json:"dimension"`
+	// Values are the dimension value ids with an optional aggregator, e.g. "*", "AVG(*)" or "11,12".
+	Values string `json:"values"`
+}
+
+// readAppSpec reads a spec from a YAML or json file, "-" reads stdin. For a directory the
+// .yaml, .yml and .json files in the directory are read and merged, e.g. a directory written by export.
+func readAppSpec(path string) (*appSpec, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return readAppSpecDir(path)
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	spec, err := decodeAppSpec(data, strings.ToLower(filepath.Ext(path)) == ".json")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return spec, nil
+}
+
+// readAppSpecDir reads and merges the spec files in a directory.
+func readAppSpecDir(dir string) (*appSpec, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	merged := &appSpec{}
+	for _, file := range files {
+		switch strings.ToLower(filepath.Ext(file.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		spec, err := readAppSpec(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(spec)
+	}
+	if err := merged.normalize(); err != nil {
+		return nil, fmt.Errorf("%s: %s", dir, err)
+	}
+	return merged, nil
+}
+
+// merge adds the objects of other to the spec. A kind that is in one of the specs stays in the
+// merged spec, also if it has no objects.
+func (s *appSpec) merge(other *appSpec) {
+	if other.Servers != nil {
+		s.Servers = append(append([]*serverSpec{}, s.Servers...), other.Servers...)
+	}
+	if other.ServerGroups != nil {
+		s.ServerGroups = append(append([]*serverGroupSpec{}, s.ServerGroups...), other.ServerGroups...)
+	}
+	if other.Dimensions != nil {
+		s.Dimensions = append(append([]*dimensionSpec{}, s.Dimensions...), other.Dimensions...)
+	}
+	if other.Metrics != nil {
+		s.Metrics = append(append([]*metricSpec{}, s.Metrics...), other.Metrics...)
+	}
+	if other.MetricGroups != nil {
+		s.MetricGroups = append(append([]*metricGroupSpec{}, s.MetricGroups...), other.MetricGroups...)
+	}
+	if other.Events != nil {
+		s.Events = append(append([]*eventSpec{}, s.Events...), other.Events...)
+	}
+	if other.AlertTypes != nil {
+		s.AlertTypes = append(append([]*alertTypeSpec{}, s.AlertTypes...), other.AlertTypes...)
+	}
+}
+
+// decodeAppSpec decodes and validates a spec, unknown fields are reported as errors.
+func decodeAppSpec(data []byte, isJSON bool) (*appSpec, error) {
+	var generic interface{}
+	if isJSON || bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&generic); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if generic, err = unmarshalYAML(data); err != nil {
+			return nil, err
+		}
+	}
+
+	spec := &appSpec{}
+	if generic != nil {
+		encoded, err := json.Marshal(generic)
+		if err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(spec); err != nil {
+			return nil, err
+		}
+	}
+	if err := spec.normalize(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// normalize checks the spec and brings the values in the format used for the live objects.
+func (s *appSpec) normalize() error {
+	names := make(map[string]bool)
+	checkName := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("a %s without a name", kind)
+		}
+		if names[kind+"/"+name] {
+			return fmt.Errorf("%s %s is defined twice", kind, name)
+		}
+		names[kind+"/"+name] = true
+		return nil
+	}
+
+	for _, server := range s.Servers {
+		if err := checkName("server", server.Name); err != nil {
+			return err
+		}
+	}
+	for _, group := range s.ServerGroups {
+		if err := checkName("servergroup", group.Name); err != nil {
+			return err
+		}
+	}
+	for _, dimension := range s.Dimensions {
+		if err := checkName("dimension", dimension.Name); err != nil {
+			return err
+		}
+	}
+	for _, metric := range s.Metrics {
+		if err := checkName("metric", metric.Name); err != nil {
+			return err
+		}
+		metric.Dimensions = sortedNames(metric.Dimensions)
+	}
+	for _, group := range s.MetricGroups {
+		if err := checkName("metricgroup", group.Name); err != nil {
+			return err
+		}
+		group.Metrics = sortedNames(group.Metrics)
+	}
+	for _, event := range s.Events {
+		if err := checkName("event", event.Name); err != nil {
+			return err
+		}
+	}
+	for _, alertType := range s.AlertTypes {
+		if err := checkName("alerttype", alertType.Name); err != nil {
+			return err
+		}
+		for _, handle := range []*string{alertType.Handle, alertType.BackupHandle, alertType.EscalationHandle} {
+			if handle == nil || *handle == "" {
+				continue
+			}
+			// Use the same format as the handles of the live objects.
+			parsed, err := api.ParseHandle(*handle)
+			if err == nil {
+				*handle, err = formatHandle(parsed)
+			}
+			if err != nil {
+				return fmt.Errorf("alerttype %s: %s", alertType.Name, err)
+			}
+		}
+		for _, trigger := range alertType.Triggers {
+			if err := checkName("trigger", alertType.Name+"/"+trigger.Name); err != nil {
+				return err
+			}
+			if trigger.Metric == "" || trigger.Config == "" {
+				return fmt.Errorf("trigger %s of alerttype %s: metric and config are required", trigger.Name, alertType.Name)
+			}
+			if trigger.Server != nil && trigger.ServerGroup != nil {
+				return fmt.Errorf("trigger %s of alerttype %s: use server or servergroup, not both", trigger.Name, alertType.Name)
+			}
+			for _, dimensionSpec := range trigger.DimensionSpecs {
+				if dimensionSpec.Dimension == "" || dimensionSpec.Values == "" {
+					return fmt.Errorf("trigger %s of alerttype %s: dimension and values are required in dimensionSpecs", trigger.Name, alertType.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// formatHandle converts a json handle into the format of the --handle flag, the contacts are
+// sorted so the order of the contacts is not compared.
+func formatHandle(handle string) (string, error) {
+	formatted, err := api.FormatHandle(handle)
+	if err != nil {
+		return "", err
+	}
+	contacts := strings.Fields(formatted)
+	sort.Strings(contacts)
+	return strings.Join(contacts, " "), nil
+}
+
+// sortedNames returns a sorted copy of names, nil stays nil.
+func sortedNames(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// specChange is a change of the live objects needed to match the spec.
+type specChange struct {
+	Action string        `json:"action"`
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	Fields []fieldChange `json:"fields,omitempty"`
+	// Reason explains why an object is skipped or not deleted.
+	Reason string `json:"reason,omitempty"`
+}
+
+// fieldChange is the old and new value of a field.
+type fieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// diffFields returns the fields that are set in desired and have another value in live, the
+// fields in always are also compared if they are not set in desired. The name is not compared.
+func diffFields(desired, live interface{}, always ...string) []fieldChange {
+	desiredFields, liveFields := toFields(desired), toFields(live)
+	for _, field := range always {
+		if _, ok := desiredFields[field]; !ok {
+			desiredFields[field] = nil
+		}
+	}
+
+	var changes []fieldChange
+	for _, field := range sortedKeys(desiredFields) {
+		if field == "name" {
+			continue
+		}
+		if !reflect.DeepEqual(desiredFields[field], liveFields[field]) {
+			changes = append(changes, fieldChange{field, liveFields[field], desiredFields[field]})
+		}
+	}
+	return changes
+}
+
+// toFields converts a spec into a map of json values.
+func toFields(spec interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return fields
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	decoder.Decode(&fields)
+	return fields
+}
+
+func stringValue(value *string, defaultValue string) string {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+func int64Value(value *int64, defaultValue int64) int64 {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+func intValue(value *int, defaultValue int) int {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
+
+func intPointer(value int) *int {
+	return &value
+}
@@ -0,0 +1,330 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// appState contains the live configuration objects of an application by name.
+type appState struct {
+	servers      map[string]*api.Server
+	serverGroups map[string]*api.ServerGroup
+	dimensions   map[string]*api.Dimension
+	metrics      map[string]*api.Metric
+	metricGroups map[string]*api.MetricGroup
+	events       map[string]*api.Event
+	alertTypes   map[string]*api.AlertType
+	// triggers contains the triggers by alert type name and trigger name.
+	triggers map[string]map[string]*api.AlertTrigger
+	// metricDimensions contains the dimension names by metric name.
+	metricDimensions map[string][]string
+	// groupMetrics contains the metric names by metric group name.
+	groupMetrics map[string][]string
+}
+
+// newAppState creates an empty state.
+func newAppState() *appState {
+	return &appState{
+		servers:          make(map[string]*api.Server),
+		serverGroups:     make(map[string]*api.ServerGroup),
+		dimensions:       make(map[string]*api.Dimension),
+		metrics:          make(map[string]*api.Metric),
+		metricGroups:     make(map[string]*api.MetricGroup),
+		events:           make(map[string]*api.Event),
+		alertTypes:       make(map[string]*api.AlertType),
+		triggers:         make(map[string]map[string]*api.AlertTrigger),
+		metricDimensions: make(map[string][]string),
+		groupMetrics:     make(map[string][]string),
+	}
+}
+
+// loadAppState gets all configuration objects of the application.
+func loadAppState(ctx context.Context, capi *api.Api) (*appState, error) {
+	state := newAppState()
+
+	var servers []*api.Server
+	var serverGroups []*api.ServerGroup
+	var dimensions []*api.Dimension
+	var metrics []*api.Metric
+	var metricGroups []*api.MetricGroup
+	var events []*api.Event
+	var alertTypes []*api.AlertType
+	for objectName, target := range map[string]interface{}{
+		"server":      &servers,
+		"servergroup": &serverGroups,
+		"dimension":   &dimensions,
+		"metric":      &metrics,
+		"metricgroup": &metricGroups,
+		"event":       &events,
+		"alerttype":   &alertTypes,
+	} {
+		result, err := capi.GetObjectsContext(ctx, objectName)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(result), target); err != nil {
+			return nil, fmt.Errorf("Could not parse the %ss: %s", objectName, err)
+		}
+	}
+
+	for _, server := range servers {
+		state.servers[server.Name] = server
+	}
+	for _, serverGroup := range serverGroups {
+		state.serverGroups[serverGroup.Name] = serverGroup
+	}
+	for _, dimension := range dimensions {
+		state.dimensions[dimension.Name] = dimension
+	}
+	for _, event := range events {
+		state.events[event.Name] = event
+	}
+
+	for _, metric := range metrics {
+		state.metrics[metric.Name] = metric
+		result, err := capi.GetDimensionsContext(ctx, metric.ID)
+		if err != nil {
+			return nil, err
+		}
+		var metricDimensions []*api.Dimension
+		if err := json.Unmarshal([]byte(result), &metricDimensions); err != nil {
+			return nil, fmt.Errorf("Could not parse the dimensions of metric %s: %s", metric.Name, err)
+		}
+		for _, dimension := range metricDimensions {
+			state.metricDimensions[metric.Name] = append(state.metricDimensions[metric.Name], dimension.Name)
+		}
+		sort.Strings(state.metricDimensions[metric.Name])
+	}
+
+	for _, metricGroup := range metricGroups {
+		state.metricGroups[metricGroup.Name] = metricGroup
+		result, err := capi.GetMetricsByGroupContext(ctx, metricGroup)
+		if err != nil {
+			return nil, err
+		}
+		var groupMetrics []*api.Metric
+		if err := json.Unmarshal([]byte(result), &groupMetrics); err != nil {
+			return nil, fmt.Errorf("Could not parse the metrics of metricgroup %s: %s", metricGroup.Name, err)
+		}
+		for _, metric := range groupMetrics {
+			state.groupMetrics[metricGroup.Name] = append(state.groupMetrics[metricGroup.Name], metric.Name)
+		}
+		sort.Strings(state.groupMetrics[metricGroup.Name])
+	}
+
+	for _, alertType := range alertTypes {
+		state.alertTypes[alertType.Name] = alertType
+		state.triggers[alertType.Name] = make(map[string]*api.AlertTrigger)
+		result, err := capi.GetTriggersContext(ctx, alertType.ID)
+		if err != nil {
+			return nil, err
+		}
+		var triggers []*api.AlertTrigger
+		if err := json.Unmarshal([]byte(result), &triggers); err != nil {
+			return nil, fmt.Errorf("Could not parse the triggers of alerttype %s: %s", alertType.Name, err)
+		}
+		for _, trigger := range triggers {
+			state.triggers[alertType.Name][trigger.Name] = trigger
+		}
+	}
+	return state, nil
+}
+
+// The names of the objects that refer to an unknown object id are formatted as #<id>.
+
+func (s *appState) serverName(id int64) string {
+	for name, server := range s.servers {
+		if server.ID == id {
+			return name
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+func (s *appState) serverGroupName(id int64) string {
+	for name, serverGroup := range s.serverGroups {
+		if serverGroup.ID == id {
+			return name
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+func (s *appState) metricName(id int64) string {
+	for name, metric := range s.metrics {
+		if metric.ID == id {
+			return name
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+func (s *appState) dimensionName(id int64) string {
+	for name, dimension := range s.dimensions {
+		if dimension.ID == id {
+			return name
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+// serverToSpec converts a live server into a spec.
+func (s *appState) serverToSpec(server *api.Server) *serverSpec {
+	return &serverSpec{
+		Name:        server.Name,
+		Description: stringPointer(server.Description),
+		Type:        stringPointer(server.Type),
+		State:       stringPointer(server.State),
+	}
+}
+
+func (s *appState) serverGroupToSpec(serverGroup *api.ServerGroup) *serverGroupSpec {
+	spec := &serverGroupSpec{
+		Name:        serverGroup.Name,
+		Description: stringPointer(serverGroup.Description),
+		Type:        stringPointer(serverGroup.Type),
+		State:       stringPointer(serverGroup.State),
+	}
+	parent := ""
+	if serverGroup.ParentID != 0 {
+		parent = s.serverGroupName(serverGroup.ParentID)
+	}
+	spec.Parent = &parent
+	return spec
+}
+
+func (s *appState) metricToSpec(metric *api.Metric) *metricSpec {
+	return &metricSpec{
+		Name:        metric.Name,
+		Description: stringPointer(metric.Description),
+		DataType:    stringPointer(metric.DataType),
+		Period:      intPointer(metric.Period),
+		Unit:        stringPointer(metric.Unit),
+		Subject:     stringPointer(metric.Subject),
+		Dimensions:  sortedNames(s.metricDimensions[metric.Name]),
+	}
+}
+
+func (s *appState) metricGroupToSpec(metricGroup *api.MetricGroup) *metricGroupSpec {
+	return &metricGroupSpec{
+		Name:        metricGroup.Name,
+		Description: stringPointer(metricGroup.Description),
+		Type:        stringPointer(metricGroup.Type),
+		State:       stringPointer(metricGroup.State),
+		Subject:     stringPointer(metricGroup.Subject),
+		Metrics:     sortedNames(s.groupMetrics[metricGroup.Name]),
+	}
+}
+
+func (s *appState) eventToSpec(event *api.Event) *eventSpec {
+	return &eventSpec{
+		Name:                  event.Name,
+		Description:           stringPointer(event.Description),
+		AttributeDescriptions: stringPointer(event.AttributeDescriptions),
+		Type:                  stringPointer(event.Type),
+	}
+}
+
+// alertTypeToSpec converts a live alert type, without its triggers, into a spec.
+func (s *appState) alertTypeToSpec(alertType *api.AlertType) (*alertTypeSpec, error) {
+	spec := &alertTypeSpec{
+		Name:              alertType.Name,
+		Description:       stringPointer(alertType.Description),
+		BackupSeconds:     int64Pointer(alertType.BackupSeconds),
+		EscalationSeconds: int64Pointer(alertType.EscalationSeconds),
+	}
+	handles := []**string{&spec.Handle, &spec.BackupHandle, &spec.EscalationHandle}
+	for i, handle := range []string{alertType.Handle, alertType.BackupHandle, alertType.EscalationHandle} {
+		formatted, err := formatHandle(handle)
+		if err != nil {
+			return nil, fmt.Errorf("alerttype %s: %s", alertType.Name, err)
+		}
+		*handles[i] = &formatted
+	}
+	return spec, nil
+}
+
+func (s *appState) triggerToSpec(trigger *api.AlertTrigger) (*triggerSpec, error) {
+	spec := &triggerSpec{
+		Name:               trigger.Name,
+		Description:        stringPointer(trigger.Description),
+		Metric:             s.metricName(trigger.Metric),
+		Config:             trigger.Config,
+		AutoResolveSeconds: int64Pointer(trigger.AutoResolve),
+	}
+	if trigger.ServerID != 0 {
+		server := s.serverName(trigger.ServerID)
+		spec.Server = &server
+	} else if trigger.GroupID != 0 {
+		serverGroup := s.serverGroupName(trigger.GroupID)
+		spec.ServerGroup = &serverGroup
+	}
+
+	if trigger.DimensionSpecs != "" {
+		var dimensionSpecs [][]interface{}
+		if err := json.Unmarshal([]byte(trigger.DimensionSpecs), &dimensionSpecs); err != nil {
+			return nil, fmt.Errorf("trigger %s: could not parse the dimension specs: %s", trigger.Name, err)
+		}
+		for _, dimensionSpec := range dimensionSpecs {
+			if len(dimensionSpec) != 2 {
+				return nil, fmt.Errorf("trigger %s: could not parse the dimension specs: %s", trigger.Name, trigger.DimensionSpecs)
+			}
+			id, _ := dimensionSpec[0].(float64)
+			spec.DimensionSpecs = append(spec.DimensionSpecs, &triggerDimensionSpec{
+				Dimension: s.dimensionName(int64(id)),
+				Values:    fmt.Sprint(dimensionSpec[1]),
+			})
+		}
+	}
+	return spec, nil
+}
+
+// toSpec converts all live objects into a spec, the objects are sorted by name.
+func (s *appState) toSpec() (*appSpec, error) {
+	spec := &appSpec{
+		Servers:      []*serverSpec{},
+		ServerGroups: []*serverGroupSpec{},
+		Dimensions:   []*dimensionSpec{},
+		Metrics:      []*metricSpec{},
+		MetricGroups: []*metricGroupSpec{},
+		Events:       []*eventSpec{},
+		AlertTypes:   []*alertTypeSpec{},
+	}
+	for _, name := range objectNames(s.servers) {
+		spec.Servers = append(spec.Servers, s.serverToSpec(s.servers[name]))
+	}
+	for _, name := range objectNames(s.serverGroups) {
+		spec.ServerGroups = append(spec.ServerGroups, s.serverGroupToSpec(s.serverGroups[name]))
+	}
+	for _, name := range objectNames(s.dimensions) {
+		spec.Dimensions = append(spec.Dimensions, &dimensionSpec{Name: name})
+	}
+	for _, name := range objectNames(s.metrics) {
+		spec.Metrics = append(spec.Metrics, s.metricToSpec(s.metrics[name]))
+	}
+	for _, name := range objectNames(s.metricGroups) {
+		spec.MetricGroups = append(spec.MetricGroups, s.metricGroupToSpec(s.metricGroups[name]))
+	}
+	for _, name := range objectNames(s.events) {
+		spec.Events = append(spec.Events, s.eventToSpec(s.events[name]))
+	}
+	for _, name := range objectNames(s.alertTypes) {
+		alertType, err := s.alertTypeToSpec(s.alertTypes[name])
+		if err != nil {
+			return nil, err
+		}
+		alertType.Triggers = []*triggerSpec{}
+		for _, triggerName := range objectNames(s.triggers[name]) {
+			trigger, err := s.triggerToSpec(s.triggers[name][triggerName])
+			if err != nil {
+				return nil, err
+			}
+			alertType.Triggers = append(alertType.Triggers, trigger)
+		}
+		spec.AlertTypes = append(spec.AlertTypes, alertType)
+	}
+	return spec, nil
+}
@@ -0,0 +1,206 @@
+package command
+
+import (
+	"bufio"
+	"coscale/api"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsdAggregator aggregates StatsD samples until they are flushed as data for the API.
+type statsdAggregator struct {
+	*metricResolver
+	// subjectTag is the tag with the server name, subject is used for samples without the tag.
+	subjectTag string
+	subject    string
+	// percentileWidth is the width of the percentiles of the timer histograms.
+	percentileWidth int
+	series          map[string]*statsdSeries
+}
+
+// statsdSeries contains the aggregated samples of a metric with the same tags.
+type statsdSeries struct {
+	name string
+	kind string
+	tags map[string]string
+	// updated is true if the series received samples since the last flush.
+	updated bool
+	// value is the sum of a counter or the value of a gauge.
+	value float64
+	// samples are the values of a timer, count is the number of samples corrected for the
+	// sample rate.
+	samples []float64
+	count   float64
+	members map[string]bool
+}
+
+// newStatsdAggregator creates a statsdAggregator, subject is A or S<server id>.
+func newStatsdAggregator(resolver *metricResolver, subjectTag, subject string, percentileWidth int) *statsdAggregator {
+	return &statsdAggregator{
+		metricResolver:  resolver,
+		subjectTag:      subjectTag,
+		subject:         subject,
+		percentileWidth: percentileWidth,
+		series:          make(map[string]*statsdSeries),
+	}
+}
+
+// add adds a line to the aggregated samples.
+func (a *statsdAggregator) add(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	sample, err := api.ParseStatsdLine(strings.TrimSpace(line))
+	if err != nil {
+		return err
+	}
+
+	key := sample.Type + "|" + sample.Name
+	for _, name := range objectNames(sample.Tags) {
+		key += "|" + name + "=" + sample.Tags[name]
+	}
+	series, ok := a.series[key]
+	if !ok {
+		series = &statsdSeries{name: sample.Name, kind: sample.Type, tags: sample.Tags, members: make(map[string]bool)}
+		a.series[key] = series
+	}
+	series.updated = true
+
+	value, _ := strconv.ParseFloat(sample.Value, 64)
+	switch sample.Type {
+	case "c":
+		series.value += value / sample.SampleRate
+	case "g":
+		// A gauge value with a sign changes the gauge.
+		if strings.HasPrefix(sample.Value, "+") || strings.HasPrefix(sample.Value, "-") {
+			series.value += value
+		} else {
+			series.value = value
+		}
+	case "ms":
+		series.samples = append(series.samples, value)
+		series.count += 1 / sample.SampleRate
+	case "s":
+		series.members[sample.Value] = true
+	}
+	return nil
+}
+
+// flush returns the data of the series that were updated since the last flush, timestamp is the
+// time of the data. Counters are sent as the sum of the interval, gauges as their last value, sets
+// as the number of unique members and timers as a histogram. The gauges keep their value.
+func (a *statsdAggregator) flush(timestamp int) ([]*api.ApiData, []error) {
+	var data []*api.ApiData
+	var errs []error
+	for _, key := range objectNames(a.series) {
+		series := a.series[key]
+		if !series.updated {
+			continue
+		}
+		if series.kind == "g" {
+			series.updated = false
+		} else {
+			delete(a.series, key)
+		}
+
+		dataType, value := "DOUBLE", ""
+		switch series.kind {
+		case "c", "g":
+			value = strconv.FormatFloat(series.value, 'f', -1, 64)
+		case "s":
+			value = strconv.Itoa(len(series.members))
+		case "ms":
+			dataType = "HISTOGRAM"
+			histogram, err := api.NewHistogram(series.samples, a.percentileWidth)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s", series.name, err))
+				continue
+			}
+			// The number of samples is corrected for the sample rate.
+			histogram.Samples = int(math.Round(series.count))
+			value = histogram.String()
+		}
+
+		subject := a.subject
+		var dimensionValues map[string]string
+		var err error
+		for name, tag := range series.tags {
+			if name == a.subjectTag {
+				if subject, err = a.serverSubject(tag); err != nil {
+					break
+				}
+				continue
+			}
+			if dimensionValues == nil {
+				dimensionValues = make(map[string]string)
+			}
+			dimensionValues[name] = tag
+		}
+		var metricID int64
+		if err == nil {
+			metricID, err = a.metricID(series.name, dataType, subject, dimensionValues)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", series.name, err))
+			continue
+		}
+		data = append(data, &api.ApiData{
+			MetricID:        metricID,
+			SubjectID:       subject,
+			Data:            []api.DataPoint{{SecondsAgo: timestamp, Data: value}},
+			DimensionValues: dimensionValues,
+		})
+	}
+	return data, errs
+}
+
+// aggregateData reads lines from r until the end of the input and adds them using add. The data of
+// flush is inserted using insert every flushInterval and at the end of the input. Errors are
+// reported on errOut, true is returned if there were errors.
+func aggregateData(r io.Reader, errOut io.Writer, insert func(map[string][]*api.ApiData) error,
+	add func(line string) error, flush func() ([]*api.ApiData, []error), flushInterval time.Duration) bool {
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), api.MaxUploadSize)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	failed := false
+	send := func() {
+		data, errs := flush()
+		for _, err := range errs {
+			fmt.Fprintln(errOut, err.Error())
+			failed = true
+		}
+		if !sendData(data, insert, errOut) {
+			failed = true
+		}
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				send()
+				return failed
+			}
+			if err := add(line); err != nil {
+				fmt.Fprintf(errOut, "%s: %q\n", err, line)
+				failed = true
+			}
+		case <-ticker.C:
+			send()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"coscale/api"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the aggregation of the StatsD types.
+func TestStatsdAggregator(t *testing.T) {
+	capi := api.NewApi("http://coscale.test", "token", "app", true, false)
+	capi.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"token":"token"}`
+		switch req.URL.Query().Get("selectByName") {
+		case "web-1":
+			body = `[{"id":12,"name":"web-1"}]`
+		case "requests":
+			body = `[{"id":1,"name":"requests"}]`
+		case "queue":
+			body = `[{"id":2,"name":"queue"}]`
+		case "latency":
+			body = `[{"id":3,"name":"latency"}]`
+		case "users":
+			body = `[{"id":4,"name":"users"}]`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+	aggregator := newStatsdAggregator(newMetricResolver(context.Background(), capi, false), "host", "A", 50)
+
+	lines := []string{
+		"requests:1|c|#host:web-1,status:200", "requests:2|c|@0.5|#host:web-1,status:200", "requests:1|c|#status:500",
+		"queue:10|g", "queue:-3|g",
+		"latency:30|ms", "latency:10|ms", "latency:20|h", "latency:40|ms|@0.5",
+		"users:a|s", "users:b|s", "users:a|s",
+	}
+	for _, line := range lines {
+		if err := aggregator.add(line); err != nil {
+			t.Fatalf("unexpected error for %q: %s", line, err)
+		}
+	}
+	if err := aggregator.add("requests:x|c"); err == nil {
+		t.Fatalf("expected an error for a bad value")
+	}
+
+	data, errs := aggregator.flush(100)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	point := func(value string) []api.DataPoint { return []api.DataPoint{{SecondsAgo: 100, Data: value}} }
+	expected := []*api.ApiData{
+		{MetricID: 1, SubjectID: "S12", Data: point("5"), DimensionValues: map[string]string{"status": "200"}},
+		{MetricID: 1, SubjectID: "A", Data: point("1"), DimensionValues: map[string]string{"status": "500"}},
+		{MetricID: 2, SubjectID: "A", Data: point("7")},
+		{MetricID: 3, SubjectID: "A", Data: point("[5,50,[10,20,40]]")},
+		{MetricID: 4, SubjectID: "A", Data: point("2")},
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Fatalf("expected: %v, found: %v", expected, data)
+	}
+
+	// Only the gauges keep their value, they are sent again when they change.
+	if data, _ := aggregator.flush(110); len(data) != 0 {
+		t.Fatalf("expected: no data without samples, found: %v", data)
+	}
+	aggregator.add("queue:+1|g")
+	if data, _ := aggregator.flush(120); len(data) != 1 || data[0].Data[0].Data != "8" {
+		t.Fatalf("expected: the changed gauge, found: %v", data)
+	}
+}
+
+// Test that aggregateData sends the aggregated data at the end of the input.
+func TestAggregateData(t *testing.T) {
+	var inserted []map[string][]*api.ApiData
+	insert := func(callData map[string][]*api.ApiData) error {
+		inserted = append(inserted, callData)
+		return nil
+	}
+	var lines []string
+	add := func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}
+	flush := func() ([]*api.ApiData, []error) {
+		if len(lines) == 0 {
+			return nil, nil
+		}
+		return []*api.ApiData{{MetricID: 1, SubjectID: "A", Data: []api.DataPoint{{SecondsAgo: 0, Data: "1"}}}}, nil
+	}
+	var errOut bytes.Buffer
+	if failed := aggregateData(strings.NewReader("a:1|c\nb:1|c\n"), &errOut, insert, add, flush, time.Hour); failed {
+		t.Fatalf("expected: no errors, found: %s", errOut.String())
+	}
+	if len(lines) != 2 || len(inserted) != 1 {
+		t.Fatalf("expected: 2 lines and 1 insert, found: %v %v", lines, inserted)
+	}
+}
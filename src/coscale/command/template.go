@@ -0,0 +1,150 @@
+package command
+
+import (
+	"bytes"
+	"coscale/api"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// objectTypes defines the api type of the objects returned by a command, used to decode the
+// result for the --format template. The keys are the same as for objectColumns.
+var objectTypes = map[string]interface{}{
+	"server":           api.Server{},
+	"servergroup":      api.ServerGroup{},
+	"metric":           api.Metric{},
+	"metricgroup":      api.MetricGroup{},
+	"metric dimension": api.Dimension{},
+	"event":            api.Event{},
+	"event listdata":   api.EventData{},
+	"event newdata":    api.EventData{},
+	"event updatedata": api.EventData{},
+	"alert":            api.Alert{},
+	"alert type":       api.AlertType{},
+	"alert trigger":    api.AlertTrigger{},
+	"apply":            specChange{},
+	"plan":             specChange{},
+	"export":           exportedFile{},
+	"clone":            specChange{},
+}
+
+// templateFuncs are the functions that can be used in a --format template.
+var templateFuncs = template.FuncMap{
+	"json":       templateJSON,
+	"time":       templateTime,
+	"join":       templateJoin,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"trim":       strings.TrimSpace,
+	"capitalize": capitalize,
+}
+
+// parseFormat parses a --format template.
+func parseFormat(format string) (*template.Template, error) {
+	t, err := template.New("format").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid format: %s", err)
+	}
+	return t, nil
+}
+
+// executeFormat renders the json result using the template. The result is decoded into a value of
+// objectType, or a list of these values, if the result matches the type. Other results are decoded
+// into maps and lists.
+func executeFormat(result string, t *template.Template, objectType interface{}) (string, error) {
+	var data interface{}
+	if objectType != nil {
+		data = decodeTyped(result, reflect.TypeOf(objectType))
+	}
+	if data == nil {
+		decoder := json.NewDecoder(strings.NewReader(result))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			return "", fmt.Errorf("The result is not json, --format can not be used: %s", err)
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// decodeTyped decodes a json object into a pointer to objectType or a json list into a slice
+// of objectType, nil is returned if the result does not match the type.
+func decodeTyped(result string, objectType reflect.Type) interface{} {
+	var target reflect.Value
+	switch trimmed := strings.TrimSpace(result); {
+	case strings.HasPrefix(trimmed, "["):
+		target = reflect.New(reflect.SliceOf(objectType))
+	case strings.HasPrefix(trimmed, "{"):
+		target = reflect.New(objectType)
+	default:
+		return nil
+	}
+	if err := json.Unmarshal([]byte(result), target.Interface()); err != nil {
+		return nil
+	}
+	if target.Elem().Kind() == reflect.Slice {
+		return target.Elem().Interface()
+	}
+	return target.Interface()
+}
+
+// templateJSON formats a value as json.
+func templateJSON(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+// templateTime formats a unix timestamp in seconds, by default as RFC 3339 in the local time zone.
+// The optional layout uses the format of the Go time package, e.g. "2006-01-02 15:04".
+func templateTime(value interface{}, layout ...string) (string, error) {
+	var seconds int64
+	switch v := value.(type) {
+	case int64:
+		seconds = v
+	case int:
+		seconds = int64(v)
+	case float64:
+		seconds = int64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return "", err
+		}
+		seconds = int64(f)
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", fmt.Errorf("time: %q is not a timestamp", v)
+		}
+		seconds = int64(f)
+	default:
+		return "", fmt.Errorf("time: %v is not a timestamp", value)
+	}
+	format := time.RFC3339
+	if len(layout) > 0 {
+		format = layout[0]
+	}
+	return time.Unix(seconds, 0).Format(format), nil
+}
+
+// templateJoin joins the elements of a list using sep.
+func templateJoin(list interface{}, sep string) (string, error) {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: %v is not a list", list)
+	}
+	elements := make([]string, value.Len())
+	for i := range elements {
+		elements[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(elements, sep), nil
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"coscale/api"
+	"testing"
+	"time"
+)
+
+// Test executeFormat with typed and generic results.
+func TestExecuteFormat(t *testing.T) {
+	timestamp := time.Unix(1495108650, 0).Format("2006-01-02 15:04")
+	tests := []struct {
+		format     string
+		result     string
+		objectType interface{}
+		expected   string
+	}{
+		{`{{range .}}{{.ID}} {{.Name}}{{"\n"}}{{end}}`, servers, api.Server{}, "34 web 1\n35 db"},
+		{`{{range .}}{{.Name | upper}}:{{len .Attributes}} {{end}}`, servers, api.Server{}, "WEB 1:0 DB:1 "},
+		{`{{(index . 1).State | lower}} {{json (index . 1).Attributes}}`, servers, api.Server{},
+			`inactive [{"ID":0,"Key":"os","Value":"","Source":""}]`},
+		{`{{.Message}} at {{time .Timestamp "2006-01-02 15:04"}}`, `{"id": 1, "timestamp": 1495108650, "message": "deploy"}`, api.EventData{},
+			"deploy at " + timestamp},
+		{`{{range .}}{{.id}} {{end}}`, servers, nil, "34 35 "},
+		{`{{join .tags ", "}} {{time .t "2006-01-02 15:04"}}`, `{"tags": ["a", "b"], "t": 1495108650}`, nil, "a, b " + timestamp},
+		{`{{.}}`, `[{"id": "not a number"}]`, api.Server{}, `[map[id:not a number]]`},
+	}
+	for _, test := range tests {
+		format, err := parseFormat(test.format)
+		if err != nil {
+			t.Fatalf("Error occured while parsing %s: %s", test.format, err)
+		}
+		obtained, err := executeFormat(test.result, format, test.objectType)
+		if err != nil {
+			t.Fatalf("Error occured while executing %s: %s", test.format, err)
+		}
+		if obtained != test.expected {
+			t.Fatalf("%s expected: %s, found: %s", test.format, test.expected, obtained)
+		}
+	}
+}
+
+// Test that every object with columns has a type for the templates.
+func TestObjectTypes(t *testing.T) {
+	for key := range objectColumns {
+		if _, ok := objectTypes[key]; !ok {
+			t.Fatalf("expected a type for %s", key)
+		}
+	}
+}
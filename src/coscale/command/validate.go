@@ -0,0 +1,70 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"sync"
+)
+
+// metricDefinitions gets the definitions of the metrics for the validation of data, every metric
+// is requested once.
+type metricDefinitions struct {
+	ctx         context.Context
+	capi        *api.Api
+	mu          sync.Mutex
+	definitions map[int64]*api.MetricDefinition
+	// missing contains the errors of the metrics that do not exist.
+	missing map[int64]error
+}
+
+// newMetricDefinitions creates a metricDefinitions, ctx is used for the API calls.
+func newMetricDefinitions(ctx context.Context, capi *api.Api) *metricDefinitions {
+	return &metricDefinitions{
+		ctx:         ctx,
+		capi:        capi,
+		definitions: make(map[int64]*api.MetricDefinition),
+		missing:     make(map[int64]error),
+	}
+}
+
+// get returns the definition of a metric.
+func (d *metricDefinitions) get(metricID int64) (*api.MetricDefinition, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if definition, ok := d.definitions[metricID]; ok {
+		return definition, nil
+	}
+	if err, ok := d.missing[metricID]; ok {
+		return nil, err
+	}
+	definition, err := d.capi.GetMetricDefinitionContext(d.ctx, metricID)
+	if api.IsNotFoundError(err) {
+		// Remember the missing metrics, other errors could be temporary.
+		d.missing[metricID] = err
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.definitions[metricID] = definition
+	return definition, nil
+}
+
+// validatingInsert returns a function that validates a batch of data before it is inserted using
+// insert, a batch with issues is not inserted. If validateOnly is true, no data is inserted.
+func validatingInsert(definitions *metricDefinitions, insert func(map[string][]*api.ApiData) (string, error),
+	validateOnly bool) func(map[string][]*api.ApiData) (string, error) {
+
+	return func(callData map[string][]*api.ApiData) (string, error) {
+		issues, err := api.ValidateData(callData, definitions.get)
+		if err != nil {
+			return "", err
+		}
+		if len(issues) > 0 {
+			return "", api.DataIssuesError(issues)
+		}
+		if validateOnly {
+			return `{"msg":"The data is valid"}`, nil
+		}
+		return insert(callData)
+	}
+}
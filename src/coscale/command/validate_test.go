@@ -0,0 +1,56 @@
+package command
+
+import (
+	"context"
+	"coscale/api"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Test that validatingInsert only inserts valid batches and requests every metric once.
+func TestValidatingInsert(t *testing.T) {
+	responses := map[string]string{
+		"POST /api/v1/app/app/login/":               `{"token":"token"}`,
+		"GET /api/v1/app/app/metrics/1/":            `{"id":1,"name":"cpu","dataType":"DOUBLE","period":60,"subject":"SERVER"}`,
+		"GET /api/v1/app/app/metrics/1/dimensions/": `[{"id":1,"name":"core"}]`,
+	}
+	requests := make(map[string]int)
+	capi := api.NewApi("http://coscale.test", "token", "app", true, false)
+	capi.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		request := req.Method + " " + req.URL.Path
+		requests[request]++
+		body, ok := responses[request]
+		if !ok {
+			return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(strings.NewReader(`{"msg":"Not Found"}`)), Header: http.Header{}}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+
+	inserted := 0
+	insert := func(callData map[string][]*api.ApiData) (string, error) {
+		inserted++
+		return `"0"`, nil
+	}
+	validate := validatingInsert(newMetricDefinitions(context.Background(), capi), insert, false)
+	for _, data := range []string{`M1:S1:0:1.2:{"core":"0"}`, `M1:S2:-60:1.3`, `M1:A:0:1.2`, `M2:S1:0:1`} {
+		callsData, err := api.ParseDataPoint(data, false)
+		if err != nil {
+			t.Fatalf("Error occured for %s: %s", data, err)
+		}
+		_, err = validate(callsData[0])
+		if strings.HasPrefix(data, "M1:S") && err != nil {
+			t.Fatalf("Error occured for %s: %s", data, err)
+		}
+		if _, ok := err.(api.DataIssuesError); !strings.HasPrefix(data, "M1:S") && !ok {
+			t.Fatalf("expected: DataIssuesError for %s, found: %v", data, err)
+		}
+	}
+	if inserted != 2 {
+		t.Fatalf("expected: 2 inserted batches, found: %d", inserted)
+	}
+	if requests["GET /api/v1/app/app/metrics/1/"] != 1 || requests["GET /api/v1/app/app/metrics/2/"] != 1 {
+		t.Fatalf("expected: every metric is requested once, found: %v", requests)
+	}
+}
@@ -0,0 +1,542 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The CLI only needs a small part of YAML, so instead of adding a dependency the values
+// produced by encoding/json (maps, slices, strings, json.Number, bool and nil) are
+// written in the YAML block style.
+
+// yamlPlainPattern matches the strings that can be written without quotes.
+var yamlPlainPattern = regexp.MustCompile(`^[A-Za-z0-9_./()$][A-Za-z0-9 _./()$+=<>@^,;*-]*$`)
+
+// yamlReservedPattern matches plain strings that would be read back as another type.
+var yamlReservedPattern = regexp.MustCompile(`^(?i:true|false|yes|no|on|off|null|~|[-+]?[0-9.][0-9._eE+-]*|[-+]?\.inf|\.nan)$`)
+
+// marshalYAML encodes a generic json value as YAML.
+func marshalYAML(value interface{}) []byte {
+	var buffer bytes.Buffer
+	writeYAML(&buffer, value, 0)
+	return buffer.Bytes()
+}
+
+// writeYAML writes value at the given indentation, a nested value starts on a new line.
+func writeYAML(buffer *bytes.Buffer, value interface{}, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buffer.WriteString(prefix + "{}\n")
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			buffer.WriteString(prefix + yamlString(key) + ":")
+			writeYAMLValue(buffer, v[key], indent+1)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			buffer.WriteString(prefix + "[]\n")
+			return
+		}
+		for _, item := range v {
+			buffer.WriteString(prefix + "-")
+			if m, ok := item.(map[string]interface{}); ok && len(m) > 0 {
+				// The first key of a map is written on the same line as the dash.
+				var nested bytes.Buffer
+				writeYAML(&nested, m, indent+1)
+				buffer.WriteString(" " + strings.TrimPrefix(nested.String(), prefix+"  "))
+				continue
+			}
+			writeYAMLValue(buffer, item, indent+1)
+		}
+	default:
+		buffer.WriteString(prefix + yamlScalar(v) + "\n")
+	}
+}
+
+// writeYAMLValue writes the value after a key or dash.
+func writeYAMLValue(buffer *bytes.Buffer, value interface{}, indent int) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			buffer.WriteString("\n")
+			writeYAML(buffer, v, indent)
+			return
+		}
+		buffer.WriteString(" {}\n")
+	case []interface{}:
+		if len(v) > 0 {
+			buffer.WriteString("\n")
+			writeYAML(buffer, v, indent)
+			return
+		}
+		buffer.WriteString(" []\n")
+	default:
+		buffer.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+// yamlScalar formats a scalar json value.
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return yamlString(v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return v.String()
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// yamlString formats a string, quoting it if required.
+func yamlString(s string) string {
+	if yamlPlainPattern.MatchString(s) && !yamlReservedPattern.MatchString(s) && !strings.HasSuffix(s, " ") {
+		return s
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// yamlLine is a line of a YAML document without the indentation and comment.
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlDecoder decodes the block style YAML written by marshalYAML and the common hand written
+// YAML: block mappings and sequences, plain and quoted scalars, single line flow collections
+// and literal (|) or folded (>) block scalars. Anchors, tags and multiple documents are not supported.
+type yamlDecoder struct {
+	lines []yamlLine
+	pos   int
+}
+
+// unmarshalYAML decodes a YAML document into the values produced by encoding/json with UseNumber.
+func unmarshalYAML(data []byte) (interface{}, error) {
+	decoder := &yamlDecoder{}
+	var block *yamlLine
+	for i, text := range strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n") {
+		trimmed := strings.TrimLeft(text, " ")
+		indent := len(text) - len(trimmed)
+		if block != nil {
+			// The lines of a block scalar are kept as they are.
+			if strings.TrimSpace(text) == "" || indent > block.indent {
+				decoder.lines = append(decoder.lines, yamlLine{i + 1, -1, text})
+				continue
+			}
+			block = nil
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs can not be used for indentation", i+1)
+		}
+		trimmed = strings.TrimRight(stripYAMLComment(trimmed), " \t")
+		if trimmed == "" || (indent == 0 && (trimmed == "---" || trimmed == "...")) {
+			continue
+		}
+		decoder.lines = append(decoder.lines, yamlLine{i + 1, indent, trimmed})
+		if isBlockScalar(trimmed) {
+			block = &yamlLine{number: i + 1, indent: indent}
+		}
+	}
+	if len(decoder.lines) == 0 {
+		return nil, nil
+	}
+	value, err := decoder.parseNode(decoder.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos < len(decoder.lines) {
+		line := decoder.lines[decoder.pos]
+		return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+	}
+	return value, nil
+}
+
+// stripYAMLComment removes a comment that is not inside quotes.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" [{,:-", text[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// isBlockScalar checks if a line ends with the indicator of a block scalar.
+func isBlockScalar(text string) bool {
+	if strings.HasPrefix(text, "- ") {
+		text = strings.TrimLeft(text[1:], " ")
+	}
+	value := text
+	if key, rest, ok := splitYAMLKey(text); ok && key != "" {
+		value = rest
+	}
+	switch value {
+	case "|", "|-", "|+", ">", ">-", ">+":
+		return true
+	}
+	return false
+}
+
+// splitYAMLKey splits a mapping line into the key and the value.
+func splitYAMLKey(text string) (string, string, bool) {
+	if text == "" || strings.IndexByte("[{", text[0]) >= 0 {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case i == 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			key := strings.TrimSpace(text[:i])
+			if strings.HasPrefix(key, `"`) || strings.HasPrefix(key, `'`) {
+				unquoted, err := parseYAMLScalar(key)
+				if err != nil {
+					return "", "", false
+				}
+				key = fmt.Sprint(unquoted)
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseNode parses the mapping, sequence or scalar starting at the current line.
+func (d *yamlDecoder) parseNode(indent int) (interface{}, error) {
+	line := d.lines[d.pos]
+	if isYAMLSequenceItem(line.text) {
+		return d.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return d.parseMapping(indent)
+	}
+	d.pos++
+	value, err := parseYAMLScalar(line.text)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %s", line.number, err)
+	}
+	return value, nil
+}
+
+// parseMapping parses the keys of a mapping at indent.
+func (d *yamlDecoder) parseMapping(indent int) (interface{}, error) {
+	result := make(map[string]interface{})
+	for d.pos < len(d.lines) && d.lines[d.pos].indent == indent && !isYAMLSequenceItem(d.lines[d.pos].text) {
+		line := d.lines[d.pos]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key", line.number)
+		}
+		if _, found := result[key]; found {
+			return nil, fmt.Errorf("line %d: duplicate key %s", line.number, key)
+		}
+		d.pos++
+		value, err := d.parseValue(line, indent, rest, true)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// parseSequence parses the items of a sequence at indent.
+func (d *yamlDecoder) parseSequence(indent int) (interface{}, error) {
+	result := []interface{}{}
+	for d.pos < len(d.lines) && d.lines[d.pos].indent == indent && isYAMLSequenceItem(d.lines[d.pos].text) {
+		line := d.lines[d.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if _, _, ok := splitYAMLKey(rest); ok || isYAMLSequenceItem(rest) {
+			// The item is a mapping or sequence starting on the line of the dash, continue
+			// with the item as a line at the indentation of its first key.
+			d.lines[d.pos] = yamlLine{line.number, indent + len(line.text) - len(rest), rest}
+			value, err := d.parseNode(d.lines[d.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+		d.pos++
+		value, err := d.parseValue(line, indent, rest, false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// parseValue parses the value after a key or dash: an inline value, a block scalar or a nested node.
+func (d *yamlDecoder) parseValue(line yamlLine, indent int, rest string, inMapping bool) (interface{}, error) {
+	if isBlockScalar(rest) {
+		return d.parseBlockScalar(rest, indent), nil
+	}
+	if rest != "" {
+		value, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line.number, err)
+		}
+		return value, nil
+	}
+	if d.pos < len(d.lines) {
+		next := d.lines[d.pos]
+		if next.indent > indent {
+			return d.parseNode(next.indent)
+		}
+		// A sequence can have the same indentation as the key of the mapping.
+		if inMapping && next.indent == indent && isYAMLSequenceItem(next.text) {
+			return d.parseSequence(indent)
+		}
+	}
+	return nil, nil
+}
+
+// parseBlockScalar reads the lines of a literal or folded block scalar.
+func (d *yamlDecoder) parseBlockScalar(indicator string, indent int) string {
+	var lines []string
+	blockIndent := -1
+	for d.pos < len(d.lines) && d.lines[d.pos].indent == -1 {
+		text := d.lines[d.pos].text
+		d.pos++
+		if strings.TrimSpace(text) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if blockIndent == -1 {
+			blockIndent = len(text) - len(strings.TrimLeft(text, " "))
+		}
+		if len(text) >= blockIndent {
+			text = text[blockIndent:]
+		}
+		lines = append(lines, text)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" && !strings.HasSuffix(indicator, "+") {
+		lines = lines[:len(lines)-1]
+	}
+
+	var value string
+	if strings.HasPrefix(indicator, ">") {
+		// Folded: lines are joined with a space, empty lines are newlines.
+		var buffer bytes.Buffer
+		for i, text := range lines {
+			switch {
+			case text == "":
+				buffer.WriteString("\n")
+			case i > 0 && lines[i-1] != "":
+				buffer.WriteString(" " + text)
+			default:
+				buffer.WriteString(text)
+			}
+		}
+		value = buffer.String()
+	} else {
+		value = strings.Join(lines, "\n")
+	}
+	if !strings.HasSuffix(indicator, "-") && len(lines) > 0 {
+		value += "\n"
+	}
+	return value
+}
+
+// parseYAMLScalar parses a scalar or a flow collection.
+func parseYAMLScalar(text string) (interface{}, error) {
+	parser := &yamlFlowParser{text: text}
+	value, err := parser.parse(false)
+	if err != nil {
+		return nil, err
+	}
+	if parser.skipSpace(); parser.pos < len(text) {
+		return nil, fmt.Errorf("unexpected %q", text[parser.pos:])
+	}
+	return value, nil
+}
+
+// yamlFlowParser parses a single line flow value: [a, b], {a: b}, quoted or plain scalars.
+type yamlFlowParser struct {
+	text string
+	pos  int
+}
+
+func (p *yamlFlowParser) skipSpace() {
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// parse parses a value, inFlow is true inside a flow collection where , ] and } end a plain scalar.
+func (p *yamlFlowParser) parse(inFlow bool) (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.text) {
+		return nil, nil
+	}
+	switch p.text[p.pos] {
+	case '[':
+		p.pos++
+		result := []interface{}{}
+		for {
+			if p.skipSpace(); p.pos < len(p.text) && p.text[p.pos] == ']' {
+				p.pos++
+				return result, nil
+			}
+			value, err := p.parse(true)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			if err := p.endItem(']'); err != nil {
+				return nil, err
+			}
+			if p.text[p.pos-1] == ']' {
+				return result, nil
+			}
+		}
+	case '{':
+		p.pos++
+		result := make(map[string]interface{})
+		for {
+			if p.skipSpace(); p.pos < len(p.text) && p.text[p.pos] == '}' {
+				p.pos++
+				return result, nil
+			}
+			key, err := p.parse(true)
+			if err != nil {
+				return nil, err
+			}
+			if p.skipSpace(); p.pos >= len(p.text) || p.text[p.pos] != ':' {
+				return nil, fmt.Errorf("expected ':' after key %v", key)
+			}
+			p.pos++
+			value, err := p.parse(true)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(key)] = value
+			if err := p.endItem('}'); err != nil {
+				return nil, err
+			}
+			if p.text[p.pos-1] == '}' {
+				return result, nil
+			}
+		}
+	case '"':
+		end := p.pos + 1
+		for ; end < len(p.text) && p.text[end] != '"'; end++ {
+			if p.text[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(p.text) {
+			return nil, fmt.Errorf("unterminated string %s", p.text[p.pos:])
+		}
+		var s string
+		if err := json.Unmarshal([]byte(p.text[p.pos:end+1]), &s); err != nil {
+			return nil, fmt.Errorf("invalid string %s", p.text[p.pos:end+1])
+		}
+		p.pos = end + 1
+		return s, nil
+	case '\'':
+		var buffer bytes.Buffer
+		for end := p.pos + 1; end < len(p.text); end++ {
+			if p.text[end] == '\'' {
+				if end+1 < len(p.text) && p.text[end+1] == '\'' {
+					buffer.WriteByte('\'')
+					end++
+					continue
+				}
+				p.pos = end + 1
+				return buffer.String(), nil
+			}
+			buffer.WriteByte(p.text[end])
+		}
+		return nil, fmt.Errorf("unterminated string %s", p.text[p.pos:])
+	}
+
+	start := p.pos
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		if inFlow && (c == ',' || c == ']' || c == '}' || (c == ':' && (p.pos+1 == len(p.text) || p.text[p.pos+1] == ' '))) {
+			break
+		}
+		p.pos++
+	}
+	return resolveYAMLPlain(strings.TrimSpace(p.text[start:p.pos])), nil
+}
+
+// endItem reads the , or closing character after an item of a flow collection.
+func (p *yamlFlowParser) endItem(closing byte) error {
+	p.skipSpace()
+	if p.pos < len(p.text) && (p.text[p.pos] == ',' || p.text[p.pos] == closing) {
+		p.pos++
+		return nil
+	}
+	return fmt.Errorf("expected ',' or '%c' in %s", closing, p.text)
+}
+
+// resolveYAMLPlain converts a plain scalar into null, a boolean, a number or a string.
+func resolveYAMLPlain(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yamlNumberPattern.MatchString(text) {
+		return json.Number(strings.TrimPrefix(text, "+"))
+	}
+	return text
+}
+
+// yamlNumberPattern matches the plain scalars that are numbers.
+var yamlNumberPattern = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
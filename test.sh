@@ -2,5 +2,6 @@
 # Tip: to remove '\r' use sed -i 's/\r//g' test.sh
 
 export GOPATH=`pwd`
+export GO111MODULE=off
 
 go test -timeout 20m -a -v coscale/...